@@ -3,7 +3,6 @@ package main
 import (
 	"flag"
 	"fmt"
-	"log"
 	"os"
 
 	"path"
@@ -24,17 +23,61 @@ func setCompilerVersion() {
 	compiler.LuajitVersion = LuajitVersion
 }
 
-func main_old() {
+func main() {
 	setCompilerVersion()
-	myflags := flag.NewFlagSet("gi", flag.ExitOnError)
+	os.Exit(gi(os.Args[1:]))
+}
+
+const usage = `usage:
+  %[1]s [flags]                    start the REPL
+  %[1]s -e 'expr' [flags]          evaluate expr, print its value, and exit
+  %[1]s run [flags] file.go [args] run a main package, with os.Args set
+  %[1]s [flags] script.go [args]   run a script of top-level statements;
+                                 a #!/usr/bin/env gi line may start it
+  %[1]s translate [flags] [-o out.lua] file.go
+                                 write the Lua for file.go
+
+flags:
+`
+
+// gi runs the gi command with args, and returns
+// the exit code.
+func gi(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			return runCmd(args[1:])
+		case "translate":
+			return translateCmd(args[1:])
+		}
+	}
+
+	myflags := newFlagSet(ProgramName)
 	cfg := compiler.NewGIConfig()
 	cfg.DefineFlags(myflags)
-
-	err := myflags.Parse(os.Args[1:])
-	err = cfg.ValidateConfig()
-	if err != nil {
-		log.Fatalf("%s command line flag error: '%s'", ProgramName, err)
+	expr := myflags.String("e", "", "evaluate the Go expression or statements, print the value, and exit")
+	if code, ok := parseFlags(myflags, cfg, args); !ok {
+		return code
+	}
+	if myflags.NArg() > 0 {
+		if !FileExists(myflags.Arg(0)) {
+			fmt.Fprintf(os.Stderr, "%s: unknown command '%s'\n", ProgramName, myflags.Arg(0))
+			myflags.Usage()
+			return 2
+		}
+		// a script, perhaps run by its #! line. The
+		// REPL never starts, so stdin is the script's.
+		cfg.Quiet = true
+		return cfg.RunScript(myflags.Arg(0), myflags.Args()[1:])
 	}
+	if cfg.KernelConnFile != "" {
+		cfg.KernelMain(cfg.KernelConnFile)
+		return compiler.ExitOK
+	}
+	if isFlagSet(myflags, "e") {
+		return cfg.EvalMain(*expr)
+	}
+
 	if !cfg.Quiet {
 		fmt.Printf(
 			`====================
@@ -58,9 +101,94 @@ https://github.com/gijit/gi/blob/master/LICENSE
 	}
 
 	cfg.LuajitMain()
+	return compiler.ExitOK
 }
 
-func main() {
+// runCmd is gi run.
+func runCmd(args []string) int {
+	myflags := newFlagSet(ProgramName + " run")
+	cfg := compiler.NewGIConfig()
+	cfg.DefineFlags(myflags)
+	if code, ok := parseFlags(myflags, cfg, args); !ok {
+		return code
+	}
+	if myflags.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "%s run: no go file given\n", ProgramName)
+		myflags.Usage()
+		return 2
+	}
+	cfg.Quiet = true
+	return cfg.RunMain(myflags.Arg(0), myflags.Args()[1:])
+}
+
+// translateCmd is gi translate.
+func translateCmd(args []string) int {
+	myflags := newFlagSet(ProgramName + " translate")
 	cfg := compiler.NewGIConfig()
-	cfg.TranslatorMain(os.Args[1])
+	cfg.DefineFlags(myflags)
+	out := myflags.String("o", "", "write the Lua to this file, rather than to stdout")
+	if code, ok := parseFlags(myflags, cfg, args); !ok {
+		return code
+	}
+	if myflags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "%s translate: want one go file\n", ProgramName)
+		myflags.Usage()
+		return 2
+	}
+	cfg.Quiet = true
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s translate: %v\n", ProgramName, err)
+			return compiler.ExitCompile
+		}
+		defer f.Close()
+		w = f
+	}
+	err := cfg.TranslateFile(myflags.Arg(0), w)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return compiler.ExitCompile
+	}
+	return compiler.ExitOK
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, usage, ProgramName)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses and validates args into cfg. If
+// that fails, or only asked for help, ok is false and
+// code is the exit code.
+func parseFlags(fs *flag.FlagSet, cfg *compiler.GIConfig, args []string) (code int, ok bool) {
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return compiler.ExitOK, false
+	}
+	if err != nil {
+		// fs has already said what was wrong.
+		return 2, false
+	}
+	err = cfg.ValidateConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s command line flag error: '%s'\n", ProgramName, err)
+		return 2, false
+	}
+	return 0, true
+}
+
+func isFlagSet(fs *flag.FlagSet, name string) (set bool) {
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return
 }
@@ -0,0 +1,34 @@
+// Package gi embeds gijit, the Go interpreter, in a
+// host Go program:
+//
+//	in, err := gi.NewInterpreter(nil)
+//	if err != nil {
+//		...
+//	}
+//	defer in.Close()
+//	in.Set("n", 10)
+//	res, err := in.Eval("n * 2") // res is []interface{}{int64(20)}
+//
+// See compiler.Interpreter for the details.
+package gi
+
+import (
+	"github.com/gijit/gi/pkg/compiler"
+)
+
+// Interpreter evaluates Go source incrementally, with
+// definitions persisting from one Eval to the next.
+type Interpreter = compiler.Interpreter
+
+// Config holds the same settings as the `gi` command line flags.
+type Config = compiler.GIConfig
+
+// NewConfig returns the default configuration.
+func NewConfig() *Config {
+	return compiler.NewGIConfig()
+}
+
+// NewInterpreter starts a new interpreter. cfg may be nil.
+func NewInterpreter(cfg *Config) (*Interpreter, error) {
+	return compiler.NewInterpreter(cfg)
+}
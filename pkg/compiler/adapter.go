@@ -0,0 +1,182 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/gijit/gi/pkg/compiler/shadow"
+	golua "github.com/glycerine/golua/lua"
+	"github.com/glycerine/luar"
+)
+
+// Interpreted values crossing into compiled Go code as a Go
+// interface travel in adapters: Go values whose methods call
+// back into Lua. The adapters for each package's interfaces
+// are written by gen-gijit-shadow-import (see genshadow.go)
+// and registered with the shadow package; luar asks for one,
+// through luar.GijitAdapter, whenever a Lua table is converted
+// to a Go interface.
+
+func init() {
+	luar.GijitAdapter = gijitAdapter
+}
+
+var (
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// vmOf finds the LuaVm of a golua.State, so that
+// adapters can take turns with the vm's other work.
+var vmOf = struct {
+	sync.Mutex
+	m map[*golua.State]*LuaVm
+}{m: make(map[*golua.State]*LuaVm)}
+
+func rememberVm(lvm *LuaVm) {
+	vmOf.Lock()
+	vmOf.m[lvm.vm] = lvm
+	vmOf.Unlock()
+}
+
+func forgetVm(lvm *LuaVm) {
+	vmOf.Lock()
+	delete(vmOf.m, lvm.vm)
+	vmOf.Unlock()
+}
+
+// gijitAdapter implements luar.GijitAdapter. The value at
+// idx qualifies when it has every method of t. Into an empty
+// interface, values with an Error or String method go as an
+// error or a fmt.Stringer, so that fmt calls those methods.
+func gijitAdapter(L *golua.State, idx int, t reflect.Type) (reflect.Value, bool) {
+	if t.NumMethod() == 0 {
+		return gijitPrintable(L, idx)
+	}
+	for i := 0; i < t.NumMethod(); i++ {
+		if !gijitHasMethod(L, idx, t.Method(i).Name) {
+			return reflect.Value{}, false
+		}
+	}
+	return shadow.Adapter(t, gijitCaller(L, idx))
+}
+
+// gijitHasMethod asks __gijitHasMethod in tsys.lua
+// whether the value at idx has the method name.
+func gijitHasMethod(L *golua.State, idx int, name string) bool {
+	if L.Type(idx) != golua.LUA_TTABLE {
+		return false
+	}
+	if idx < 0 && idx > golua.LUA_REGISTRYINDEX {
+		idx = L.GetTop() + idx + 1
+	}
+	top := L.GetTop()
+	defer L.SetTop(top)
+	L.GetGlobal("__gijitHasMethod")
+	if !L.IsFunction(-1) {
+		return false
+	}
+	L.PushValue(idx)
+	L.PushString(name)
+	if err := L.Call(2, 1); err != nil {
+		return false
+	}
+	return L.ToBoolean(-1)
+}
+
+// gijitCaller returns the shadow.Caller for the value
+// at idx. Its calls run on the goroutine that has the
+// vm: right away when Go code called from Lua calls
+// back, otherwise once the vm is free.
+func gijitCaller(L *golua.State, idx int) shadow.Caller {
+	obj := luar.NewLuaObject(L, idx)
+	vmOf.Lock()
+	lvm := vmOf.m[L]
+	vmOf.Unlock()
+
+	return func(method string, args []interface{}, results []interface{}) {
+		call := func() {
+			callGijitMethod(L, obj, method, args, results)
+		}
+		if lvm == nil {
+			call()
+			return
+		}
+		lvm.goro.doFunc(call)
+	}
+}
+
+// callGijitMethod calls obj:method(args...), storing
+// the results through the pointers in results.
+func callGijitMethod(L *golua.State, obj *luar.LuaObject, method string, args []interface{}, results []interface{}) {
+	top := L.GetTop()
+	defer L.SetTop(top)
+
+	obj.Push()
+	L.GetField(-1, method)
+	L.Insert(-2)
+	for _, a := range args {
+		luar.GoToLuaProxy(L, a)
+	}
+	if err := L.Call(len(args)+1, len(results)); err != nil {
+		panic(err)
+	}
+	for i, r := range results {
+		if _, err := luar.LuaToGo(L, top+1+i, r); err != nil {
+			panic(fmt.Errorf("result %d of %s: %v", i+1, method, err))
+		}
+	}
+}
+
+// gijitPrintable wraps values with an Error or String
+// method for an empty interface. The wrapper still
+// marshals to JSON as the value itself would.
+func gijitPrintable(L *golua.State, idx int) (reflect.Value, bool) {
+	var face reflect.Type
+	switch {
+	case gijitHasMethod(L, idx, "Error"):
+		face = errorType
+	case gijitHasMethod(L, idx, "String"):
+		face = stringerType
+	default:
+		return reflect.Value{}, false
+	}
+	var val interface{}
+	if v, ok, err := luar.GijitValue(L, idx); ok && err == nil {
+		val = v.Interface()
+	}
+	p := &gijitPrintableValue{call: gijitCaller(L, idx), val: val}
+	if face == errorType {
+		return reflect.ValueOf(&gijitErrorValue{p}), true
+	}
+	return reflect.ValueOf(p), true
+}
+
+// gijitPrintableValue is an interpreted value with a
+// String method, in an interface{}.
+type gijitPrintableValue struct {
+	call shadow.Caller
+	val  interface{}
+}
+
+func (p *gijitPrintableValue) String() (r0 string) {
+	p.call("String", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (p *gijitPrintableValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.val)
+}
+
+// gijitErrorValue is an interpreted value with an
+// Error method, in an interface{}.
+type gijitErrorValue struct {
+	*gijitPrintableValue
+}
+
+func (e *gijitErrorValue) Error() (r0 string) {
+	e.call("Error", []interface{}{}, []interface{}{&r0})
+	return
+}
@@ -0,0 +1,61 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1800InterpretedTypesSatisfyGoInterfaces(t *testing.T) {
+
+	cv.Convey(`values of types declared at the prompt should satisfy the Go interfaces of binary packages: sort.Sort calls their Len, Less and Swap, fmt calls their String and Error, and they pass as an error`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		code := `
+import (
+	"fmt"
+	"sort"
+)
+type byLen struct {
+	s []string
+}
+func (b *byLen) Len() int { return len(b.s) }
+func (b *byLen) Less(i, j int) bool { return len(b.s[i]) < len(b.s[j]) }
+func (b *byLen) Swap(i, j int) { b.s[i], b.s[j] = b.s[j], b.s[i] }
+
+words := &byLen{s: []string{"ccc", "a", "dddd", "bb"}}
+sort.Sort(words)
+first := words.s[0]
+last := words.s[3]
+sorted := sort.IsSorted(words)
+
+type Point struct {
+	X, Y int
+}
+func (p Point) String() string { return fmt.Sprintf("(%d, %d)", p.X, p.Y) }
+pt := fmt.Sprintf("%v and %s", Point{X: 1, Y: 2}, Point{X: 3, Y: 4})
+
+type myErr struct {
+	msg string
+}
+func (e *myErr) Error() string { return "myErr: " + e.msg }
+var e error = &myErr{msg: "boom"}
+printed := fmt.Sprintf("%v", e)
+wrapped := fmt.Errorf("wrap: %w", e).Error()
+`
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustString(vm, "first", "a")
+		LuaMustString(vm, "last", "dddd")
+		LuaMustBool(vm, "sorted", true)
+		LuaMustString(vm, "pt", "(1, 2) and (3, 4)")
+		LuaMustString(vm, "printed", "myErr: boom")
+		LuaMustString(vm, "wrapped", "wrap: myErr: boom")
+	})
+}
@@ -20,6 +20,7 @@ import (
 	//"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -119,10 +120,16 @@ func (s *Session) importWithSrcDir(path string, srcDir string, mode build.Import
 		// These stdlib packages have cgo and non-cgo versions (via build tags); we want the latter.
 		bctx.CgoEnabled = false
 	}
-	pkg, err := bctx.Import(path, srcDir, mode)
+	pkg, mod, err := importFromModule(bctx, path, srcDir, mode)
 	if err != nil {
 		return nil, err
 	}
+	if pkg == nil {
+		pkg, err = bctx.Import(path, srcDir, mode)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// TODO: Resolve issue #415 and remove this temporary workaround.
 	if strings.HasSuffix(pkg.ImportPath, "/vendor/github.com/glycerine/gofront/incr/js") {
@@ -155,10 +162,12 @@ func (s *Session) importWithSrcDir(path string, srcDir string, mode build.Import
 
 	if _, err := os.Stat(pkg.PkgObj); os.IsNotExist(err) && strings.HasPrefix(pkg.PkgObj, build.Default.GOROOT) {
 		// fall back to GOPATH
-		firstGopathWorkspace := filepath.SplitList(build.Default.GOPATH)[0] // TODO: Need to check inside all GOPATH workspaces.
-		gopathPkgObj := filepath.Join(firstGopathWorkspace, pkg.PkgObj[len(build.Default.GOROOT):])
-		if _, err := os.Stat(gopathPkgObj); err == nil {
-			pkg.PkgObj = gopathPkgObj
+		for _, workspace := range filepath.SplitList(build.Default.GOPATH) {
+			gopathPkgObj := filepath.Join(workspace, pkg.PkgObj[len(build.Default.GOROOT):])
+			if _, err := os.Stat(gopathPkgObj); err == nil {
+				pkg.PkgObj = gopathPkgObj
+				break
+			}
 		}
 	}
 
@@ -167,7 +176,7 @@ func (s *Session) importWithSrcDir(path string, srcDir string, mode build.Import
 		return nil, err
 	}
 
-	return &PackageData{Package: pkg, JSFiles: jsFiles}, nil
+	return &PackageData{Package: pkg, JSFiles: jsFiles, Module: mod}, nil
 }
 
 // excludeExecutable excludes all executable implementation .go files.
@@ -451,6 +460,7 @@ type Options struct {
 	Color          bool
 	BuildTags      []string
 	WriteToFile    bool
+	CacheDir       string // for compiled source packages; see cache.go.
 }
 
 func (o *Options) PrintError(format string, a ...interface{}) {
@@ -473,6 +483,7 @@ type PackageData struct {
 	IsTest     bool // IsTest is true if the package is being built for running tests.
 	SrcModTime time.Time
 	UpToDate   bool
+	Module     *goModule // the main module, if found through its go.mod.
 }
 
 type Session struct {
@@ -482,6 +493,17 @@ type Session struct {
 	Watcher  *fsnotify.Watcher
 	//AllowImportCaching bool
 	ic *IncrState
+
+	// the archive cache keys of the source packages
+	// built, and which of them came from the cache.
+	archiveKeys map[string]string
+	cacheHits   map[string]bool
+
+	// the source directory of each package built, and
+	// those changed since; see reload.go.
+	watchMu sync.Mutex
+	pkgDirs map[string]string
+	stale   map[string]bool
 }
 
 func NewSession(options *Options, ic *IncrState) *Session {
@@ -497,6 +519,11 @@ func NewSession(options *Options, ic *IncrState) *Session {
 		options:  options,
 		Archives: make(map[string]*Archive),
 		ic:       ic,
+
+		archiveKeys: make(map[string]string),
+		cacheHits:   make(map[string]bool),
+		pkgDirs:     make(map[string]string),
+		stale:       make(map[string]bool),
 	}
 	s.Types = make(map[string]*types.Package)
 	return s
@@ -606,7 +633,12 @@ func (s *Session) BuildPackage(pkg *PackageData, depth int) (*Archive, error) {
 
 	//}
 
-	if pkg.PkgObj != "" {
+	s.watchDir(pkg)
+
+	// module packages have no PkgObj, but still need
+	// their imports brought in first.
+	var cacheKey string
+	if pkg.PkgObj != "" || pkg.Module != nil {
 		var fileInfo os.FileInfo
 		gijitBinary, err := os.Executable()
 		if err == nil {
@@ -668,36 +700,21 @@ func (s *Session) BuildPackage(pkg *PackageData, depth int) (*Archive, error) {
 			}
 		}
 
-		cachingAllowed := false
-		if cachingAllowed {
-			pkgObjFileInfo, err := os.Stat(pkg.PkgObj)
-			if err == nil && !pkg.SrcModTime.After(pkgObjFileInfo.ModTime()) {
-				pp("\n\n package object is up to date, load from disk if library\n\n")
-				// package object is up to date, load from disk if library
+		// the on-disk archive cache, see cache.go.
+		if !pkg.IsCommand() {
+			cacheKey, err = s.archiveKey(pkg)
+			if err != nil {
+				return nil, err
+			}
+			if archive := s.readCachedArchive(pkg.ImportPath, cacheKey); archive != nil {
+				pp("\n\n read cached archive for import path='%s'", pkg.ImportPath)
 				pkg.UpToDate = true
-				if pkg.IsCommand() {
-					return nil, nil
-				}
-
-				objFile, err := os.Open(pkg.PkgObj)
-				if err != nil {
-					return nil, err
-				}
-				defer objFile.Close()
-
-				archive, err := ReadArchive(pkg.PkgObj, pkg.ImportPath, objFile, s.Types)
-				if err != nil {
-					return nil, err
-				}
-
-				pp("\n\n Just read Archive from disk, filename='%s', import path='%s'. archive.Pkg='%#v'\n and archive='%#v'", pkg.PkgObj, pkg.ImportPath, archive.Pkg, archive)
-
 				s.Archives[pkg.ImportPath] = archive
-
-				return archive, err
-			} // end ReadArchive from disk
-
-		} // end if cachingAllowed
+				s.archiveKeys[pkg.ImportPath] = cacheKey
+				s.cacheHits[pkg.ImportPath] = true
+				return archive, nil
+			}
+		}
 	}
 
 	fileSet := token.NewFileSet()
@@ -709,6 +726,7 @@ func (s *Session) BuildPackage(pkg *PackageData, depth int) (*Archive, error) {
 	//localImportPathCache := make(map[string]*Archive)
 	importContext := &ImportContext{
 		Packages: s.Types,
+		Preempt:  s.ic != nil && s.ic.cfg.Preempt,
 		Import: func(path, pkgDir string, depth int) (*Archive, error) {
 			pp("callback to Import() in ImportContext: path='%s', pkgDir='%s'", path, pkgDir)
 			//if s.AllowImportCaching? TODO figure out balance between speed and editability.
@@ -780,6 +798,13 @@ func (s *Session) BuildPackage(pkg *PackageData, depth int) (*Archive, error) {
 	}
 
 	s.Archives[pkg.ImportPath] = archive
+	if cacheKey != "" {
+		s.archiveKeys[pkg.ImportPath] = cacheKey
+		if err := s.writeCachedArchive(cacheKey, archive); err != nil {
+			// only the next start is slower.
+			s.options.PrintError("could not cache the archive of %s: %v\n", pkg.ImportPath, err)
+		}
+	}
 
 	if pkg.PkgObj == "" || pkg.IsCommand() {
 		pp("\n\n returning early, pkg.PkgObj==\"\" or pkg.IsCommand()=%v, archive.Pkg='%#v'\n", pkg.IsCommand(), archive.Pkg)
@@ -0,0 +1,201 @@
+package compiler
+
+// cache.go: compiled source packages are kept on disk, so
+// that importing a large package is quick the second time.
+//
+// BuildPackage writes each archive it compiles to the
+// cache directory (~/.cache/gijit by default), under a key
+// that hashes everything the compile depended on: the gi
+// binary, the build tags, the package's files, and the keys
+// of the source packages it imports. A change to any of
+// those gives a new key, so stale archives are never read,
+// only left behind until :cache clear removes them.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// archiveCacheFormat changes whenever the saved
+// archive's layout does.
+const archiveCacheFormat = "gijit archive cache 1"
+
+// ArchiveCacheDir is where compiled source packages are
+// kept, unless Options.CacheDir says otherwise.
+func ArchiveCacheDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "gijit")
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, ".cache", "gijit")
+	}
+	return filepath.Join(os.TempDir(), "gijit-cache")
+}
+
+func (s *Session) cacheDir() string {
+	if s.options.CacheDir != "" {
+		return s.options.CacheDir
+	}
+	return ArchiveCacheDir()
+}
+
+var giIdentity struct {
+	once sync.Once
+	id   string
+}
+
+// giBinaryIdentity names the build of gi that is running,
+// since the archives it writes depend on its compiler and
+// its natives. A development build has no version stamped
+// in, so the executable's size and time stand in for one.
+func giBinaryIdentity() string {
+	giIdentity.once.Do(func() {
+		giIdentity.id = Version()
+		exe, err := os.Executable()
+		if err == nil {
+			var fi os.FileInfo
+			fi, err = os.Stat(exe)
+			if err == nil {
+				giIdentity.id += fmt.Sprintf("\n%s %d %d", exe, fi.Size(), fi.ModTime().UnixNano())
+			}
+		}
+	})
+	return giIdentity.id
+}
+
+// archiveKey hashes what the archive of pkg depends on.
+// The source packages pkg imports must already be built.
+func (s *Session) archiveKey(pkg *PackageData) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", archiveCacheFormat, giBinaryIdentity())
+	fmt.Fprintf(h, "path %s\ntags %q\nminify %v\ntest %v\n", pkg.ImportPath, s.options.BuildTags, s.options.Minify, pkg.IsTest)
+
+	var files []string
+	files = append(files, pkg.GoFiles...)
+	files = append(files, pkg.JSFiles...)
+	if pkg.IsTest {
+		files = append(files, pkg.TestGoFiles...)
+	}
+	sort.Strings(files)
+	for _, name := range files {
+		f, err := os.Open(filepath.Join(pkg.Dir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file %s\n", name)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	imports := append([]string(nil), pkg.Imports...)
+	sort.Strings(imports)
+	for _, path := range imports {
+		// shadowed and binary packages have no key;
+		// they come with the gi binary.
+		fmt.Fprintf(h, "import %s %s\n", path, s.archiveKeys[path])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *Session) cachedArchivePath(key string) string {
+	return filepath.Join(s.cacheDir(), "archives", key[:2], key)
+}
+
+// readCachedArchive returns the archive saved under key,
+// or nil if there isn't one that can be read.
+func (s *Session) readCachedArchive(path, key string) *Archive {
+	fn := s.cachedArchivePath(key)
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	// the export data names packages by their Path, so
+	// make sure it finds the ones already imported,
+	// shadowed packages among them, and not fresh copies
+	// whose types would be distinct.
+	for _, a := range s.Archives {
+		if a.Pkg != nil && s.Types[a.Pkg.Path()] == nil {
+			s.Types[a.Pkg.Path()] = a.Pkg
+		}
+	}
+	archive, err := ReadArchive(fn, path, f, s.Types)
+	if err != nil {
+		// a damaged entry; the compile will replace it.
+		pp("ignoring unreadable cached archive '%s': '%v'", fn, err)
+		return nil
+	}
+	archive.Pkg = s.Types[path]
+	return archive
+}
+
+// writeCachedArchive saves archive under key. The write
+// goes through a temporary file, so that another gi reading
+// the same entry never sees half of it.
+func (s *Session) writeCachedArchive(key string, archive *Archive) error {
+	fn := s.cachedArchivePath(key)
+	if err := os.MkdirAll(filepath.Dir(fn), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(fn), key+".tmp")
+	if err != nil {
+		return err
+	}
+	err = WriteArchive(archive, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fn)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// CachedImports lists the packages this session loaded
+// from the archive cache rather than compiling them.
+func (s *Session) CachedImports() []string {
+	var paths []string
+	for path := range s.cacheHits {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// CacheSize counts the archives in the cache, and
+// their bytes.
+func (s *Session) CacheSize() (n int, bytes int64, err error) {
+	err = filepath.Walk(filepath.Join(s.cacheDir(), "archives"), func(path string, fi os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			n++
+			bytes += fi.Size()
+		}
+		return nil
+	})
+	return
+}
+
+// ClearCache removes every archive from the cache.
+func (s *Session) ClearCache() error {
+	return os.RemoveAll(filepath.Join(s.cacheDir(), "archives"))
+}
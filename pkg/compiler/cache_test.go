@@ -0,0 +1,85 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test2600ArchiveCache(t *testing.T) {
+
+	cv.Convey(`a source import should be compiled once and then read from the archive cache, until its files or those of a package it imports change`, t, func() {
+
+		dir, err := ioutil.TempDir("", "gijit-cache")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+		writeFixture(dir, map[string]string{
+			"app/go.mod": "module example.com/app\n",
+			"app/shout/shout.go": `package shout
+
+import "example.com/app/bang"
+
+func Shout(s string) string { return bang.Bang(s) }
+`,
+			"app/bang/bang.go": "package bang\n\nfunc Bang(s string) string { return s + \"!\" }\n",
+		})
+		cacheDir := filepath.Join(dir, "cache")
+
+		wd, err := os.Getwd()
+		panicOn(err)
+		defer os.Chdir(wd)
+		panicOn(os.Chdir(filepath.Join(dir, "app")))
+
+		// start imports shout at a fresh prompt, and
+		// returns the packages read from the cache.
+		start := func(want string) []string {
+			vm, cfg := newVmAnywhere()
+			defer vm.Close()
+			inc := NewIncrState(vm, cfg)
+			inc.Session.options.CacheDir = cacheDir
+			translation, err := inc.Tr([]byte(`
+import "example.com/app/shout"
+s := shout.Shout("hi")
+`))
+			panicOn(err)
+			LuaRunAndReport(vm, string(translation))
+			LuaMustString(vm, "s", want)
+			return inc.Session.CachedImports()
+		}
+		entries := func() int {
+			s := NewSession(&Options{CacheDir: cacheDir}, nil)
+			n, _, err := s.CacheSize()
+			panicOn(err)
+			return n
+		}
+
+		cv.So(start("hi!"), cv.ShouldBeEmpty)
+		cv.So(entries(), cv.ShouldEqual, 2)
+		cv.So(start("hi!"), cv.ShouldResemble, []string{"example.com/app/bang", "example.com/app/shout"})
+
+		// shout's key includes bang's, so both rebuild.
+		writeFixture(dir, map[string]string{
+			"app/bang/bang.go": "package bang\n\nfunc Bang(s string) string { return s + \"!!\" }\n",
+		})
+		cv.So(start("hi!!"), cv.ShouldBeEmpty)
+		cv.So(entries(), cv.ShouldEqual, 4)
+
+		writeFixture(dir, map[string]string{
+			"app/shout/shout.go": `package shout
+
+import "example.com/app/bang"
+
+// Shout is louder now.
+func Shout(s string) string { return bang.Bang(bang.Bang(s)) }
+`,
+		})
+		cv.So(start("hi!!!!"), cv.ShouldResemble, []string{"example.com/app/bang"})
+
+		panicOn(NewSession(&Options{CacheDir: cacheDir}, nil).ClearCache())
+		cv.So(entries(), cv.ShouldEqual, 0)
+		cv.So(start("hi!!!!"), cv.ShouldBeEmpty)
+	})
+}
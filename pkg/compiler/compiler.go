@@ -88,6 +88,15 @@ type Archive struct {
 	Check     *types.Checker
 
 	FuncSrcCache map[string]string
+
+	// DeclGraph tracks top-level declarations and
+	// their dependencies across inputs, see redef.go.
+	DeclGraph *declGraph
+
+	// ImportedCode is the package code the latest input
+	// took from the archives of its imports, which a
+	// rollback gives back; see txn.go.
+	ImportedCode []importedCode
 }
 
 type Decl struct {
@@ -440,6 +449,10 @@ type SourceMapFilter struct {
 	line            int
 	column          int
 	fileSet         *token.FileSet
+
+	// PosCallback, if set, gets the line and position of each
+	// mark, unresolved, for the line maps of linemap.go.
+	PosCallback func(generatedLine int, originalPos token.Pos)
 }
 
 func (f *SourceMapFilter) Write(p []byte) (n int, err error) {
@@ -470,6 +483,9 @@ func (f *SourceMapFilter) Write(p []byte) (n int, err error) {
 		if f.MappingCallback != nil {
 			f.MappingCallback(f.line+1, f.column, f.fileSet.Position(token.Pos(binary.BigEndian.Uint32(p[i+1:i+5]))))
 		}
+		if f.PosCallback != nil {
+			f.PosCallback(f.line+1, token.Pos(binary.BigEndian.Uint32(p[i+1:i+5])))
+		}
 		p = p[i+5:]
 		n += 5
 	}
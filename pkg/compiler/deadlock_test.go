@@ -0,0 +1,65 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test2100DeadlockAndGoroutines(t *testing.T) {
+
+	cv.Convey(`:goroutines should list each blocked goroutine with what it waits on and its Go line, and an eval that blocks with every goroutine asleep should fail with ErrDeadlock and leave the vm usable`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) error {
+			translation, err := inc.Tr([]byte(src))
+			panicOn(err)
+			LuaRunAndReport(vm, inc.tagChunk(string(translation)))
+			return lastEvalError(vm)
+		}
+
+		err = run(`
+import "sync"
+ch := make(chan int)
+c2 := make(chan int)
+go func() {
+	<-c2
+}()
+var wg sync.WaitGroup
+wg.Add(1)
+go func() {
+	wg.Wait()
+}()
+`)
+		cv.So(err, cv.ShouldBeNil)
+
+		report := goroutinesReport(vm)
+		cv.So(report, cv.ShouldContainSubstring, "[chan receive]")
+		cv.So(report, cv.ShouldContainSubstring, "waiting on c2")
+		cv.So(report, cv.ShouldContainSubstring, "[sync.WaitGroup.Wait]")
+		cv.So(report, cv.ShouldContainSubstring, "<input ")
+
+		err = run(`<-ch`)
+		cv.So(err, cv.ShouldEqual, ErrDeadlock)
+
+		// the abandoned eval is gone from the list,
+		// and the next eval runs as usual.
+		err = run(`x := 7`)
+		cv.So(err, cv.ShouldBeNil)
+		LuaMustInt64(vm, "x", 7)
+		cv.So(strings.Count(goroutinesReport(vm), "waiting on ch"), cv.ShouldEqual, 0)
+
+		// the goroutines can still be woken.
+		err = run(`
+wg.Done()
+c2 <- 1
+`)
+		cv.So(err, cv.ShouldBeNil)
+		cv.So(goroutinesReport(vm), cv.ShouldNotContainSubstring, "waiting on c2")
+	})
+}
@@ -287,6 +287,14 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			return c.formatExpr("__makeMap({%s}, %s, %s, %s)", joined, keyName, eleName, xName)
 		case *types.Struct:
 			pp("in expressions.go, for *types.Struct")
+			if _, isNamed := exprType.(*types.Named); isNamed && len(e.Elts) == 0 {
+				// sync.WaitGroup{} and the like: a shadow
+				// struct literal is its zero value, from
+				// the type's constructor.
+				if isShad, shortPkgAndTyp := isShadowStruct(exprType.String()); isShad {
+					return c.formatExpr("__type__.%s()", shortPkgAndTyp)
+				}
+			}
 			elements := make([]string, t.NumFields())
 			isKeyValue := true
 			if len(e.Elts) != 0 {
@@ -463,17 +471,18 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 				return c.formatExpr("-(%1e)", e.X)
 				//return c.formatExpr("-(%1r+%1i)", e.X)
 				//return c.formatExpr("%1s(-%2r, -%2i)", c.typeName(0, t), e.X)
-			case isUnsigned(basic):
-				return c.fixNumber(c.formatExpr("-%e", e.X), basic)
-			default:
+			case isFloat(basic):
 				return c.formatExpr("-%e", e.X)
+			default:
+				// -(-128) is -128 for an int8.
+				return c.fixNumber(c.formatExpr("-(%e)", e.X), basic)
 			}
 		case token.XOR:
 			if is64Bit(basic) {
-				return c.formatExpr("__bit.bxor(%e)", e.X)
+				return c.formatExpr("__bit.bnot(%e)", e.X)
 				//return c.formatExpr("%1s(~%2h, ~%2l >>> 0)", c.typeName(0, t), e.X)
 			}
-			return c.fixNumber(c.formatExpr("~%e", e.X), basic)
+			return c.fixNumber(c.formatExpr("__bit.bnot(%e)", e.X), basic)
 		case token.NOT:
 			return c.formatExpr(" not %e", e.X)
 		default:
@@ -538,8 +547,11 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 					//	shift = ">>>"
 					//}
 
-					// jea:
-					return c.formatExpr(`__integerByZeroCheck(%1e / %2e)`, e.X, e.Y)
+					// jea: MinInt32 / -1 wraps, so fixNumber.
+					if isUnsigned(basic) {
+						return c.fixNumber(c.formatExpr(`__divUint64(%e, %e)`, e.X, e.Y), basic)
+					}
+					return c.fixNumber(c.formatExpr(`__divInt64(%e, %e)`, e.X, e.Y), basic)
 					// return c.formatExpr(`(%1s = %2e / %3e, (%1s == %1s && %1s ~= 1/0 && %1s ~= -1/0) ? %1s %4s 0 : error("integer divide by zero"))`, c.newVariable("_q"), e.X, e.Y, shift)
 				}
 				if basic.Kind() == types.Float32 {
@@ -547,7 +559,10 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 				}
 				return c.formatExpr("((%e) / (%e))", e.X, e.Y)
 			case token.REM:
-				return c.formatExpr(`__integerByZeroCheck(%1e %% %2e)`, e.X, e.Y)
+				if isUnsigned(basic) {
+					return c.formatExpr(`__remUint64(%e, %e)`, e.X, e.Y)
+				}
+				return c.formatExpr(`__remInt64(%e, %e)`, e.X, e.Y)
 			case token.SHL, token.SHR:
 				op := e.Op.String()
 				if e.Op == token.SHR {
@@ -562,11 +577,32 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 				if v := c.p.Types[e.Y].Value; v != nil {
 					i, _ := constant.Uint64Val(constant.ToInt(v))
 					if i >= 64 {
+						// every bit is shifted out, leaving the sign for >>.
+						switch {
+						case e.Op == token.SHR && !isUnsigned(basic):
+							return c.formatExpr("__bit.arshift(%e, 63)", e.X)
+						case isUnsigned(basic):
+							return c.formatExpr("0ULL")
+						}
 						return c.formatExpr("0LL")
 					}
 					return c.fixNumber(c.formatExpr("%s(%e, %s)", op, e.X, strconv.FormatUint(i, 10)), basic)
 				}
-				return c.fixNumber(c.formatExpr("%s(%e, %e)", op, e.X, e.Y), basic)
+				// the bit library takes the count mod 64; Go doesn't.
+				shift := "__shlInt64"
+				switch {
+				case e.Op == token.SHL && isUnsigned(basic):
+					shift = "__shlUint64"
+				case e.Op == token.SHR && isUnsigned(basic):
+					shift = "__shrUint64"
+				case e.Op == token.SHR:
+					shift = "__shrInt64"
+				}
+				// a negative signed count panics.
+				if !isUnsigned(c.p.TypeOf(e.Y).Underlying().(*types.Basic)) {
+					return c.fixNumber(c.formatExpr("%s(%e, __shiftCount(%e))", shift, e.X, e.Y), basic)
+				}
+				return c.fixNumber(c.formatExpr("%s(%e, %e)", shift, e.X, e.Y), basic)
 
 				//if e.Op == token.SHR && !isUnsigned(basic) {
 				//	return c.fixNumber(c.formatParenExpr("%e >> __min(%f, 31)", e.X, e.Y), basic)
@@ -682,14 +718,10 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			if typesutil.IsJsObject(c.p.TypeOf(e.Index)) {
 				c.p.errList = append(c.p.errList, types.Error{Fset: c.p.fileSet, Pos: e.Index.Pos(), Msg: "cannot use js.Object as map key"})
 			}
+			// the map's keyFor turns key into the string it is
+			// stored under, so composite keys compare by value.
 			key := fmt.Sprintf("%s", c.translateImplicitConversion(e.Index, t.Key()))
 			pp("t.Key()='%T'/%#v", t.Key(), t.Key())
-			switch bas := t.Key().(type) {
-			case *types.Basic:
-				if bas.Kind() != types.String {
-					key = `"` + key + `"`
-				}
-			}
 			//key := fmt.Sprintf("%s.keyFor(%s)", c.typeName(0, t.Key()), c.translateImplicitConversion(e.Index, t.Key()))
 			if _, isTuple := exprType.(*types.Tuple); isTuple {
 
@@ -1387,6 +1419,19 @@ func (c *funcContext) translateConversion(expr ast.Expr, desiredType types.Type)
 		switch {
 		case isInteger(t):
 			basicExprType := exprType.Underlying().(*types.Basic)
+			if isInteger(basicExprType) && isUnsigned(t) != isUnsigned(basicExprType) {
+				// a change of signedness keeps the bits:
+				// uint(int8(-1)) is 1<<64 - 1.
+				cast := "__castInt64"
+				if isUnsigned(t) {
+					cast = "__castUint64"
+				}
+				value := c.formatExpr("%s(%e)", cast, expr)
+				if _, isNamed := desiredType.(*types.Named); isNamed && is64Bit(t) {
+					return c.formatExpr("%s(%s)", c.typeName(desiredType, nil), value)
+				}
+				return c.fixNumber(value, t)
+			}
 			switch {
 			case is64Bit(t):
 				if !is64Bit(basicExprType) {
@@ -1398,14 +1443,12 @@ func (c *funcContext) translateConversion(expr ast.Expr, desiredType types.Type)
 				}
 				return c.formatExpr("%s(%e)", c.typeName(desiredType, nil), expr)
 			case is64Bit(basicExprType):
-				if !isUnsigned(t) && !isUnsigned(basicExprType) {
-					return c.formatParenExpr("%e", expr)
-				}
-				return c.formatExpr("%s", c.translateExpr(expr, nil), t)
+				// int32(x) keeps the low 32 bits of x.
+				return c.fixNumber(c.translateExpr(expr, nil), t)
 			case isFloat(basicExprType):
 				// jea
 				//return c.formatParenExpr("%e >> 0", expr)
-				return c.formatParenExpr("int(%e)", expr)
+				return c.fixNumber(c.formatParenExpr("int(%e)", expr), t)
 			case types.Identical(exprType, types.Typ[types.UnsafePointer]):
 				return c.translateExpr(expr, nil)
 			default:
@@ -1682,7 +1725,22 @@ func (c *funcContext) fixNumber(value *expression, basic *types.Basic) (xprn *ex
 	defer func() {
 		pp("returning from fixNumber with xprn='%s'", x2s(xprn))
 	}()
+	// integers are int64 or uint64 cdata, whose arithmetic
+	// wraps at 64 bits; wrap the narrower ones to their
+	// width. See __wrapInt8 and friends in prelude/int64.lua.
 	switch basic.Kind() {
+	case types.Int8:
+		return c.formatExpr("__wrapInt8(%s)", value)
+	case types.Int16:
+		return c.formatExpr("__wrapInt16(%s)", value)
+	case types.Int32:
+		return c.formatExpr("__wrapInt32(%s)", value)
+	case types.Uint8:
+		return c.formatExpr("__wrapUint8(%s)", value)
+	case types.Uint16:
+		return c.formatExpr("__wrapUint16(%s)", value)
+	case types.Uint32:
+		return c.formatExpr("__wrapUint32(%s)", value)
 	case types.Float32, types.Float64:
 		return value
 	default:
@@ -151,6 +151,7 @@ func FullPackageCompile(importPath string, files []*ast.File, fileSet *token.Fil
 			indentation:  1,
 			dependencies: make(map[types.Object]bool),
 			minify:       minify,
+			preempt:      importContext.Preempt,
 			fileSet:      fileSet,
 			files:        files,
 		},
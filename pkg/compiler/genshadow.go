@@ -1,8 +1,12 @@
 package compiler
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 
 	"path/filepath"
 
@@ -36,20 +40,16 @@ func GenShadowImport(importPath, dirForVendor, residentPkg, outDir string) error
 
 	pkgName := pkg.Name()
 
-	o, err := os.Create(outDir + string(os.PathSeparator) + pkgName + ".genimp.go")
-	if err != nil {
-		return err
+	// the adapters can mention other packages'
+	// types, so the imports are known only at the
+	// end: write the body first.
+	o := &bytes.Buffer{}
+	imports := map[string]bool{importPath: true}
+	qual := func(p *types.Package) string {
+		imports[p.Path()] = true
+		return p.Name()
 	}
-
-	fmt.Fprintf(o, `package shadow_%s
-
-import "%s"
-
-var Pkg = make(map[string]interface{})
-var Ctor = make(map[string]interface{})
-
-func init() {
-`, base, importPath)
+	adapters := 0
 
 	scope := pkg.Scope()
 	nms := scope.Names()
@@ -116,6 +116,9 @@ func init() {
 				switch obj.(type) {
 				case *types.TypeName:
 					ifaceTemplate(o, obj, nm, pkgName, oty, under, &atEnd)
+					if adapterTemplate(o, nm, pkgName, under.(*types.Interface), qual, &atEnd) {
+						adapters++
+					}
 				case *types.Var:
 					direct(o, nm, pkgName)
 				default:
@@ -145,7 +148,35 @@ func init() {
 	}
 	fmt.Fprintf(o, "%s", genInitLuaFinish(pkgName))
 
-	return nil
+	// self-register, so that linking the generated
+	// package into gi is all it takes to import it.
+	fmt.Fprintf(o, "%s", genRegister(importPath, adapters > 0))
+
+	f, err := os.Create(outDir + string(os.PathSeparator) + pkgName + ".genimp.go")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "package shadow_%s\n\n", base)
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(f, "import %q\n", path)
+	}
+	fmt.Fprintf(f, `import "github.com/gijit/gi/pkg/compiler/shadow"
+
+var Pkg = make(map[string]interface{})
+var Ctor = make(map[string]interface{})
+`)
+	if adapters > 0 {
+		fmt.Fprintf(f, "var Adapter = make(map[string]interface{})\n")
+	}
+	fmt.Fprintf(f, "\nfunc init() {\n")
+	_, err = o.WriteTo(f)
+	return err
 }
 
 /* make a function like:
@@ -181,11 +212,11 @@ func %s(x interface{}) %s.%s {
 	return
 }
 
-func direct(o *os.File, nm, pkgName string) {
+func direct(o io.Writer, nm, pkgName string) {
 	fmt.Fprintf(o, "    Pkg[\"%s\"] = %s.%s\n", nm, pkgName, nm)
 }
 
-func ctor(o *os.File, nm, pkgName string) {
+func ctor(o io.Writer, nm, pkgName string) {
 	fmt.Fprintf(o, "    Ctor[\"%[1]s\"] = GijitShadow_NewStruct_%[1]s\n", nm)
 }
 
@@ -198,7 +229,7 @@ func GijitShadow_CtorStruct_Time(src *time.Time) *time.Time {
 	return &a
 }
 */
-func structTemplate(o *os.File, obj types.Object, nm, pkgName string, oty, under types.Type, atEnd *[]string) {
+func structTemplate(o io.Writer, obj types.Object, nm, pkgName string, oty, under types.Type, atEnd *[]string) {
 	// example from "io":
 	/*
 		type PipeReader struct {
@@ -223,7 +254,7 @@ func GijitShadow_NewStruct_%[2]s(src *%[1]s.%[2]s) *%[1]s.%[2]s {
 
 }
 
-func ifaceTemplate(o *os.File, obj types.Object, nm, pkgName string, oty, under types.Type, atEnd *[]string) {
+func ifaceTemplate(o io.Writer, obj types.Object, nm, pkgName string, oty, under types.Type, atEnd *[]string) {
 
 	//pp("ifaceTemplate:: we see Named '%s'\n. oty:'%#v',\n under:'%#v',\n, obj='%#v', \n", nm, oty, under, obj)
 
@@ -233,6 +264,129 @@ func ifaceTemplate(o *os.File, obj types.Object, nm, pkgName string, oty, under
 	//fmt.Fprintf(o, "    Pkg[\"%s\"] = %s\n", nm, funcName1)
 }
 
+/* make an adapter, like this one for io.Reader:
+
+type GijitShadow_Adapter_Reader struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Reader) Read(p0 []byte) (r0 int, r1 error) {
+	a.call("Read", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_Reader(call shadow.Caller) io.Reader {
+	return &GijitShadow_Adapter_Reader{call: call}
+}
+
+The compiler wraps a value from the REPL in one, with
+a Caller that runs its methods on the LuaJIT thread, when
+it is passed to Go as an io.Reader. There is no adapter
+when Go code outside the package couldn't implement the
+interface: for unexported methods, or unexported types
+in the method signatures.
+*/
+func adapterTemplate(o io.Writer, nm, pkgName string, iface *types.Interface, qual types.Qualifier, atEnd *[]string) bool {
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if !m.Exported() || !onlyExportedNames(m.Type(), map[types.Type]bool{}) {
+			return false
+		}
+	}
+
+	adapter := "GijitShadow_Adapter_" + nm
+	decl := fmt.Sprintf(`
+type %s struct {
+	call shadow.Caller
+}
+`, adapter)
+
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		sig := m.Type().(*types.Signature)
+		var params, args, results, resultPtrs []string
+		for j := 0; j < sig.Params().Len(); j++ {
+			ty := types.TypeString(sig.Params().At(j).Type(), qual)
+			if sig.Variadic() && j == sig.Params().Len()-1 {
+				ty = "..." + strings.TrimPrefix(ty, "[]")
+			}
+			params = append(params, fmt.Sprintf("p%d %s", j, ty))
+			args = append(args, fmt.Sprintf("p%d", j))
+		}
+		for j := 0; j < sig.Results().Len(); j++ {
+			ty := types.TypeString(sig.Results().At(j).Type(), qual)
+			results = append(results, fmt.Sprintf("r%d %s", j, ty))
+			resultPtrs = append(resultPtrs, fmt.Sprintf("&r%d", j))
+		}
+		res := ""
+		if len(results) > 0 {
+			res = " (" + strings.Join(results, ", ") + ")"
+		}
+		decl += fmt.Sprintf(`
+func (a *%s) %s(%s)%s {
+	a.call(%q, []interface{}{%s}, []interface{}{%s})
+	return
+}
+`, adapter, m.Name(), strings.Join(params, ", "), res, m.Name(),
+			strings.Join(args, ", "), strings.Join(resultPtrs, ", "))
+	}
+
+	decl += fmt.Sprintf(`
+func GijitShadow_NewAdapter_%[1]s(call shadow.Caller) %[2]s.%[1]s {
+	return &%[3]s{call: call}
+}
+`, nm, pkgName, adapter)
+
+	*atEnd = append(*atEnd, decl)
+	fmt.Fprintf(o, "    Adapter[\"%[1]s\"] = GijitShadow_NewAdapter_%[1]s\n", nm)
+	return true
+}
+
+// onlyExportedNames is false if t refers to a named
+// type that other packages can't name.
+func onlyExportedNames(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Named:
+		obj := t.Obj()
+		return obj.Pkg() == nil || obj.Exported()
+	case *types.Pointer:
+		return onlyExportedNames(t.Elem(), seen)
+	case *types.Slice:
+		return onlyExportedNames(t.Elem(), seen)
+	case *types.Array:
+		return onlyExportedNames(t.Elem(), seen)
+	case *types.Chan:
+		return onlyExportedNames(t.Elem(), seen)
+	case *types.Map:
+		return onlyExportedNames(t.Key(), seen) && onlyExportedNames(t.Elem(), seen)
+	case *types.Signature:
+		return onlyExportedNames(t.Params(), seen) && onlyExportedNames(t.Results(), seen)
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			if !onlyExportedNames(t.At(i).Type(), seen) {
+				return false
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !t.Field(i).Exported() || !onlyExportedNames(t.Field(i).Type(), seen) {
+				return false
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumMethods(); i++ {
+			if !t.Method(i).Exported() || !onlyExportedNames(t.Method(i).Type(), seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func genInitLuaStart(shortPkg string) string {
 
 	return fmt.Sprintf("\n\n func InitLua() string {\n  "+
@@ -243,6 +397,14 @@ func genInitLuaFinish(shortPkg string) string {
 	return "\n`}"
 }
 
+func genRegister(importPath string, adapters bool) string {
+	reg := fmt.Sprintf("\tshadow.Register(%q, Pkg, Ctor, InitLua)\n", importPath)
+	if adapters {
+		reg += "\tshadow.RegisterAdapters(Adapter)\n"
+	}
+	return "\n\nfunc init() {\n" + reg + "}\n"
+}
+
 func perStructInitLua(shortPkg, structName string) string {
 
 	return fmt.Sprintf(`
@@ -34,12 +34,27 @@ type Goro struct {
 	beatCount int64
 
 	Ready chan struct{}
+
+	// exclusive keeps tickets from other goroutines
+	// off the vm while one is running; depth counts the
+	// tickets running, so that callbacks into Lua from
+	// Go code a ticket called can go ahead, inside it.
+	exclusive sync.Mutex
+	depth     int32
 }
 
 type GoroConfig struct {
 	GiCfg *GIConfig
 }
 
+// globalNotFound is the error from a ticket asking
+// for a global that is nil.
+type globalNotFound string
+
+func (e globalNotFound) Error() string {
+	return fmt.Sprintf("not found: '%s'", string(e))
+}
+
 type ticket struct {
 	myGoro *Goro
 
@@ -52,6 +67,11 @@ type ticket struct {
 	// what to do after any registrations, optional
 	run []byte
 
+	//input
+	// Go code to run against the vm, optional;
+	// after run. Adapters call Lua methods with it.
+	fn func()
+
 	//input
 	// what to fetch for return, optional;
 	// one of register, run, or varname should be
@@ -106,6 +126,8 @@ const (
 	GetInt64 GetType = iota
 	GetString
 	GetChan
+	GetInterface      // any value, converted by luar.LuaToGo
+	GetInterfaceSlice // a slice, as []interface{}
 )
 
 func NewGoro(lvm *LuaVm, cfg *GoroConfig) (*Goro, error) {
@@ -207,6 +229,8 @@ func (r *Goro) handleHeartbeat() {
 
 func (r *Goro) handleTicket(t *ticket) {
 	//fmt.Printf("goro.handleTicket: top \n")
+	atomic.AddInt32(&r.depth, 1)
+	defer atomic.AddInt32(&r.depth, -1)
 
 	if len(t.regmap) > 0 {
 		luar.Register(r.vm, t.regns, t.regmap)
@@ -216,6 +240,9 @@ func (r *Goro) handleTicket(t *ticket) {
 	if len(t.run) > 0 {
 		t.runErr = r.privateRun(t.run, t.useEvalCoroutine)
 	}
+	if t.runErr == nil && t.fn != nil {
+		t.fn()
+	}
 	if t.runErr == nil && len(t.varname) > 0 {
 		for key := range t.varname {
 			if key == "" {
@@ -224,7 +251,7 @@ func (r *Goro) handleTicket(t *ticket) {
 			r.vm.GetGlobal(key)
 			if r.vm.IsNil(-1) {
 				r.vm.Pop(1)
-				t.getErr = fmt.Errorf("not found: '%s'", t.varname)
+				t.getErr = globalNotFound(key)
 				break
 			} else {
 				switch t.gettyp {
@@ -235,6 +262,14 @@ func (r *Goro) handleTicket(t *ticket) {
 				case GetChan:
 					r.vm.Pop(1)
 					t.varname[key], t.getErr = getChannelFromGlobal(r.lvm, key, true)
+				case GetInterface:
+					var i interface{}
+					t.getErr = luaToGoProtected(r.vm, &i)
+					t.varname[key] = i
+				case GetInterfaceSlice:
+					var slc []interface{}
+					t.getErr = luaToGoProtected(r.vm, &slc)
+					t.varname[key] = slc
 				}
 				if !t.leaveOnTop {
 					r.vm.Pop(1)
@@ -245,15 +280,58 @@ func (r *Goro) handleTicket(t *ticket) {
 	close(t.done)
 }
 
+// luaToGoProtected converts the value on top of the stack
+// into a, as luar.LuaToGo does, but inside a pcall: the
+// conversion can run Lua (index metamethods, say), and an
+// error raised there with no pcall around it would abort
+// the whole process. Such an error is returned instead.
+func luaToGoProtected(L *golua.State, a interface{}) error {
+	top := L.GetTop()
+	defer L.SetTop(top)
+
+	var convErr error
+	L.PushGoFunction(func(L *golua.State) int {
+		_, convErr = luar.LuaToGo(L, 1, a)
+		return 0
+	})
+	L.PushValue(top)
+	if err := L.Call(1, 0); err != nil {
+		return err
+	}
+	return convErr
+}
+
+// do runs t once it has the vm to itself. While a ticket
+// runs, the vm belongs to the Go code that ticket's Lua
+// calls: a ticket it makes, say from an adapter method,
+// runs right away. So such Go code must not pass an
+// interpreted value to another goroutine that calls it
+// before the call from Lua returns.
 func (r *Goro) do(t *ticket) {
+	if atomic.LoadInt32(&r.depth) > 0 {
+		// Lua called Go, which calls back into Lua:
+		// we already have the vm.
+		r.handleTicket(t)
+		return
+	}
 	if reserveMainThread {
 		r.doticket <- t
 		<-t.done
 	} else {
+		r.exclusive.Lock()
+		defer r.exclusive.Unlock()
 		r.handleTicket(t)
 	}
 }
 
+// doFunc runs f once it has the vm to itself,
+// like any other ticket.
+func (r *Goro) doFunc(f func()) {
+	t := r.newTicket("", false)
+	t.fn = f
+	r.do(t)
+}
+
 func (t *ticket) Do() error {
 	pp("ticket.Do() called, run='%s'", string(t.run))
 	t.myGoro.do(t)
@@ -295,7 +373,7 @@ func (goro *Goro) privateRun(run []byte, useEvalCoroutine bool) error {
 		//fmt.Printf("good: found __eval (0x%x). it is at -2 of the stack, our running code at -1. running '%s'\n", eval, s)
 		if verb.VerboseVerbose {
 			fmt.Printf("before vm.Call(1,0), stacks are:")
-			showLuaStacks(vm)
+			showLuaStacks(lvm)
 		}
 
 		vm.Call(1, 0)
@@ -305,7 +383,7 @@ func (goro *Goro) privateRun(run []byte, useEvalCoroutine bool) error {
 
 		if verb.VerboseVerbose {
 			fmt.Printf("\nafter vm.Call(1,0), stacks are:\n")
-			showLuaStacks(vm)
+			showLuaStacks(lvm)
 		}
 
 		return nil
@@ -0,0 +1,63 @@
+package compiler
+
+import (
+	"reflect"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1600InterpretedTypesCrossIntoGo(t *testing.T) {
+
+	cv.Convey(`structs, slices and maps of types declared at the prompt should reach binary packages as real Go values with all their fields, and Lua funcs should be callable as Go funcs`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		code := `
+import (
+	"fmt"
+	"strings"
+)
+type Point struct {
+	X, Y   int
+	Name   string ` + "`json:\"name\"`" + `
+	hidden int
+}
+ps := []Point{{1, 2, "a", 7}, {3, 4, "b", 8}}
+s := fmt.Sprintf("%+v", ps)
+m := fmt.Sprintf("%v", map[string]Point{"o": {}})
+comma := strings.IndexFunc("hello, world", func(r rune) bool { return r == ',' })
+found := comma == 5
+`
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustString(vm, "s", "[{X:1 Y:2 Name:a Hidden:7} {X:3 Y:4 Name:b Hidden:8}]")
+		LuaMustString(vm, "m", "map[o:{0 0  0}]")
+		LuaMustBool(vm, "found", true)
+
+		// reflect can't make unexported fields, so hidden is
+		// exported, with a lua tag to find it by.
+		LuaRunAndReport(vm, `pt = __gijitTypeToGoType(__type__.Point):String()`)
+		LuaMustString(vm, "pt", `struct { X int; Y int; Name string "json:\"name\""; Hidden int "json:\"-\" lua:\"hidden\"" }`)
+	})
+}
+
+func Test1601UnexportedFieldNamesAreExportedWholeRunes(t *testing.T) {
+
+	cv.Convey(`structOfForLua should upper-case a field's whole first rune, and prefix an X when that still doesn't export it`, t, func() {
+
+		props := []string{"émoji", "_x", "世界"}
+		typs := []reflect.Type{reflect.TypeOf(0), reflect.TypeOf(""), reflect.TypeOf(true)}
+		st := structOfForLua(props, typs, []string{"", "", ""}, []bool{false, false, false})
+
+		cv.So(st.Field(0).Name, cv.ShouldEqual, "Émoji")
+		cv.So(st.Field(1).Name, cv.ShouldEqual, "X_x")
+		cv.So(st.Field(2).Name, cv.ShouldEqual, "X世界")
+		cv.So(st.Field(2).Tag.Get("lua"), cv.ShouldEqual, "世界")
+	})
+}
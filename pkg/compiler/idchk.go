@@ -32,43 +32,37 @@ a continuously online REPL, it messes with the type checking
 that follows the syntax error.
 */
 
-type identVisitor struct {
-	av *assignVisitor
-}
-
 type assignVisitor struct {
-	iv    *identVisitor
 	bad   bool
 	which string
 }
 
-func newAssignVisitor() *assignVisitor {
-	av := &assignVisitor{
-		iv: &identVisitor{},
-	}
-	av.iv.av = av
-	return av
-}
-
 func (av *assignVisitor) Visit(node ast.Node) (w ast.Visitor) {
 	switch n := node.(type) {
 
 	case *ast.ValueSpec:
 		pp("Visit assignVisitor, ValueSpec, n = '%#v'", n)
 		for _, id := range n.Names {
-			if predeclared[id.Name] {
-				pp("Visit found bad '%s'", id.Name)
-				av.bad = true
-				av.which = id.Name
+			if av.check(id) {
 				return nil
 			}
 		}
 
 	case *ast.AssignStmt:
 		pp("Visit assignVisitor, AssignStmt, n = '%#v'", n)
-		walkExprList(av.iv, n.Lhs)
-		if av.bad {
-			return nil
+		// only the names assigned to; a type name in
+		// an index, as in g[[2]int{1, 2}] = true, is fine.
+		for _, x := range n.Lhs {
+			for {
+				p, ok := x.(*ast.ParenExpr)
+				if !ok {
+					break
+				}
+				x = p.X
+			}
+			if id, ok := x.(*ast.Ident); ok && av.check(id) {
+				return nil
+			}
 		}
 	default:
 		pp("Visit assignVisitor ignoring node '%#v'/'%T'", node, node)
@@ -76,28 +70,19 @@ func (av *assignVisitor) Visit(node ast.Node) (w ast.Visitor) {
 	return av
 }
 
-func walkExprList(v ast.Visitor, list []ast.Expr) {
-	for _, x := range list {
-		ast.Walk(v, x)
-	}
-}
-
-func (iv *identVisitor) Visit(node ast.Node) (w ast.Visitor) {
-	switch id := node.(type) {
-	case *ast.Ident:
-		pp("Visit identCheckVisitor, nm = '%v'", id.Name)
-		if predeclared[id.Name] {
-			iv.av.bad = true
-			iv.av.which = id.Name
-			return nil
-		}
+// check notes id, if it is a predeclared type name.
+func (av *assignVisitor) check(id *ast.Ident) bool {
+	if predeclared[id.Name] {
+		pp("Visit found bad '%s'", id.Name)
+		av.bad = true
+		av.which = id.Name
 	}
-	return iv
+	return av.bad
 }
 
 func checkAllowedIdents(file *ast.File) (hasBadId bool, whichBad string) {
 	pp("top of checkAllowedIdents")
-	v := newAssignVisitor()
+	v := &assignVisitor{}
 	for _, n := range file.Nodes {
 		ast.Walk(v, n)
 	}
@@ -191,7 +191,7 @@ func Test062SprintfOneSlice(t *testing.T) {
 		*/
 		LoadAndRunTestHelper(t, vm, translation)
 
-		LuaMustString(vm, "a", "yip []interface {}{4, 5, 6} eee\n")
+		LuaMustString(vm, "a", "yip []int{4, 5, 6} eee\n")
 	})
 }
 
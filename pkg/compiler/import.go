@@ -15,38 +15,44 @@ import (
 
 	golua "github.com/glycerine/golua/lua"
 
-	// shadow_ imports: available inside the REPL
+	"github.com/gijit/gi/pkg/compiler/shadow"
 
-	shadow_bytes "github.com/gijit/gi/pkg/compiler/shadow/bytes"
-	shadow_encoding_binary "github.com/gijit/gi/pkg/compiler/shadow/encoding/binary"
-	shadow_errors "github.com/gijit/gi/pkg/compiler/shadow/errors"
+	// shadow_ imports: available inside the REPL. Each
+	// registers itself with the shadow package from init().
+
+	_ "github.com/gijit/gi/pkg/compiler/shadow/bufio"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/bytes"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/encoding/binary"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/encoding/json"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/errors"
 	shadow_fmt "github.com/gijit/gi/pkg/compiler/shadow/fmt"
-	shadow_io "github.com/gijit/gi/pkg/compiler/shadow/io"
-	shadow_io_ioutil "github.com/gijit/gi/pkg/compiler/shadow/io/ioutil"
-	shadow_math "github.com/gijit/gi/pkg/compiler/shadow/math"
-	shadow_math_rand "github.com/gijit/gi/pkg/compiler/shadow/math/rand"
-	shadow_os "github.com/gijit/gi/pkg/compiler/shadow/os"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/io"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/io/ioutil"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/math"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/math/rand"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/os"
 	shadow_reflect "github.com/gijit/gi/pkg/compiler/shadow/reflect"
-	shadow_regexp "github.com/gijit/gi/pkg/compiler/shadow/regexp"
-	shadow_runtime "github.com/gijit/gi/pkg/compiler/shadow/runtime"
-	shadow_runtime_debug "github.com/gijit/gi/pkg/compiler/shadow/runtime/debug"
-	shadow_strconv "github.com/gijit/gi/pkg/compiler/shadow/strconv"
-	shadow_strings "github.com/gijit/gi/pkg/compiler/shadow/strings"
-	shadow_sync "github.com/gijit/gi/pkg/compiler/shadow/sync"
-	shadow_sync_atomic "github.com/gijit/gi/pkg/compiler/shadow/sync/atomic"
-	shadow_time "github.com/gijit/gi/pkg/compiler/shadow/time"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/regexp"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/runtime"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/runtime/debug"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/sort"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/strconv"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/strings"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/sync"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/sync/atomic"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/time"
 
 	// gonum
 	shadow_blas "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/blas"
-	shadow_fd "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/diff/fd"
-	shadow_floats "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/floats"
-	shadow_graph "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/graph"
-	shadow_integrate "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/integrate"
-	shadow_lapack "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/lapack"
-	shadow_mat "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/mat"
-	shadow_optimize "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/optimize"
-	shadow_stat "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/stat"
-	shadow_unit "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/unit"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/diff/fd"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/floats"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/graph"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/integrate"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/lapack"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/mat"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/optimize"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/stat"
+	_ "github.com/gijit/gi/pkg/compiler/shadow/gonum.org/v1/gonum/unit"
 
 	// actuals
 	"gonum.org/v1/gonum/blas"
@@ -64,6 +70,27 @@ import (
 // distinguish binary imports from source imports
 var binaryPackage = make(map[string]bool)
 
+// RegisterShadowPackage makes a binary Go package importable
+// at the REPL. The Pkg, Ctor and InitLua arguments are those
+// written by gen-gijit-shadow-import, whose output calls this
+// (via shadow.Register) from init(). ctor and initLua may be nil.
+func RegisterShadowPackage(path string, pkgMap, ctor map[string]interface{}, initLua func() string) {
+	shadow.Register(path, pkgMap, ctor, initLua)
+}
+
+// shadowLuaFixups holds Lua to run after a shadow package
+// is bound, for packages whose native functions must be
+// replaced by versions that cooperate with the
+// coroutine scheduler.
+var shadowLuaFixups = map[string]string{
+	// Sleep, After, NewTimer, NewTicker, etc.; see prelude/timer.lua.
+	"time": "\ntime = __timeShadow(time);\n",
+	// Unmarshal and Decoder.Decode; see prelude/json.lua.
+	"encoding/json": "\njson = __jsonShadow(json);\n",
+	// Mutex, RWMutex, WaitGroup, Once and Cond; see prelude/sync.lua.
+	"sync": "\nsync = __syncShadow(sync);\n",
+}
+
 func init() {
 	a := 1
 	b := interface{}(&a)
@@ -126,12 +153,13 @@ func (ic *IncrState) EnableImportsFromLua() {
 	}
 
 	stacksClosure := func() {
-		showLuaStacks(ic.goro.vm)
+		showLuaStacks(ic.goro.lvm)
 	}
 	luar.Register(ic.goro.vm, "", luar.Map{
 		"__go_run_import":     goRunImportFromLua,
 		"__go_compile_import": goCompileImportFromLua,
 		"__stacks":            stacksClosure,
+		"__gijitGoTrace":      ic.goro.lvm.chunkMaps.goTrace,
 	})
 
 	// Enable __zygo() calls. Type checking established
@@ -190,120 +218,24 @@ func (ic *IncrState) RunTimeGiImportFunc(path, pkgDir string, depth int) error {
 			return err
 		}
 
-	case "bytes":
-		t0.regmap["bytes"] = shadow_bytes.Pkg
-		t0.regmap["__ctor__bytes"] = shadow_bytes.Ctor
-		t0.run = append(t0.run, shadow_bytes.InitLua()...)
-
-	case "encoding/binary":
-		t0.regmap["binary"] = shadow_encoding_binary.Pkg
-		t0.regmap["__ctor__binary"] = shadow_encoding_binary.Ctor
-		t0.run = append(t0.run, shadow_encoding_binary.InitLua()...)
-
-	case "errors":
-		t0.regmap["errors"] = shadow_errors.Pkg
-		t0.regmap["__ctor__errors"] = shadow_errors.Ctor
-		t0.run = append(t0.run, shadow_errors.InitLua()...)
-
-	case "fmt":
-		pp("RunTimeGiImportFunc sees 'fmt', known and shadowed.")
-		t0.regmap["fmt"] = shadow_fmt.Pkg
-		t0.regmap["__ctor__fmt"] = shadow_fmt.Ctor
-		t0.run = append(t0.run, shadow_fmt.InitLua()...)
-	case "io":
-		t0.regmap["io"] = shadow_io.Pkg
-		t0.regmap["__ctor__io"] = shadow_io.Ctor
-		t0.run = append(t0.run, shadow_io.InitLua()...)
-	case "math":
-		t0.regmap["math"] = shadow_math.Pkg
-		t0.regmap["__ctor__math"] = shadow_math.Ctor
-		t0.run = append(t0.run, shadow_math.InitLua()...)
-	case "math/rand":
-		t0.regmap["rand"] = shadow_math_rand.Pkg
-		t0.regmap["__ctor__math_rand"] = shadow_math_rand.Ctor
-		t0.run = append(t0.run, shadow_math_rand.InitLua()...)
-	case "os":
-		t0.regmap["os"] = shadow_os.Pkg
-		t0.regmap["__ctor__os"] = shadow_os.Ctor
-		t0.run = append(t0.run, shadow_os.InitLua()...)
-
-	case "reflect":
-		t0.regmap["reflect"] = shadow_reflect.Pkg
-		t0.regmap["__ctor__reflect"] = shadow_reflect.Ctor
-		t0.run = append(t0.run, shadow_reflect.InitLua()...)
-
-	case "regexp":
-		t0.regmap["regexp"] = shadow_regexp.Pkg
-		t0.regmap["__ctor__regexp"] = shadow_regexp.Ctor
-		t0.run = append(t0.run, shadow_regexp.InitLua()...)
-
-	case "sync":
-		t0.regmap["sync"] = shadow_sync.Pkg
-		t0.regmap["__ctor__sync"] = shadow_sync.Ctor
-		t0.run = append(t0.run, shadow_sync.InitLua()...)
-
-	case "sync/atomic":
-		t0.regmap["atomic"] = shadow_sync_atomic.Pkg
-		t0.regmap["__ctor__atomic"] = shadow_sync_atomic.Ctor
-		t0.run = append(t0.run, shadow_sync_atomic.InitLua()...)
-
-	case "time":
-		t0.regmap["time"] = shadow_time.Pkg
-		t0.regmap["__ctor__time"] = shadow_time.Ctor
-		t0.run = append(t0.run, shadow_time.InitLua()...)
-
-	case "runtime":
-		t0.regmap["runtime"] = shadow_runtime.Pkg
-		t0.regmap["__ctor__runtime"] = shadow_runtime.Ctor
-		t0.run = append(t0.run, shadow_runtime.InitLua()...)
-
-	case "runtime/debug":
-		t0.regmap["debug"] = shadow_runtime_debug.Pkg
-		t0.regmap["__ctor__debug"] = shadow_runtime_debug.Ctor
-		t0.run = append(t0.run, shadow_runtime_debug.InitLua()...)
-
-	case "strconv":
-		t0.regmap["strconv"] = shadow_strconv.Pkg
-		t0.regmap["__ctor__strconv"] = shadow_strconv.Ctor
-		t0.run = append(t0.run, shadow_strconv.InitLua()...)
-
-	case "strings":
-		t0.regmap["strings"] = shadow_strings.Pkg
-		t0.regmap["__ctor__strings"] = shadow_strings.Ctor
-		t0.run = append(t0.run, shadow_strings.InitLua()...)
-
-	case "io/ioutil":
-		t0.regmap["ioutil"] = shadow_io_ioutil.Pkg
-		t0.regmap["__ctor__ioutil"] = shadow_io_ioutil.Ctor
-		t0.run = append(t0.run, shadow_io_ioutil.InitLua()...)
-
-		// gonum:
-	case "gonum.org/v1/gonum/blas":
-		t0.regmap["blas"] = shadow_blas.Pkg
-	case "gonum.org/v1/gonum/fd":
-		t0.regmap["fd"] = shadow_fd.Pkg
-	case "gonum.org/v1/gonum/floats":
-		t0.regmap["floats"] = shadow_floats.Pkg
-	case "gonum.org/v1/gonum/graph":
-		t0.regmap["graph"] = shadow_graph.Pkg
-	case "gonum.org/v1/gonum/integrate":
-		t0.regmap["integrate"] = shadow_integrate.Pkg
-	case "gonum.org/v1/gonum/lapack":
-		t0.regmap["lapack"] = shadow_lapack.Pkg
-	case "gonum.org/v1/gonum/mat":
-		t0.regmap["mat"] = shadow_mat.Pkg
-	case "gonum.org/v1/gonum/optimize":
-		t0.regmap["optimize"] = shadow_optimize.Pkg
-	case "gonum.org/v1/gonum/stat":
-		t0.regmap["stat"] = shadow_stat.Pkg
-	case "gonum.org/v1/gonum/unit":
-		t0.regmap["unit"] = shadow_unit.Pkg
-
 	default:
-		// source import
-		srcImport = true
-		// don't need to compile again, just call pkg.__init()
-		t0.run = []byte(fmt.Sprintf("%s.__init();", omitAnyShadowPathPrefix(path, true)))
+		sp, ok := shadow.Lookup(path)
+		if !ok {
+			// source import
+			srcImport = true
+			// don't need to compile again, just call pkg.__init()
+			t0.run = []byte(fmt.Sprintf("%s.__init();", omitAnyShadowPathPrefix(path, true)))
+			break
+		}
+		pp("RunTimeGiImportFunc sees '%s', known and shadowed.", path)
+		t0.regmap[sp.Name] = sp.Pkg
+		if sp.Ctor != nil {
+			t0.regmap["__ctor__"+sp.Name] = sp.Ctor
+		}
+		if sp.InitLua != nil {
+			t0.run = append(t0.run, sp.InitLua()...)
+		}
+		t0.run = append(t0.run, shadowLuaFixups[path]...)
 	}
 	if !srcImport {
 		binaryPackage[path] = true
@@ -341,41 +273,9 @@ func (ic *IncrState) CompileTimeGiImportFunc(path, pkgDir string, depth int) (*A
 
 	switch path {
 
-	// all these are shadowed, see below for the load of type checking info.
-
-	// gen-gijit-shadow outputs to pkg/compiler/shadow/...
-	case "bytes":
-	case "encoding/binary":
-	case "errors":
-	case "fmt":
-	case "io":
-	case "math":
-	case "math/rand":
-	case "os":
-	case "reflect":
-	case "regexp":
-	case "sync":
-	case "sync/atomic":
-	case "time":
-	case "runtime":
-	case "runtime/debug":
-	case "strconv":
-	case "strings":
-	case "io/ioutil":
-		// gonum:
-	case "gonum.org/v1/gonum/blas":
-	case "gonum.org/v1/gonum/fd":
-	case "gonum.org/v1/gonum/floats":
-	case "gonum.org/v1/gonum/graph":
-	case "gonum.org/v1/gonum/integrate":
-	case "gonum.org/v1/gonum/lapack":
-	case "gonum.org/v1/gonum/mat":
-	case "gonum.org/v1/gonum/optimize":
-	case "gonum.org/v1/gonum/stat":
-	case "gonum.org/v1/gonum/unit":
-
-		// we need to load the type-checking info into arch.Pkg
-		// now so that the compile can complete.
+	// anything registered with the shadow package is
+	// handled after the switch, see below for the load
+	// of type checking info.
 
 	case "kong":
 	case "gitesting":
@@ -416,6 +316,12 @@ func (ic *IncrState) CompileTimeGiImportFunc(path, pkgDir string, depth int) (*A
 		}
 
 	default:
+		if _, ok := shadow.Lookup(path); ok {
+			// we need to load the type-checking info into arch.Pkg
+			// now so that the compile can complete.
+			break
+		}
+
 		// try a source import?
 
 		p1("should we source import path='%s'? depth=%v", path, depth)
@@ -423,7 +329,7 @@ func (ic *IncrState) CompileTimeGiImportFunc(path, pkgDir string, depth int) (*A
 
 		if depth > 7 {
 			// not allowed
-			return nil, fmt.Errorf("deep source imports forbidden for performance reasons. problem with import of package '%s' (not shadowed? [1]) depth=%v ... [footnote 1] To shadow it, run gen-gijit-shadow-import on the package, add a case and import above, and recompile gijit.", path, depth)
+			return nil, fmt.Errorf("deep source imports forbidden for performance reasons. problem with import of package '%s' (not shadowed? [1]) depth=%v ... [footnote 1] To shadow it, run gen-gijit-shadow-import on the package, link the generated package into gi with a blank import, and recompile gijit.", path, depth)
 		}
 
 		archive, err := ic.ImportSourcePackage(path, pkgDir, depth+1)
@@ -461,7 +367,7 @@ func (ic *IncrState) CompileTimeGiImportFunc(path, pkgDir string, depth int) (*A
 		fmt.Printf("source import of package '%s' failed: '%v'", path, err)
 
 		// need to run gen-gijit-shadow-import
-		return nil, fmt.Errorf("error on import: problem with package '%s' (not shadowed? [1]): '%v'. ... [footnote 1] To shadow it, run gen-gijit-shadow-import on the package, add a case and import above, and recompile gijit.", path, err)
+		return nil, fmt.Errorf("error on import: problem with package '%s' (not shadowed? [1]): '%v'. ... [footnote 1] To shadow it, run gen-gijit-shadow-import on the package, link the generated package into gi with a blank import, and recompile gijit.", path, err)
 	}
 
 	// successfully match path to a shadow package, bring in its
@@ -726,48 +632,3 @@ func getFunFor__callZygo(pkg *types.Package) *types.Func {
 	fun := types.NewFunc(token.NoPos, pkg, "__zygo", sig)
 	return fun
 }
-
-/* time stuff
-
-var $setTimeout = function(f, t) {
-  $awakeGoroutines++;
-  return setTimeout(function() {
-    $awakeGoroutines--;
-    f();
-  }, t);
-};
-
-func Sleep(d Duration) {
-	c := make(chan struct{})
-	js.Global.Call("$setTimeout", js.InternalObject(func() { close(c) }), int(d/Millisecond))
-	<-c
-}
-
-
-func startTimer(t *runtimeTimer) {
-	t.active = true
-	diff := (t.when - runtimeNano()) / int64(Millisecond)
-	if diff > 1<<31-1 { // math.MaxInt32
-		return
-	}
-	if diff < 0 {
-		diff = 0
-	}
-	t.timeout = js.Global.Call("$setTimeout", js.InternalObject(func() {
-		t.active = false
-		if t.period != 0 {
-			t.when += t.period
-			startTimer(t)
-		}
-		go t.f(t.arg, 0)
-	}), diff+1)
-}
-
-func stopTimer(t *runtimeTimer) bool {
-	js.Global.Call("clearTimeout", t.timeout)
-	wasActive := t.active
-	t.active = false
-	return wasActive
-}
-
-*/
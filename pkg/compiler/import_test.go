@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"unicode"
 
 	//"github.com/gijit/gi/pkg/verb"
 	cv "github.com/glycerine/goconvey/convey"
@@ -248,3 +249,30 @@ b := Incr(1)
 		LuaMustInt64(vm, "c", 5)
 	})
 }
+
+func Test1007RegisteredShadowPackageIsImportable(t *testing.T) {
+
+	cv.Convey(`a binary package added with RegisterShadowPackage should be importable without any change to the compiler`, t, func() {
+
+		RegisterShadowPackage("unicode", map[string]interface{}{
+			"IsUpper": unicode.IsUpper,
+		}, nil, nil)
+
+		code := `
+import "unicode"
+up := unicode.IsUpper('G')
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		fmt.Printf("\n translation='%s'\n", translation)
+
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustBool(vm, "up", true)
+	})
+}
@@ -55,6 +55,7 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 
 	var newCodeText [][]byte
 	var funcSrcCache map[string]string
+	var graph *declGraph
 
 	var typesInfo *types.Info
 	if a == nil {
@@ -67,9 +68,11 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 			Scopes:     make(map[ast.Node]*types.Scope),
 		}
 		funcSrcCache = make(map[string]string)
+		graph = newDeclGraph()
 	} else {
 		typesInfo = a.TypesInfo
 		funcSrcCache = a.FuncSrcCache
+		graph = a.DeclGraph
 		//pp("typesInfo.Types = '%#v'", typesInfo.Types)
 	}
 
@@ -193,6 +196,8 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 			indentation:  1,
 			dependencies: make(map[types.Object]bool),
 			minify:       minify,
+			linePos:      importPath == "main",
+			preempt:      importContext.Preempt,
 			fileSet:      fileSet,
 			files:        files,
 		},
@@ -215,6 +220,7 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 	// imports
 	var importDecls []*Decl
 	var importedPaths []string
+	var imported []importedCode
 	for _, importedPkg := range pkg.Imports() {
 		if importedPkg == types.Unsafe {
 			// Prior to Go 1.9, unsafe import was excluded by Imports() method,
@@ -245,7 +251,10 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 
 				// Now the imported package has been merged with newCodeText,
 				// clear it so that is doesn't persist for every input hereafter.
-				arc.NewCodeText = [][]byte{}
+				// But only once we succeed; see txn.go.
+				if len(arc.NewCodeText) > 0 {
+					imported = append(imported, importedCode{arc: arc, code: arc.NewCodeText})
+				}
 			}
 
 		}
@@ -309,9 +318,21 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 	var funcDecls []*Decl
 	var mainFunc *types.Func
 
-	for _, file := range simplifiedFiles {
+	// record what this input declares, and what
+	// it used, for redefinition tracking in redef.go.
+	graph.startInput()
+	printOrig := func(node interface{}) string {
+		var by bytes.Buffer
+		err := printer.Fprint(&by, fileSet, node)
+		panicOn(err)
+		return by.String()
+	}
+
+	for fileIdx, file := range simplifiedFiles {
 		pp("file.Nodes has %v elements", len(file.Nodes))
-		for _, decl := range file.Nodes {
+		for nodeIdx, decl := range file.Nodes {
+			origNode := files[fileIdx].Nodes[nodeIdx]
+			newCodeText = append(newCodeText, c.posMarker(origNode.Pos()))
 
 			// fill out vars and functions
 
@@ -328,6 +349,7 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 				var by bytes.Buffer
 				err = printer.Fprint(&by, fileSet, d)
 				panicOn(err)
+				graph.recordFuncSrc(funcSrcCache, d.Name.Name)
 				funcSrcCache[d.Name.Name] = by.String()
 				pp("stored in c.p.funcSrcCache['%s'] the value '%s'", d.Name.Name, funcSrcCache[d.Name.Name])
 
@@ -397,6 +419,23 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 						de.DeclCode = c.translateToplevelFunction(fun, funcInfo)
 					})
 					funcDecls = append(funcDecls, &de)
+
+					td := &topDecl{
+						key:  o.FullName(),
+						name: o.Pkg().Path() + "." + o.Name(),
+						kind: "func",
+						src:  printOrig(origNode),
+						deps: de.DceDeps,
+					}
+					if fun.Recv != nil {
+						td.kind = "method"
+						td.name += "___tilde_"
+						// a method is lost when its receiver type is redefined.
+						if named, ok := recvType.(*types.Named); ok {
+							td.deps = append(td.deps, named.Obj().Pkg().Path()+"."+named.Obj().Name())
+						}
+					}
+					graph.define(td)
 					pp("place3, appending to newCodeText: de.DeclCode='%s'", string(de.DeclCode))
 					newCodeText = append(newCodeText, de.DeclCode)
 
@@ -426,7 +465,7 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 				switch d.Tok {
 				case token.TYPE:
 					pp("we're in the token.TYPE!")
-					for _, spec := range d.Specs {
+					for specIdx, spec := range d.Specs {
 						o := c.p.Defs[spec.(*ast.TypeSpec).Name].(*types.TypeName)
 						c.p.typeNames = append(c.p.typeNames, o)
 						c.objectName(o) // register toplevel name
@@ -438,6 +477,15 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 						newCodeText = append(newCodeText, by)
 						typeDecls = append(typeDecls, decl)
 						pp("named type codegen for '%s' generated: '%s'", o, string(by))
+
+						qual := o.Pkg().Path() + "." + o.Name()
+						graph.define(&topDecl{
+							key:  qual,
+							name: qual,
+							kind: "type",
+							src:  "type " + printOrig(origNode.(*ast.GenDecl).Specs[specIdx]),
+							deps: decl.DceDeps,
+						})
 					}
 				case token.VAR:
 					//vv("we're in the token.VAR")
@@ -489,6 +537,7 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 										pp("placeN+1, appending to newCodeText: d.InitCode='%s'", string(de.InitCode))
 										newCodeText = append(newCodeText, de.InitCode)
 									})
+									graph.defineVar(o, de.DceDeps)
 
 								} else {
 
@@ -544,6 +593,9 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 										}
 									}
 									varDecls = append(varDecls, &d)
+									for _, lhs := range init.Lhs {
+										graph.defineVar(lhs, d.DceDeps)
+									}
 									pp("place2, appending to newCodeText: d.InitCode='%s'", string(d.InitCode))
 									newCodeText = append(newCodeText, d.InitCode)
 
@@ -593,6 +645,9 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 					default:
 					}
 
+					// the prompt's print heuristics below look at the
+					// Lua text; its position was marked above.
+					c.output = removeLineMarkers(c.output)
 					n := len(c.output)
 					var ele string
 					if bytes.HasSuffix(c.output, []byte(";\n")) {
@@ -739,6 +794,9 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 	if len(c.p.errList) != 0 {
 		return nil, c.p.errList
 	}
+	for _, imp := range imported {
+		imp.arc.NewCodeText = [][]byte{}
+	}
 
 	if a == nil {
 		return &Archive{
@@ -757,12 +815,15 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 			Check:        check,
 			Pkg:          pkg,
 			FuncSrcCache: funcSrcCache,
+			DeclGraph:    graph,
+			ImportedCode: imported,
 		}, nil
 	} else {
 		a.Pkg = pkg
 		a.Check = check
 		a.NewCodeText = newCodeText
 		a.FuncSrcCache = funcSrcCache
+		a.ImportedCode = imported
 	}
 	return a, nil
 }
@@ -0,0 +1,222 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+var intConformanceTypes = []string{
+	"int8", "int16", "int32", "int64",
+	"uint8", "uint16", "uint32", "uint64",
+}
+
+var intConformanceOps = []string{
+	"+", "-", "*", "/", "%", "<<", ">>", "&", "|", "^", "&^",
+}
+
+// intConformanceVals are the operands for typ: the
+// small values, the shift counts either side of 64,
+// and typ's extremes. Each is held as the int64 with
+// the same bits; unsigned types keep just their width.
+func intConformanceVals(typ string) []int64 {
+	var w uint
+	fmt.Sscanf(typ[len(typ)-2:], "%d", &w)
+	if typ == "int8" || typ == "uint8" {
+		w = 8
+	}
+	if typ[0] == 'u' {
+		max := int64(-1)
+		if w < 64 {
+			max = 1<<w - 1
+		}
+		return []int64{0, 1, 3, 7, 63, 64, max - 1, max}
+	}
+	min := int64(-1) << (w - 1)
+	max := -(min + 1)
+	return []int64{0, 1, -1, 3, -7, 63, 64, min, min + 1, max}
+}
+
+// goIntOp is the compiled-Go answer for x op y in typ,
+// with the result's bits in an int64.
+func goIntOp(typ, op string, x, y int64) int64 {
+	var r [11]int64
+	switch typ {
+	case "int8":
+		a, b := int8(x), int8(y)
+		var q, m int8
+		if b != 0 {
+			q, m = a/b, a%b
+		}
+		r = [11]int64{int64(a + b), int64(a - b), int64(a * b), int64(q), int64(m), int64(a << uint(b)), int64(a >> uint(b)), int64(a & b), int64(a | b), int64(a ^ b), int64(a &^ b)}
+	case "int16":
+		a, b := int16(x), int16(y)
+		var q, m int16
+		if b != 0 {
+			q, m = a/b, a%b
+		}
+		r = [11]int64{int64(a + b), int64(a - b), int64(a * b), int64(q), int64(m), int64(a << uint(b)), int64(a >> uint(b)), int64(a & b), int64(a | b), int64(a ^ b), int64(a &^ b)}
+	case "int32":
+		a, b := int32(x), int32(y)
+		var q, m int32
+		if b != 0 {
+			q, m = a/b, a%b
+		}
+		r = [11]int64{int64(a + b), int64(a - b), int64(a * b), int64(q), int64(m), int64(a << uint(b)), int64(a >> uint(b)), int64(a & b), int64(a | b), int64(a ^ b), int64(a &^ b)}
+	case "int64":
+		a, b := x, y
+		var q, m int64
+		if b != 0 {
+			q, m = a/b, a%b
+		}
+		r = [11]int64{a + b, a - b, a * b, q, m, a << uint(b), a >> uint(b), a & b, a | b, a ^ b, a &^ b}
+	case "uint8":
+		a, b := uint8(x), uint8(y)
+		var q, m uint8
+		if b != 0 {
+			q, m = a/b, a%b
+		}
+		r = [11]int64{int64(a + b), int64(a - b), int64(a * b), int64(q), int64(m), int64(a << uint(b)), int64(a >> uint(b)), int64(a & b), int64(a | b), int64(a ^ b), int64(a &^ b)}
+	case "uint16":
+		a, b := uint16(x), uint16(y)
+		var q, m uint16
+		if b != 0 {
+			q, m = a/b, a%b
+		}
+		r = [11]int64{int64(a + b), int64(a - b), int64(a * b), int64(q), int64(m), int64(a << uint(b)), int64(a >> uint(b)), int64(a & b), int64(a | b), int64(a ^ b), int64(a &^ b)}
+	case "uint32":
+		a, b := uint32(x), uint32(y)
+		var q, m uint32
+		if b != 0 {
+			q, m = a/b, a%b
+		}
+		r = [11]int64{int64(a + b), int64(a - b), int64(a * b), int64(q), int64(m), int64(a << uint(b)), int64(a >> uint(b)), int64(a & b), int64(a | b), int64(a ^ b), int64(a &^ b)}
+	case "uint64":
+		a, b := uint64(x), uint64(y)
+		var q, m uint64
+		if b != 0 {
+			q, m = a/b, a%b
+		}
+		r = [11]int64{int64(a + b), int64(a - b), int64(a * b), int64(q), int64(m), int64(a << b), int64(a >> b), int64(a & b), int64(a | b), int64(a ^ b), int64(a &^ b)}
+	}
+	for i, o := range intConformanceOps {
+		if o == op {
+			return r[i]
+		}
+	}
+	panic("unknown op " + op)
+}
+
+// goIntLit is x as a constant of typ.
+func goIntLit(typ string, x int64) string {
+	if typ[0] == 'u' {
+		return fmt.Sprintf("%d", uint64(x))
+	}
+	return fmt.Sprintf("%d", x)
+}
+
+func Test1400IntegerOpsMatchCompiledGo(t *testing.T) {
+
+	cv.Convey(`every binary integer operator, on every integer width, should give the same result as compiled Go: wraparound, shifts by the width or more, and MinInt / -1 included`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		for _, typ := range intConformanceTypes {
+			vals := intConformanceVals(typ)
+
+			var src bytes.Buffer
+			for i, x := range vals {
+				fmt.Fprintf(&src, "var %s_x%d %s = %s\n", typ, i, typ, goIntLit(typ, x))
+			}
+			type check struct {
+				name   string
+				expr   string
+				expect int64
+			}
+			var checks []check
+			for _, op := range intConformanceOps {
+				for i, x := range vals {
+					for j, y := range vals {
+						if y == 0 && (op == "/" || op == "%") {
+							continue
+						}
+						a := fmt.Sprintf("%s_x%d", typ, i)
+						b := fmt.Sprintf("%s_x%d", typ, j)
+						if op == "<<" || op == ">>" {
+							b = "uint(" + b + ")"
+						}
+						ck := check{
+							name:   fmt.Sprintf("%s_r%d", typ, len(checks)),
+							expr:   a + " " + op + " " + b,
+							expect: goIntOp(typ, op, x, y),
+						}
+						fmt.Fprintf(&src, "%s := %s\n", ck.name, ck.expr)
+						checks = append(checks, ck)
+					}
+				}
+			}
+
+			translation, err := inc.Tr(src.Bytes())
+			panicOn(err)
+			LuaRunAndReport(vm, string(translation))
+
+			for _, ck := range checks {
+				cv.So(fmt.Sprintf("%s: %s", ck.expr, luaInt64Bits(vm, ck.name)), cv.ShouldEqual,
+					fmt.Sprintf("%s: %d", ck.expr, ck.expect))
+			}
+		}
+	})
+}
+
+func Test1401ShiftCountsAndSignedness(t *testing.T) {
+
+	cv.Convey(`a conversion that changes signedness should keep the bits, a huge unsigned shift count should shift every bit out, and a negative signed count should panic`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation, err := inc.Tr([]byte(`
+var m int8 = -128
+var k int = -1
+var u uint64 = 1 << 63
+a := int8(1) << uint(m)
+b := uint64(m)
+c := uint8(k)
+d := int64(u) >> uint(k)
+`))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+		LuaMustInt64(vm, "a", 0)
+		cv.So(luaInt64Bits(vm, "b"), cv.ShouldEqual, "-128")
+		LuaMustInt64(vm, "c", 255)
+		LuaMustInt64(vm, "d", -1)
+
+		translation, err = inc.Tr([]byte(`e := 1 << k`))
+		panicOn(err)
+		err = LuaRun(vm, string(translation), true)
+		if err == nil {
+			err = lastEvalError(vm)
+		}
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "negative shift amount")
+	})
+}
+
+// luaInt64Bits returns the bits of the integer cdata
+// in global varname, as an int64, in decimal.
+func luaInt64Bits(lvm *LuaVm, varname string) string {
+	vm := lvm.vm
+	vm.GetGlobal(varname)
+	defer vm.Pop(1)
+	if vm.IsNil(-1) {
+		return "nil"
+	}
+	return fmt.Sprintf("%d", vm.CdataToInt64(-1))
+}
@@ -0,0 +1,227 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Interpreter embeds gijit in a host Go program. It
+// owns one LuaJIT vm and one incremental type-checker
+// and translator, so definitions made by one Eval are
+// visible to the next, just as at the `gi>` prompt.
+//
+// An Interpreter is safe for use by multiple goroutines;
+// calls are serialized.
+type Interpreter struct {
+	cfg *GIConfig
+	lvm *LuaVm
+	inc *IncrState
+
+	mut    sync.Mutex
+	closed bool
+}
+
+// NewInterpreter starts a new LuaJIT vm with the prelude
+// loaded. cfg may be nil for the defaults; the statically
+// embedded prelude is used unless cfg.Dev is set.
+func NewInterpreter(cfg *GIConfig) (*Interpreter, error) {
+	if cfg == nil {
+		cfg = NewGIConfig()
+		cfg.Quiet = true
+	}
+	err := cfg.ValidateConfig()
+	if err != nil {
+		return nil, err
+	}
+	lvm, err := NewLuaVmWithPrelude(cfg)
+	if err != nil {
+		return nil, err
+	}
+	inc := NewIncrState(lvm, cfg)
+
+	// we fetch the value of expressions
+	// ourselves; don't print them.
+	inc.NoAnsPrint = true
+
+	return &Interpreter{
+		cfg: cfg,
+		lvm: lvm,
+		inc: inc,
+	}, nil
+}
+
+var ErrInterpreterClosed = fmt.Errorf("gijit Interpreter is closed")
+
+// Eval type-checks, translates and runs the Go statements
+// or expression in src. If src is a single expression, its
+// value(s) are returned.
+func (in *Interpreter) Eval(src string) ([]interface{}, error) {
+	in.mut.Lock()
+	defer in.mut.Unlock()
+	if in.closed {
+		return nil, ErrInterpreterClosed
+	}
+	ans, _, err := in.eval(src)
+	return ans, err
+}
+
+// eval is Eval, without the lock. ran reports whether
+// src translated, and so whether an err came from running it.
+func (in *Interpreter) eval(src string) (ans []interface{}, ran bool, err error) {
+	// so we can tell if this src set it.
+	err = LuaRun(in.lvm, "__gijit_ans = nil;", false)
+	if err != nil {
+		return nil, false, err
+	}
+
+	translation, err := TranslateAndCatchPanic(in.inc, []byte(src))
+	if err != nil {
+		return nil, false, err
+	}
+	in.inc.beginRun(in.lvm)
+	err = LuaRun(in.lvm, in.inc.tagChunk(translation), true)
+	if err == nil {
+		err = lastEvalError(in.lvm)
+	}
+	in.inc.endRun(in.lvm, err != nil)
+	if err != nil {
+		return nil, true, err
+	}
+
+	t := in.lvm.goro.newTicket("", false)
+	t.varname["__gijit_ans"] = nil
+	t.gettyp = GetInterfaceSlice
+	err = t.Do()
+	if _, ok := err.(globalNotFound); ok {
+		// not an expression; nothing to return.
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("fetching the value of '%s': %v", src, err)
+	}
+	ans, _ = t.varname["__gijit_ans"].([]interface{})
+	return ans, true, nil
+}
+
+// Set declares a package-level variable name in the
+// interpreter and assigns it v. v must be a bool,
+// a number, a string, or a func that mentions no
+// package-qualified types: func(a, b int) int will do,
+// func(d time.Duration) will not.
+func (in *Interpreter) Set(name string, v interface{}) error {
+	in.mut.Lock()
+	defer in.mut.Unlock()
+	if in.closed {
+		return ErrInterpreterClosed
+	}
+
+	typ, err := declarableGoType(v)
+	if err != nil {
+		return fmt.Errorf("Interpreter.Set('%s'): %v", name, err)
+	}
+
+	// tell the type checker first...
+	decl := fmt.Sprintf("var %s %s", name, typ)
+	translation, err := in.inc.TrWithPrepend([]byte(decl), false)
+	if err != nil {
+		return err
+	}
+	err = LuaRun(in.lvm, string(translation), true)
+	if err != nil {
+		return err
+	}
+
+	// ...then replace the zero value with v.
+	t := in.lvm.goro.newTicket("", false)
+	t.regmap[name] = v
+	return t.Do()
+}
+
+// Get returns the value of the global variable name,
+// converted to Go.
+func (in *Interpreter) Get(name string) (interface{}, error) {
+	in.mut.Lock()
+	defer in.mut.Unlock()
+	if in.closed {
+		return nil, ErrInterpreterClosed
+	}
+
+	t := in.lvm.goro.newTicket("", false)
+	t.varname[name] = nil
+	t.gettyp = GetInterface
+	err := t.Do()
+	if err != nil {
+		return nil, err
+	}
+	return t.varname[name], nil
+}
+
+// Close shuts down the LuaJIT vm. The
+// Interpreter cannot be used afterwards.
+func (in *Interpreter) Close() {
+	in.mut.Lock()
+	defer in.mut.Unlock()
+	if in.closed {
+		return
+	}
+	in.closed = true
+	in.lvm.Close()
+}
+
+// lastEvalError returns the runtime error, if any, from
+// the last LuaRun on the eval coroutine. Such errors are
+// caught in __gijitMainEval and left in __lastEvalErr.
+func lastEvalError(lvm *LuaVm) error {
+	t := lvm.goro.newTicket("", false)
+	t.varname["__lastEvalErr"] = nil
+	t.gettyp = GetString
+	err := t.Do()
+	if err != nil {
+		return err
+	}
+	if lastErr, _ := t.varname["__lastEvalErr"].(string); lastErr != "" {
+		switch lastErr {
+		case ErrDeadlock.Error():
+			return ErrDeadlock
+		case ErrInterrupted.Error():
+			return ErrInterrupted
+		}
+		return fmt.Errorf("%s", lastErr)
+	}
+	return nil
+}
+
+// ErrDeadlock is the error from an eval that blocked
+// with every goroutine asleep. The scheduler in chan.lua
+// abandons such an eval, so the vm can go on.
+var ErrDeadlock = fmt.Errorf("fatal error: all goroutines are asleep - deadlock!")
+
+// declarableGoType returns the Go source for the type
+// of v, if it is one that Set can declare.
+func declarableGoType(v interface{}) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("cannot Set an untyped nil")
+	}
+	rt := reflect.TypeOf(v)
+	switch rt.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		if rt.PkgPath() != "" {
+			// luar would proxy it rather than push a number.
+			return "", fmt.Errorf("named type %T; convert to %s first", v, rt.Kind())
+		}
+		return rt.Kind().String(), nil
+	case reflect.Func:
+		s := rt.String()
+		if strings.Contains(s, ".") {
+			return "", fmt.Errorf("func type '%s' mentions a named type", s)
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("unsupported type %T", v)
+}
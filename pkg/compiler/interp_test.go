@@ -0,0 +1,46 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1100InterpreterEvalSetGet(t *testing.T) {
+
+	cv.Convey(`the embeddable Interpreter should Eval Go, return expression values, and Set/Get globals`, t, func() {
+
+		in, err := NewInterpreter(nil)
+		panicOn(err)
+		defer in.Close()
+
+		res, err := in.Eval(`a := 6`)
+		panicOn(err)
+		cv.So(res, cv.ShouldBeNil)
+
+		err = in.Set("b", 7)
+		panicOn(err)
+
+		res, err = in.Eval(`a * b`)
+		panicOn(err)
+		cv.So(res, cv.ShouldResemble, []interface{}{int64(42)})
+
+		err = in.Set("greet", func(s string) string { return "hello " + s })
+		panicOn(err)
+		_, err = in.Eval(`g := greet("gi")`)
+		panicOn(err)
+
+		g, err := in.Get("g")
+		panicOn(err)
+		cv.So(g, cv.ShouldEqual, "hello gi")
+
+		// type errors come back as errors, and
+		// leave the interpreter usable.
+		_, err = in.Eval(`a = "not an int"`)
+		cv.So(err, cv.ShouldNotBeNil)
+
+		a, err := in.Get("a")
+		panicOn(err)
+		cv.So(a, cv.ShouldEqual, int64(6))
+	})
+}
@@ -0,0 +1,93 @@
+package compiler
+
+// Ctrl-C and :timeout stop the running eval without
+// killing the REPL. While an eval runs, SIGINT, or the
+// timer for the :timeout limit, sets a LuaJIT count hook
+// (golua's SetInterrupt) that calls __gijitInterrupt in
+// prelude/chan.lua at the next Lua instruction. That
+// panics with "interrupted", so the eval's defers run as
+// it unwinds, and the prompt comes back with every
+// earlier definition intact.
+//
+// LuaJIT doesn't run hooks inside compiled traces, so a
+// loop the JIT has compiled notices the interrupt only
+// once it leaves its trace. Every loop leaves it now and
+// then, at its back-edge call of __backEdge (or, under
+// -preempt, __preempt); see printBackEdge.
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// ErrInterrupted is the error from an eval stopped by
+// Ctrl-C or by the :timeout limit.
+var ErrInterrupted = fmt.Errorf("panic: interrupted")
+
+// Interrupt stops the eval running on lvm, if any, at
+// its next Lua instruction. It may be called from any
+// goroutine.
+func (lvm *LuaVm) Interrupt() {
+	lvm.vm.SetInterrupt()
+}
+
+// interruptible lets Ctrl-C interrupt lvm, as does the
+// passing of timeout if it is positive, until the returned
+// done is called. done reports whether the timeout fired.
+func (lvm *LuaVm) interruptible(timeout time.Duration) (done func() (timedOut bool)) {
+	var mu sync.Mutex
+	armed := true
+	fired := false
+	fire := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if !armed {
+			return false
+		}
+		lvm.Interrupt()
+		return true
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	quit := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				fire()
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	var timer *time.Timer
+	if timeout > 0 {
+		timer = time.AfterFunc(timeout, func() {
+			if fire() {
+				mu.Lock()
+				fired = true
+				mu.Unlock()
+			}
+		})
+	}
+
+	return func() bool {
+		signal.Stop(sigs)
+		close(quit)
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		armed = false
+		// an interrupt that came after the eval finished
+		// must not stop the next one.
+		lvm.vm.ClearInterrupt()
+		return fired
+	}
+}
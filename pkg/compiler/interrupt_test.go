@@ -0,0 +1,70 @@
+package compiler
+
+import (
+	"testing"
+	"time"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test2200InterruptRunningEval(t *testing.T) {
+
+	cv.Convey(`an interrupt, as from ctrl-c or :timeout, should stop a spinning eval with a panic that runs its defers, and leave earlier definitions intact`, t, func() {
+
+		// without -preempt: compiled loops must still stop.
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		var timedOut bool
+		run := func(src string, timeout time.Duration) error {
+			// as the prompt does, so that spin(), which has
+			// no value, is taken as a statement.
+			translation, err := TranslateAndCatchPanic(inc, []byte(src))
+			panicOn(err)
+			done := vm.interruptible(timeout)
+			LuaRunAndReport(vm, inc.tagChunk(string(translation)))
+			timedOut = done()
+			return lastEvalError(vm)
+		}
+
+		err = run(`
+deferRan := false
+n := 0
+func spin() {
+	defer func() { deferRan = true }()
+	for {
+		n++
+	}
+}
+`, 0)
+		cv.So(err, cv.ShouldBeNil)
+
+		err = run(`spin()`, 50*time.Millisecond)
+		cv.So(err, cv.ShouldEqual, ErrInterrupted)
+		cv.So(timedOut, cv.ShouldBeTrue)
+		LuaMustBool(vm, "deferRan", true)
+
+		err = run(`spun := n > 0`, 50*time.Millisecond)
+		cv.So(err, cv.ShouldBeNil)
+		cv.So(timedOut, cv.ShouldBeFalse)
+		LuaMustBool(vm, "spun", true)
+
+		// an eval blocked while a goroutine spins.
+		err = run(`
+ch := make(chan int)
+go func() {
+	for {
+		n++
+	}
+}()
+<-ch
+`, 50*time.Millisecond)
+		cv.So(err, cv.ShouldEqual, ErrInterrupted)
+
+		err = run(`x := 3`, 0)
+		cv.So(err, cv.ShouldBeNil)
+		LuaMustInt64(vm, "x", 3)
+	})
+}
@@ -0,0 +1,124 @@
+package compiler
+
+import (
+	"encoding/json"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+// jsonInner and jsonRec match Inner and Rec in
+// Test1700, compiled, to get the bytes Go writes.
+type jsonInner struct {
+	A int      `json:"a"`
+	B []string `json:"b,omitempty"`
+}
+
+type jsonRec struct {
+	Name  string         `json:"name"`
+	Count int            `json:"count,omitempty"`
+	Score float64        `json:"score"`
+	Tags  []string       `json:"tags"`
+	Attrs map[string]int `json:"attrs,omitempty"`
+	In    jsonInner      `json:"in"`
+	Skip  string         `json:"-"`
+	Plain bool
+	note  string
+}
+
+func Test1700JsonOfInterpretedTypes(t *testing.T) {
+
+	cv.Convey(`encoding/json should Marshal, Unmarshal and Decode types declared at the prompt, honoring struct tags, and Marshal should give the same bytes as compiled Go`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		code := `
+import (
+	"encoding/json"
+	"strings"
+)
+type Inner struct {
+	A int      ` + "`json:\"a\"`" + `
+	B []string ` + "`json:\"b,omitempty\"`" + `
+}
+type Rec struct {
+	Name  string         ` + "`json:\"name\"`" + `
+	Count int            ` + "`json:\"count,omitempty\"`" + `
+	Score float64        ` + "`json:\"score\"`" + `
+	Tags  []string       ` + "`json:\"tags\"`" + `
+	Attrs map[string]int ` + "`json:\"attrs,omitempty\"`" + `
+	In    Inner          ` + "`json:\"in\"`" + `
+	Skip  string         ` + "`json:\"-\"`" + `
+	Plain bool
+	note  string
+}
+r := Rec{Name: "x", Score: 1.5, Tags: []string{"a", "b"}, Attrs: map[string]int{"k": 1, "j": 2}, In: Inner{A: 3}, Skip: "no", Plain: true, note: "hi"}
+b, err := json.Marshal(r)
+out := string(b)
+marshalOk := err == nil
+ind, _ := json.MarshalIndent(&r, "", "  ")
+outIndent := string(ind)
+
+var back Rec
+err2 := json.Unmarshal(b, &back)
+unmarshalOk := err2 == nil
+b2, _ := json.Marshal(back)
+roundTrip := string(b2)
+backJ := back.Attrs["j"]
+backTag := back.Tags[1]
+
+dec := json.NewDecoder(strings.NewReader(` + "`" + `{"a":7,"b":["p","q"]} {"a":8}` + "`" + `))
+var in1, in2 Inner
+dec.Decode(&in1)
+dec.Decode(&in2)
+d1 := in1.A
+d1b := in1.B[1]
+d2 := in2.A
+d2len := len(in2.B)
+
+var n int
+json.Unmarshal([]byte("42"), &n)
+
+var ns []int
+json.Unmarshal([]byte("[1,2,3]"), &ns)
+nsLen := len(ns)
+ns2 := ns[2]
+
+var m map[string]interface{}
+json.Unmarshal([]byte(` + "`" + `{"x":1.5,"y":"s"}` + "`" + `), &m)
+my := m["y"].(string)
+`
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+
+		r := jsonRec{Name: "x", Score: 1.5, Tags: []string{"a", "b"}, Attrs: map[string]int{"k": 1, "j": 2}, In: jsonInner{A: 3}, Skip: "no", Plain: true, note: "hi"}
+		b, err := json.Marshal(r)
+		panicOn(err)
+		ind, err := json.MarshalIndent(&r, "", "  ")
+		panicOn(err)
+
+		LuaMustBool(vm, "marshalOk", true)
+		LuaMustString(vm, "out", string(b))
+		LuaMustString(vm, "outIndent", string(ind))
+
+		// Skip and note don't survive the trip, as in Go.
+		LuaMustBool(vm, "unmarshalOk", true)
+		LuaMustString(vm, "roundTrip", string(b))
+		LuaMustInt64(vm, "backJ", 2)
+		LuaMustString(vm, "backTag", "b")
+
+		LuaMustInt64(vm, "d1", 7)
+		LuaMustString(vm, "d1b", "q")
+		LuaMustInt64(vm, "d2", 8)
+		LuaMustInt(vm, "d2len", 0)
+
+		LuaMustInt64(vm, "n", 42)
+		LuaMustInt(vm, "nsLen", 3)
+		LuaMustInt64(vm, "ns2", 3)
+		LuaMustString(vm, "my", "s")
+	})
+}
@@ -0,0 +1,250 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+// kernelTestClient plays the Jupyter front end.
+type kernelTestClient struct {
+	signer *Kernel // for encode/decode/sign only
+	shell  *zmtpConn
+	iopub  *zmtpConn
+	hb     *zmtpConn
+}
+
+func (c *kernelTestClient) request(typ string, content interface{}) (req, reply *kernelMsg, err error) {
+	frames, err := c.signer.encode(nil, nil, typ, content)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err = c.signer.decode(frames)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = c.shell.WriteMsg(frames)
+	if err != nil {
+		return nil, nil, err
+	}
+	got, err := c.shell.ReadMsg()
+	if err != nil {
+		return nil, nil, err
+	}
+	reply, err = c.signer.decode(got)
+	return
+}
+
+// iopubUntilIdle collects the iopub messages that
+// have parent as their parent, until status idle.
+func (c *kernelTestClient) iopubUntilIdle(parent *kernelMsg) (msgs []*kernelMsg, err error) {
+	c.iopub.nc.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		frames, err := c.iopub.ReadMsg()
+		if err != nil {
+			return msgs, err
+		}
+		msg, err := c.signer.decode(frames)
+		if err != nil {
+			return msgs, err
+		}
+		var ph kernelHeader
+		json.Unmarshal(frames[len(frames)-3], &ph)
+		if ph.MsgID != parent.header.MsgID {
+			continue
+		}
+		msgs = append(msgs, msg)
+		if msg.header.MsgType == "status" && strings.Contains(string(msg.content), "idle") {
+			return msgs, nil
+		}
+	}
+}
+
+func freeLocalPort() int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	panicOn(err)
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+type kernelTestResults struct {
+	hbEcho    string
+	info      string
+	execReply string
+	execIOPub []*kernelMsg
+	errReply  string
+	compReply string
+	shutReply string
+	clientErr error
+}
+
+func Test1200JupyterKernelExecuteRequest(t *testing.T) {
+
+	cv.Convey(`gi -kernel should answer kernel_info, execute_request, complete_request and shutdown_request from a scripted Jupyter client`, t, func() {
+
+		dir, err := ioutil.TempDir("", "gijit-kernel-test")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+
+		// keep the test cells out of the user's ~/.gijit.hist
+		oldHome := os.Getenv("HOME")
+		os.Setenv("HOME", dir)
+		defer os.Setenv("HOME", oldHome)
+
+		conn := KernelConnInfo{
+			Transport:       "tcp",
+			IP:              "127.0.0.1",
+			ShellPort:       freeLocalPort(),
+			IOPubPort:       freeLocalPort(),
+			StdinPort:       freeLocalPort(),
+			ControlPort:     freeLocalPort(),
+			HBPort:          freeLocalPort(),
+			SignatureScheme: "hmac-sha256",
+			Key:             "not-so-secret",
+		}
+		by, err := json.Marshal(&conn)
+		panicOn(err)
+		connFile := filepath.Join(dir, "kernel.json")
+		panicOn(ioutil.WriteFile(connFile, by, 0600))
+
+		k, err := NewKernel(NewGIConfig(), connFile)
+		panicOn(err)
+		defer k.Close()
+
+		res := &kernelTestResults{}
+		clientDone := make(chan bool)
+		go func() {
+			defer close(clientDone)
+			res.clientErr = runKernelTestClient(conn, res)
+			if res.clientErr != nil {
+				// don't leave Serve waiting forever.
+				shutdownKernelForTest(conn)
+			}
+		}()
+
+		// LuaJIT stays on this goroutine.
+		k.Serve()
+		<-clientDone
+
+		cv.So(res.clientErr, cv.ShouldBeNil)
+		cv.So(res.hbEcho, cv.ShouldEqual, "ping")
+		cv.So(res.info, cv.ShouldContainSubstring, `"implementation":"gijit"`)
+		cv.So(res.execReply, cv.ShouldContainSubstring, `"status":"ok"`)
+
+		var stream, display string
+		for _, m := range res.execIOPub {
+			switch m.header.MsgType {
+			case "stream":
+				stream += string(m.content)
+			case "display_data":
+				display += string(m.content)
+			}
+		}
+		cv.So(stream, cv.ShouldContainSubstring, "42")
+		cv.So(display, cv.ShouldContainSubstring, "elapsed")
+
+		cv.So(res.errReply, cv.ShouldContainSubstring, `"status":"error"`)
+		cv.So(res.compReply, cv.ShouldContainSubstring, `"a"`)
+		cv.So(res.compReply, cv.ShouldContainSubstring, `"cursor_start":5`)
+		cv.So(res.shutReply, cv.ShouldContainSubstring, `"status":"ok"`)
+	})
+}
+
+func runKernelTestClient(conn KernelConnInfo, res *kernelTestResults) (err error) {
+	addr := func(port int) string {
+		return fmt.Sprintf("%s:%d", conn.IP, port)
+	}
+	c := &kernelTestClient{
+		signer: &Kernel{key: []byte(conn.Key), session: "test-client"},
+	}
+	c.hb, err = zmtpDial(addr(conn.HBPort), "REQ")
+	if err != nil {
+		return err
+	}
+	defer c.hb.Close()
+	c.iopub, err = zmtpDial(addr(conn.IOPubPort), "SUB")
+	if err != nil {
+		return err
+	}
+	defer c.iopub.Close()
+	c.shell, err = zmtpDial(addr(conn.ShellPort), "DEALER")
+	if err != nil {
+		return err
+	}
+	defer c.shell.Close()
+
+	err = c.hb.WriteMsg([][]byte{[]byte("ping")})
+	if err != nil {
+		return err
+	}
+	echo, err := c.hb.ReadMsg()
+	if err != nil {
+		return err
+	}
+	res.hbEcho = string(echo[0])
+
+	_, reply, err := c.request("kernel_info_request", map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	res.info = string(reply.content)
+
+	req, reply, err := c.request("execute_request", map[string]interface{}{
+		"code":   "a := 6 * 7\nprintln(a)",
+		"silent": false,
+	})
+	if err != nil {
+		return err
+	}
+	res.execReply = string(reply.content)
+	res.execIOPub, err = c.iopubUntilIdle(req)
+	if err != nil {
+		return err
+	}
+
+	_, reply, err = c.request("execute_request", map[string]interface{}{
+		"code": "b := a + notDefinedAnywhere",
+	})
+	if err != nil {
+		return err
+	}
+	res.errReply = string(reply.content)
+
+	// a was declared by the first cell.
+	_, reply, err = c.request("complete_request", map[string]interface{}{
+		"code":       "b := a",
+		"cursor_pos": 6,
+	})
+	if err != nil {
+		return err
+	}
+	res.compReply = string(reply.content)
+
+	_, reply, err = c.request("shutdown_request", map[string]interface{}{"restart": false})
+	if err != nil {
+		return err
+	}
+	res.shutReply = string(reply.content)
+	return nil
+}
+
+func shutdownKernelForTest(conn KernelConnInfo) {
+	shell, err := zmtpDial(fmt.Sprintf("%s:%d", conn.IP, conn.ShellPort), "DEALER")
+	if err != nil {
+		return
+	}
+	defer shell.Close()
+	c := &kernelTestClient{
+		signer: &Kernel{key: []byte(conn.Key), session: "test-client"},
+		shell:  shell,
+	}
+	c.request("shutdown_request", map[string]interface{}{"restart": false})
+}
@@ -0,0 +1,268 @@
+package compiler
+
+// linemap.go: mapping the Lua we run back to the Go it
+// came from, so that runtime errors and :stacks show
+// Go files, lines and function names.
+//
+// While translating at the prompt, writePos puts a position
+// mark, '\b' and four bytes of token.Pos as for gopherjs
+// source maps, in front of each statement's Lua. encodeString
+// escapes a '\b', so a mark is never part of a Lua string.
+// TrWithPrepend writes its output through a SourceMapFilter,
+// which drops the marks, and keeps, for each line of the
+// output, the Go position it came from.
+// Before running the Lua, the Repl and Interpreter tag it
+// with a first line of --[[gi:N]], which becomes part of
+// the chunk name LuaJIT uses in errors and tracebacks:
+// [string "--[[gi:N]]..."]:line. goTrace finds those and
+// rewrites them using the line map of chunk N.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/token"
+)
+
+// posMarker is the position mark that writePos emits.
+func (c *funcContext) posMarker(pos token.Pos) []byte {
+	if !c.p.linePos || !pos.IsValid() {
+		return nil
+	}
+	b := []byte{'\b', 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(pos))
+	return b
+}
+
+// stripLineMarkers removes the posMarkers from lua, and
+// returns the Go position for each of its lines. A line
+// without a marker has the position of the line before.
+func stripLineMarkers(lua []byte) ([]byte, []token.Pos) {
+	var out bytes.Buffer
+	marks := make(map[int]token.Pos)
+	f := &SourceMapFilter{
+		Writer: &out,
+		PosCallback: func(generatedLine int, originalPos token.Pos) {
+			// the outermost statement on the line comes first.
+			if _, ok := marks[generatedLine]; !ok {
+				marks[generatedLine] = originalPos
+			}
+		},
+	}
+	f.Write(lua)
+
+	poses := make([]token.Pos, bytes.Count(out.Bytes(), []byte("\n"))+1)
+	var cur token.Pos
+	for i := range poses {
+		if pos, ok := marks[i+1]; ok {
+			cur = pos
+		}
+		poses[i] = cur
+	}
+	return out.Bytes(), poses
+}
+
+func removeLineMarkers(lua []byte) []byte {
+	var out bytes.Buffer
+	f := &SourceMapFilter{Writer: &out}
+	f.Write(lua)
+	return out.Bytes()
+}
+
+// goFunc is the extent of a Go func declaration or literal.
+type goFunc struct {
+	pos, end token.Pos
+	name     string
+}
+
+// collectGoFuncs names the funcs in file the way the Go
+// runtime does: main.f, main.(*T).M, and main.f.func1 for
+// the first func literal in f. A func literal at the top
+// level of the prompt is main.func1.
+func collectGoFuncs(file *ast.File, pkgName string) (fns []goFunc) {
+	var stack []ast.Node
+	names := make(map[ast.Node]string)
+	lits := make(map[ast.Node]int)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		var outer ast.Node
+		for i := len(stack) - 1; i >= 0; i-- {
+			if _, ok := names[stack[i]]; ok {
+				outer = stack[i]
+				break
+			}
+		}
+		stack = append(stack, n)
+
+		switch f := n.(type) {
+		case *ast.FuncDecl:
+			name := pkgName + "." + f.Name.Name
+			if f.Recv != nil && len(f.Recv.List) > 0 {
+				switch r := f.Recv.List[0].Type.(type) {
+				case *ast.StarExpr:
+					if id, ok := r.X.(*ast.Ident); ok {
+						name = pkgName + ".(*" + id.Name + ")." + f.Name.Name
+					}
+				case *ast.Ident:
+					name = pkgName + "." + r.Name + "." + f.Name.Name
+				}
+			}
+			names[f] = name
+			fns = append(fns, goFunc{pos: f.Pos(), end: f.End(), name: name})
+		case *ast.FuncLit:
+			lits[outer]++
+			name := fmt.Sprintf("%s.func%d", pkgName, lits[outer])
+			if outer != nil {
+				name = fmt.Sprintf("%s.func%d", names[outer], lits[outer])
+			}
+			names[f] = name
+			fns = append(fns, goFunc{pos: f.Pos(), end: f.End(), name: name})
+		}
+		return true
+	})
+	return
+}
+
+// goLine is where a line of Lua came from.
+type goLine struct {
+	file string // empty for input typed at the prompt.
+	line int
+	fn   string // empty at the top level.
+}
+
+// goLinesFor resolves the positions from stripLineMarkers.
+func goLinesFor(fset *token.FileSet, poses []token.Pos, fns []goFunc) []goLine {
+	out := make([]goLine, len(poses))
+	for i, pos := range poses {
+		if !pos.IsValid() {
+			continue
+		}
+		p := fset.Position(pos)
+		gl := goLine{file: p.Filename, line: p.Line}
+		// the innermost func is the last to start.
+		var best token.Pos
+		for _, f := range fns {
+			if f.pos <= pos && pos < f.end && f.pos >= best {
+				best = f.pos
+				gl.fn = f.name
+			}
+		}
+		out[i] = gl
+	}
+	return out
+}
+
+// maxChunkMaps is how many line maps a luaChunkMaps
+// keeps; errors from older chunks keep their Lua
+// locations.
+const maxChunkMaps = 1000
+
+// luaChunkMaps holds the line maps of the latest
+// chunks run on a LuaVm, by chunk number.
+type luaChunkMaps struct {
+	mut    sync.Mutex
+	chunks [][]goLine
+	first  int // the number of chunks[0].
+}
+
+// add registers lines as the map of a new chunk,
+// and returns the chunk's number.
+func (m *luaChunkMaps) add(lines []goLine) int {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.first == 0 {
+		m.first = 1
+	}
+	if len(m.chunks) == maxChunkMaps {
+		m.chunks[0] = nil
+		m.chunks = m.chunks[1:]
+		m.first++
+	}
+	m.chunks = append(m.chunks, lines)
+	return m.first + len(m.chunks) - 1
+}
+
+// lookup returns the Go for line luaLine of chunk n.
+func (m *luaChunkMaps) lookup(n, luaLine int) (goLine, bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	// line 1 of the chunk is its --[[gi:N]] tag.
+	i := luaLine - 2
+	k := n - m.first
+	if k < 0 || k >= len(m.chunks) || i < 0 || i >= len(m.chunks[k]) {
+		return goLine{}, false
+	}
+	gl := m.chunks[k][i]
+	return gl, gl.line > 0
+}
+
+var luaChunkLocRegex = regexp.MustCompile(`\[string "--\[\[gi:(\d+)\]\][^"]*"\]:(\d+)`)
+
+// goTrace rewrites the chunk:line locations in s, a Lua
+// error message or traceback, as Go file:line. Traceback
+// lines also get the Go function name.
+func (m *luaChunkMaps) goTrace(s string) string {
+	if m == nil || !strings.Contains(s, `[string "--[[gi:`) {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		loc := luaChunkLocRegex.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(line[loc[2]:loc[3]])
+		ln, _ := strconv.Atoi(line[loc[4]:loc[5]])
+		gl, ok := m.lookup(n, ln)
+		rest := line[loc[1]:]
+		if ok && gl.fn != "" && (strings.HasPrefix(rest, ": in function") || strings.HasPrefix(rest, ": in main chunk")) {
+			lines[i] = line[:loc[0]] + gl.where(n) + ": in " + gl.fn
+			continue
+		}
+		lines[i] = luaChunkLocRegex.ReplaceAllStringFunc(line, func(x string) string {
+			sm := luaChunkLocRegex.FindStringSubmatch(x)
+			n, _ := strconv.Atoi(sm[1])
+			ln, _ := strconv.Atoi(sm[2])
+			if gl, ok := m.lookup(n, ln); ok {
+				if gl.fn != "" {
+					return gl.where(n) + " (" + gl.fn + ")"
+				}
+				return gl.where(n)
+			}
+			return x
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// where is file:line, or <input N>:line at the prompt.
+func (gl goLine) where(chunk int) string {
+	file := gl.file
+	if file == "" {
+		file = fmt.Sprintf("<input %d>", chunk)
+	}
+	return fmt.Sprintf("%s:%d", file, gl.line)
+}
+
+// tagChunk registers the line map of the latest
+// translation with the vm, and tags lua with its
+// chunk number. Use it on the output of Tr just
+// before running it.
+func (tr *IncrState) tagChunk(lua string) string {
+	lines := tr.lastLines
+	tr.lastLines = nil
+	if len(lines) == 0 || tr.goro == nil || tr.goro.lvm == nil {
+		return lua
+	}
+	n := tr.goro.lvm.chunkMaps.add(lines)
+	return fmt.Sprintf("--[[gi:%d]]\n%s", n, lua)
+}
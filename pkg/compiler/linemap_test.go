@@ -0,0 +1,87 @@
+package compiler
+
+import (
+	"fmt"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1303LuaErrorsMapBackToGoLines(t *testing.T) {
+
+	cv.Convey(`Lua error and traceback locations in translated code should be shown as the Go file, line and function they came from`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		// as :source gives a file: boom is on line 10 of boom.go.
+		src := "//line boom.go:10\nfunc boom(a []int) int {\n\tb := a[3]\n\treturn b\n}\n"
+		translation, err := inc.Tr([]byte(src))
+		panicOn(err)
+		cv.So(string(translation), cv.ShouldNotContainSubstring, "\b")
+
+		lines := inc.lastLines
+		tagged := inc.tagChunk(string(translation))
+		cv.So(tagged, cv.ShouldStartWith, "--[[gi:1]]\n")
+
+		// the Lua for 'b := a[3]'. The chunk's
+		// first line is the --[[gi:1]] tag.
+		luaLine := 0
+		for i, gl := range lines {
+			if gl.file == "boom.go" && gl.line == 11 && gl.fn == "main.boom" {
+				luaLine = i + 2
+				break
+			}
+		}
+		cv.So(luaLine, cv.ShouldBeGreaterThan, 0)
+
+		loc := fmt.Sprintf(`[string "--[[gi:1]]..."]:%d`, luaLine)
+		trace := loc + ": index out of range\nstack traceback:\n\t" + loc + ": in function 'boom'"
+		got := vm.chunkMaps.goTrace(trace)
+		cv.So(got, cv.ShouldContainSubstring, "boom.go:11 (main.boom): index out of range")
+		cv.So(got, cv.ShouldContainSubstring, "\tboom.go:11: in main.boom")
+		cv.So(got, cv.ShouldNotContainSubstring, "[string")
+
+		// input typed at the prompt, at the top level.
+		translation, err = inc.Tr([]byte("r := boom([]int{1})"))
+		panicOn(err)
+		tagged = inc.tagChunk(string(translation))
+		cv.So(tagged, cv.ShouldStartWith, "--[[gi:2]]\n")
+
+		got = vm.chunkMaps.goTrace("\t[string \"--[[gi:2]]...\"]:2: in main chunk")
+		cv.So(got, cv.ShouldEqual, "\t<input 2>:1: in main chunk")
+
+		// locations in untagged chunks, like the prelude's, are left alone.
+		other := `[string "x = 1"]:1: oops`
+		cv.So(vm.chunkMaps.goTrace(other), cv.ShouldEqual, other)
+
+		// the position marks don't touch strings that look like them.
+		translation, err = inc.Tr([]byte(`m := "--[[@12]]\b"`))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+		LuaMustString(vm, "m", "--[[@12]]\b")
+	})
+}
+
+func Test1305ChunkMapsAreCapped(t *testing.T) {
+
+	cv.Convey(`a vm should keep the line maps of only its latest maxChunkMaps chunks`, t, func() {
+
+		var m luaChunkMaps
+		for i := 1; i <= maxChunkMaps+2; i++ {
+			cv.So(m.add([]goLine{{line: i}}), cv.ShouldEqual, i)
+		}
+		cv.So(len(m.chunks), cv.ShouldEqual, maxChunkMaps)
+
+		_, ok := m.lookup(2, 2)
+		cv.So(ok, cv.ShouldBeFalse)
+		gl, ok := m.lookup(3, 2)
+		cv.So(ok, cv.ShouldBeTrue)
+		cv.So(gl.line, cv.ShouldEqual, 3)
+		gl, ok = m.lookup(maxChunkMaps+2, 2)
+		cv.So(ok, cv.ShouldBeTrue)
+		cv.So(gl.line, cv.ShouldEqual, maxChunkMaps+2)
+	})
+}
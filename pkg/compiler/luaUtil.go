@@ -40,9 +40,14 @@ type LuaVm struct {
 
 	goro *Goro
 	mut  sync.Mutex
+
+	// line maps of the translated chunks run
+	// here, for goTrace. See linemap.go.
+	chunkMaps luaChunkMaps
 }
 
 func (lvm *LuaVm) Close() {
+	forgetVm(lvm)
 	lvm.goro.halt.RequestStop()
 	<-lvm.goro.halt.Done.Chan
 }
@@ -75,7 +80,7 @@ func NewLuaVmWithPrelude(cfg *GIConfig) (lvm *LuaVm, err error) {
 
 	if useStaticPrelude {
 		cfg.PreludePath = ""
-	} else {
+	} else if cfg.PreludePath == "" {
 		cwd, err := os.Getwd()
 		panicOn(err)
 		cfg.PreludePath = cwd + "/prelude"
@@ -92,6 +97,7 @@ func NewLuaVmWithPrelude(cfg *GIConfig) (lvm *LuaVm, err error) {
 	vm = luar.Init() // does vm.OpenLibs() for us, adds luar. functions.
 	registerLuarReqs(vm)
 	lvm.vm = vm
+	rememberVm(lvm)
 
 	// before any LuaRun, must setup the lvm.goro
 	gcfg := &GoroConfig{}
@@ -277,6 +283,24 @@ func DumpLuaStackAsString(L *golua.State, ignoreTop int) (s string) {
 	return
 }
 
+// luaTraceback returns debug.traceback() of the
+// coroutine at index on L's stack.
+func luaTraceback(L *golua.State, index int) string {
+	top := L.GetTop()
+	defer L.SetTop(top)
+	if index < 0 {
+		index = top + index + 1
+	}
+	L.GetGlobal("debug")
+	L.GetField(-1, "traceback")
+	L.PushValue(index)
+	err := L.Call(1, 1)
+	if err != nil {
+		return fmt.Sprintf("no traceback: %v", err)
+	}
+	return L.ToString(-1)
+}
+
 func FetchPreludeFilenames(preludePath string, quiet bool) ([]string, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -0,0 +1,109 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1510StructArrayAndInterfaceMapKeys(t *testing.T) {
+
+	cv.Convey(`structs, arrays and interfaces holding them should work as map keys, comparing by value for make, index, delete, range and len; a nil interface key should be seen by range`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		code := `
+type Point struct{ X, Y int }
+m := make(map[Point]int)
+m[Point{1, 2}] = m[Point{1, 2}] + 1
+m[Point{1, 2}] = m[Point{1, 2}] + 1
+m[Point{3, 4}] = 5
+p := Point{1, 2}
+a := m[p]
+b, ok := m[Point{9, 9}]
+n := len(m)
+sx := 0
+for k, v := range m {
+	sx += k.X * v
+}
+delete(m, Point{3, 4})
+n2 := len(m)
+
+lit := map[Point]string{{1, 2}: "a", {2, 1}: "b"}
+litb := lit[Point{2, 1}]
+
+g := map[[2]int]bool{}
+g[[2]int{1, 2}] = true
+has := g[[2]int{1, 2}]
+hasNot := g[[2]int{2, 1}]
+
+s := map[[2]string]int{}
+s[[2]string{"a_b", "c"}] = 1
+s[[2]string{"a", "b_c"}] = 2
+slen := len(s)
+
+i := make(map[interface{}]string)
+i[Point{1, 2}] = "pt"
+i[1] = "one"
+i["1"] = "str"
+i[[2]int{1, 2}] = "arr"
+ilen := len(i)
+ip := i[Point{1, 2}]
+ione := i[1]
+istr := i["1"]
+iarr := i[[2]int{1, 2}]
+
+w := map[string]int{}
+w["len"] = 7
+wl := w["len"]
+wn := len(w)
+
+j := map[interface{}]int{}
+j[nil] = 3
+j[2] = 4
+jnil := 0
+jsum := 0
+for k, v := range j {
+	if k == nil {
+		jnil++
+	}
+	jsum += v
+}
+`
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustInt64(vm, "a", 2)
+		LuaMustInt64(vm, "b", 0)
+		LuaMustBool(vm, "ok", false)
+		LuaMustInt(vm, "n", 2)
+		LuaMustInt64(vm, "sx", 1*2+3*5)
+		LuaMustInt(vm, "n2", 1)
+
+		LuaMustString(vm, "litb", "b")
+
+		LuaMustBool(vm, "has", true)
+		LuaMustBool(vm, "hasNot", false)
+
+		// "_" in a string element can't make two keys collide.
+		LuaMustInt(vm, "slen", 2)
+
+		LuaMustInt(vm, "ilen", 4)
+		LuaMustString(vm, "ip", "pt")
+		LuaMustString(vm, "ione", "one")
+		LuaMustString(vm, "istr", "str")
+		LuaMustString(vm, "iarr", "arr")
+
+		// keys that are also fields of the map's Lua table.
+		LuaMustInt64(vm, "wl", 7)
+		LuaMustInt(vm, "wn", 1)
+
+		// range visits a nil interface key too.
+		LuaMustInt64(vm, "jnil", 1)
+		LuaMustInt64(vm, "jsum", 7)
+	})
+}
@@ -0,0 +1,267 @@
+package compiler
+
+// modules.go: source imports from Go modules.
+//
+// The forked go/build only knows GOPATH. Before falling
+// back to it, importWithSrcDir asks the go.mod of the main
+// module, the one holding the working directory (or else
+// the importing package), where a package's source lives:
+// in the main module itself, in its vendor directory, in
+// a local directory named by a replace directive, or in
+// the module cache. Nothing is downloaded; a module that
+// isn't already on disk can't be imported.
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gijit/gi/pkg/gostd/build"
+)
+
+// goModule is what importing needs from a go.mod.
+type goModule struct {
+	Dir     string // holds the go.mod
+	Path    string // from the module line
+	Require map[string]string
+	Replace map[string]modReplace
+}
+
+// modReplace is the right hand side of a replace
+// directive. Version is empty if Path is a directory.
+type modReplace struct {
+	OldVersion string // empty to replace every version
+	Path       string
+	Version    string
+}
+
+// findGoMod returns the module whose go.mod is in dir or
+// the nearest directory above it, or nil if there is none.
+func findGoMod(dir string) (*goModule, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			return parseGoMod(dir, data)
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		up := filepath.Dir(dir)
+		if up == dir {
+			return nil, nil
+		}
+		dir = up
+	}
+}
+
+// parseGoMod reads the module, require and replace
+// directives of a go.mod, and ignores the others.
+func parseGoMod(dir string, data []byte) (*goModule, error) {
+	m := &goModule{
+		Dir:     dir,
+		Require: make(map[string]string),
+		Replace: make(map[string]modReplace),
+	}
+	block := ""
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := sc.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		verb := block
+		switch {
+		case block != "" && f[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(f) == 2 && f[1] == "(":
+			block = f[0]
+			continue
+		case block == "":
+			verb, f = f[0], f[1:]
+		}
+		for i := range f {
+			if uq, err := strconv.Unquote(f[i]); err == nil {
+				f[i] = uq
+			}
+		}
+		bad := func() error {
+			return fmt.Errorf("%s:%d: malformed %s directive", filepath.Join(dir, "go.mod"), lineno, verb)
+		}
+		switch verb {
+		case "module":
+			if len(f) != 1 {
+				return nil, bad()
+			}
+			m.Path = f[0]
+		case "require":
+			if len(f) != 2 {
+				return nil, bad()
+			}
+			m.Require[f[0]] = f[1]
+		case "replace":
+			var r modReplace
+			arrow := 1
+			if len(f) > 1 && f[1] != "=>" {
+				r.OldVersion = f[1]
+				arrow = 2
+			}
+			rhs := f[arrow+1:]
+			if len(f) <= arrow || f[arrow] != "=>" || len(rhs) == 0 || len(rhs) > 2 {
+				return nil, bad()
+			}
+			r.Path = rhs[0]
+			if len(rhs) == 2 {
+				r.Version = rhs[1]
+			} else if !isModDir(r.Path) {
+				return nil, bad()
+			}
+			m.Replace[f[0]] = r
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if m.Path == "" {
+		return nil, fmt.Errorf("%s: no module directive", filepath.Join(dir, "go.mod"))
+	}
+	return m, nil
+}
+
+// isModDir tells a directory on the right of a replace
+// from a module path.
+func isModDir(p string) bool {
+	return filepath.IsAbs(p) || p == "." || p == ".." ||
+		strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../")
+}
+
+// packageDir returns the directory holding the source of
+// the package importPath, or "" if m doesn't provide it.
+func (m *goModule) packageDir(importPath string) string {
+	if rest, ok := underModule(importPath, m.Path); ok {
+		return filepath.Join(m.Dir, rest)
+	}
+
+	// the longest module path that holds the package.
+	mod, rest := "", ""
+	for p := range m.Require {
+		if r, ok := underModule(importPath, p); ok && len(p) > len(mod) {
+			mod, rest = p, r
+		}
+	}
+	for p := range m.Replace {
+		if r, ok := underModule(importPath, p); ok && len(p) > len(mod) {
+			mod, rest = p, r
+		}
+	}
+	if mod == "" {
+		return ""
+	}
+
+	vendor := filepath.Join(m.Dir, "vendor")
+	if _, err := os.Stat(filepath.Join(vendor, "modules.txt")); err == nil {
+		return filepath.Join(vendor, filepath.FromSlash(importPath))
+	}
+
+	version := m.Require[mod]
+	if r, ok := m.Replace[mod]; ok && (r.OldVersion == "" || r.OldVersion == version) {
+		if r.Version == "" {
+			dir := filepath.FromSlash(r.Path)
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(m.Dir, dir)
+			}
+			return filepath.Join(dir, rest)
+		}
+		mod, version = r.Path, r.Version
+	}
+	if version == "" {
+		return ""
+	}
+	return filepath.Join(modCacheDir(), escapeModPath(mod)+"@"+escapeModPath(version), rest)
+}
+
+// underModule reports whether importPath is in the module
+// modPath, and if so the package's path within it.
+func underModule(importPath, modPath string) (rest string, ok bool) {
+	if importPath == modPath {
+		return "", true
+	}
+	if strings.HasPrefix(importPath, modPath+"/") {
+		return filepath.FromSlash(importPath[len(modPath)+1:]), true
+	}
+	return "", false
+}
+
+// modCacheDir is where the go command unpacks modules.
+func modCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.SplitList(build.Default.GOPATH)[0], "pkg", "mod")
+}
+
+// escapeModPath spells a module path or version the way
+// the module cache does, with each upper case letter
+// written as '!' and its lower case.
+func escapeModPath(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// importFromModule finds the package path through the
+// main module's go.mod. It returns a nil package, and no
+// error, if there is no go.mod or the package isn't in a
+// module it requires, so that GOPATH is tried next.
+func importFromModule(bctx *build.Context, path, srcDir string, mode build.ImportMode) (*build.Package, *goModule, error) {
+	if build.IsLocalImport(path) {
+		return nil, nil, nil
+	}
+	wd, _ := os.Getwd()
+	tried := make(map[string]bool)
+	for _, from := range []string{wd, srcDir} {
+		m, err := findGoMod(from)
+		if err != nil {
+			return nil, nil, err
+		}
+		if m == nil || tried[m.Dir] {
+			continue
+		}
+		tried[m.Dir] = true
+		dir := m.packageDir(path)
+		if dir == "" {
+			continue
+		}
+		pkg, err := bctx.ImportDir(dir, mode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("import %q from module %s: %v", path, m.Path, err)
+		}
+		pkg.ImportPath = path
+		// nothing is written back into a module.
+		pkg.PkgObj = ""
+		return pkg, m, nil
+	}
+	return nil, nil, nil
+}
@@ -0,0 +1,121 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+// writeFixture writes files, named by slash separated
+// paths under dir.
+func writeFixture(dir string, files map[string]string) {
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		panicOn(os.MkdirAll(filepath.Dir(path), 0755))
+		panicOn(ioutil.WriteFile(path, []byte(src), 0644))
+	}
+}
+
+// testPreludePath is the prelude directory, found before
+// any test changes directory.
+var testPreludePath = func() string {
+	wd, err := os.Getwd()
+	panicOn(err)
+	return filepath.Join(wd, "prelude")
+}()
+
+// newVmAnywhere is NewLuaVmWithPrelude(nil) for the tests
+// that chdir away from this package: it finds the prelude
+// at its absolute path, not under the working directory.
+func newVmAnywhere() (*LuaVm, *GIConfig) {
+	cfg := NewGIConfig()
+	cfg.PreludePath = testPreludePath
+	vm, err := NewLuaVmWithPrelude(cfg)
+	panicOn(err)
+	return vm, cfg
+}
+
+func Test2500SourceImportThroughGoMod(t *testing.T) {
+
+	cv.Convey(`packages outside GOPATH should import by source through the go.mod of the main module: from the module itself, a replace with a local directory, the module cache, or vendor`, t, func() {
+
+		dir, err := ioutil.TempDir("", "gijit-modules")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+		writeFixture(dir, map[string]string{
+			"app/go.mod": `module example.com/app
+
+go 1.12
+
+require (
+	example.com/Loud v0.2.0 // indirect
+	example.com/greet v1.0.0
+)
+
+replace example.com/greet => ../greet
+`,
+			"app/util/util.go": "package util\n\nfunc Twice(x int) int { return 2 * x }\n",
+			"greet/go.mod":     "module example.com/greet\n",
+			"greet/greet.go": `package greet
+
+import "example.com/Loud/bang"
+
+func Hello(name string) string { return bang.Bang("hello " + name) }
+`,
+			"modcache/example.com/!loud@v0.2.0/bang/bang.go": "package bang\n\nfunc Bang(s string) string { return s + \"!\" }\n",
+
+			"vapp/go.mod":                            "module example.com/vapp\n\nrequire example.com/seven v1.0.0\n",
+			"vapp/vendor/modules.txt":                "# example.com/seven v1.0.0\nexample.com/seven\n",
+			"vapp/vendor/example.com/seven/seven.go": "package seven\n\nconst N = 7\n",
+		})
+
+		wd, err := os.Getwd()
+		panicOn(err)
+		defer os.Chdir(wd)
+		defer os.Setenv("GOMODCACHE", os.Getenv("GOMODCACHE"))
+		os.Setenv("GOMODCACHE", filepath.Join(dir, "modcache"))
+
+		// evalIn translates and runs src at a fresh prompt
+		// started in dir.
+		evalIn := func(dir, src string) (*LuaVm, error) {
+			panicOn(os.Chdir(dir))
+			vm, cfg := newVmAnywhere()
+			inc := NewIncrState(vm, cfg)
+			translation, err := inc.Tr([]byte(src))
+			if err != nil {
+				return vm, err
+			}
+			LuaRunAndReport(vm, string(translation))
+			return vm, lastEvalError(vm)
+		}
+
+		vm, err := evalIn(filepath.Join(dir, "app", "util"), `
+import (
+	"example.com/app/util"
+	"example.com/greet"
+)
+n := util.Twice(21)
+hi := greet.Hello("gi")
+`)
+		cv.So(err, cv.ShouldBeNil)
+		LuaMustInt64(vm, "n", 42)
+		LuaMustString(vm, "hi", "hello gi!")
+		vm.Close()
+
+		// the main module has no such package.
+		vm, err = evalIn(filepath.Join(dir, "app"), `import "example.com/app/missing"`)
+		cv.So(err, cv.ShouldNotBeNil)
+		vm.Close()
+
+		vm, err = evalIn(filepath.Join(dir, "vapp"), `
+import "example.com/seven"
+n := seven.N
+`)
+		cv.So(err, cv.ShouldBeNil)
+		LuaMustInt64(vm, "n", 7)
+		vm.Close()
+	})
+}
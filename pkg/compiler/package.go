@@ -39,6 +39,8 @@ type pkgContext struct {
 	indentation  int
 	dependencies map[types.Object]bool
 	minify       bool
+	linePos      bool // writePos emits posMarkers, see linemap.go.
+	preempt      bool // loops call __preempt, not __backEdge; see printBackEdge.
 	fileSet      *token.FileSet
 	files        []*ast.File
 	errList      ErrorList
@@ -113,6 +115,10 @@ type flowData struct {
 type ImportContext struct {
 	Packages map[string]*types.Package
 	Import   func(path, pkgDir string, depth int) (*Archive, error)
+
+	// Preempt is GIConfig.Preempt, for the packages
+	// compiled in this context.
+	Preempt bool
 }
 
 // packageImporter implements go/types.Importer interface.
@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test2000PreemptSpinningGoroutines(t *testing.T) {
+
+	cv.Convey(`with GIConfig.Preempt, two goroutines spinning in tight loops should both make progress, and a goroutine that spins forever should not keep the prompt from coming back`, t, func() {
+
+		cfg := NewGIConfig()
+		cfg.Preempt = true
+		vm, err := NewLuaVmWithPrelude(cfg)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, cfg)
+
+		code := `
+import "time"
+stop := false
+a, b := 0, 0
+go func() {
+	for !stop {
+		a++
+	}
+}()
+go func() {
+	for !stop {
+		b++
+	}
+}()
+time.Sleep(20 * time.Millisecond)
+stop = true
+fair := a > 0 && b > 0 && a < 4*b && b < 4*a
+`
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		cv.So(strings.Contains(string(translation), "__preempt()"), cv.ShouldBeTrue)
+		LuaRunAndReport(vm, string(translation))
+		LuaMustBool(vm, "fair", true)
+
+		translation, err = inc.Tr([]byte(`
+spin := 0
+go func() {
+	for {
+		spin++
+	}
+}()
+`))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+
+		translation, err = inc.Tr([]byte(`spun := spin > 0`))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+		LuaMustBool(vm, "spun", true)
+	})
+
+	cv.Convey(`without GIConfig.Preempt, loops should not call __preempt`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation, err := inc.Tr([]byte(`
+n := 0
+for i := 0; i < 10; i++ {
+	n += i
+}
+`))
+		panicOn(err)
+		cv.So(strings.Contains(string(translation), "__preempt"), cv.ShouldBeFalse)
+		LuaRunAndReport(vm, string(translation))
+		LuaMustInt64(vm, "n", 45)
+	})
+}
@@ -72,7 +72,8 @@ elseif jit.os == "OSX" then
 else
    -- for linux, clock_gettime(CLOCK_MONOTONIC)
 
-   if ffi.typeof("nanotime") == nil then
+   -- ffi.typeof raises an error on an unknown type name.
+   if not pcall(ffi.typeof, "nanotime") then
       ffi.cdef[[
        typedef long time_t;
        typedef int clockid_t;
@@ -93,3 +94,30 @@ else
    end
 
 end
+
+-- __abs_sleep(ns) blocks the whole LuaJIT thread
+-- for ns nanoseconds. Only the scheduler should call
+-- this, when every runnable goroutine is waiting
+-- on a timer.
+
+if jit.os == "Windows" then
+   ffi.cdef[[
+   void __stdcall Sleep(uint32_t dwMilliseconds);
+   ]]
+   __abs_sleep=function(ns)
+      local ms = tonumber(ns / 1000000LL)
+      if ms > 0 then
+         ffi.C.Sleep(ms)
+      end
+   end
+else
+   ffi.cdef[[
+   int usleep(uint32_t usec);
+   ]]
+   __abs_sleep=function(ns)
+      local us = tonumber(ns / 1000LL)
+      if us > 0 then
+         ffi.C.usleep(us)
+      end
+   end
+end
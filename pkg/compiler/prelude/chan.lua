@@ -79,8 +79,15 @@ local TIMEOUT = {err = "TIMEOUT"}
 
 -- Global objects for scheduler
 local tasks_runnable = {}       -- list of coroutines ready to be resumed
+local tasks_preempted = {}      -- coroutines that yielded in __preempt
 local tasks_to = {}             -- all the timeout tasks
+local timers = {}               -- pending timers, earliest deadline first
+local blocked_on = {}           -- coroutine -> the alt_array it waits in
+local interrupt_requested = false -- see __gijitInterrupt
+local interrupted_co = {}       -- goroutines stopped by an interrupt
 local altexec
+local deadlock
+local abandon_eval
 
 __all_coro = {} -- array
 
@@ -88,7 +95,7 @@ __cleanupDeadCoro = function()
    local keepers_all = {}
    local keepers_notes = {}
    for i,co in ipairs(__all_coro) do
-      if coroutine.status(co) ~= "dead" then
+      if coroutine.status(co) ~= "dead" and not __coro2notes[co].__abandoned then
          table.insert(keepers_all, co)
          local v = __coro2notes[co]
          v.__loc = #keepers_all
@@ -217,6 +224,109 @@ local CircularBuffer = {
    end,
 }
 
+----------------------------------------------------------------------------
+-- Timers
+--
+-- A timer is a table {when=, f=}; when is an absolute
+-- __abs_now() deadline in nanoseconds. The scheduler calls
+-- f(now) once the deadline has passed. Timers back
+-- time.Sleep, time.After, time.NewTimer and time.NewTicker;
+-- see timer.lua.
+
+local function timer_add(when, f)
+   local t = {when = when, f = f}
+   local i = #timers + 1
+   while i > 1 and timers[i-1].when > when do
+      i = i - 1
+   end
+   table.insert(timers, i, t)
+   return t
+end
+
+-- returns true if t was still pending.
+local function timer_remove(t)
+   for i, v in ipairs(timers) do
+      if v == t then
+         table.remove(timers, i)
+         return true
+      end
+   end
+   return false
+end
+
+-- run every timer whose deadline has passed.
+local function timers_fire(now)
+   local n = 0
+   while #timers > 0 and timers[1].when <= now do
+      local t = table.remove(timers, 1)
+      t.f(now)
+      n = n + 1
+   end
+   return n
+end
+
+-- Is the current eval still going, blocked or not?
+-- Preempted goroutines only get another turn while it
+-- is; after it ends they wait for the next eval, so
+-- that spinning goroutines can't keep the prompt away.
+local function eval_alive()
+   return __gijitEvalCoro ~= nil and
+      coroutine.status(__gijitEvalCoro) ~= "dead"
+end
+
+-- Is the current eval coroutine blocked? If so, the
+-- scheduler should wait for the next timer rather than
+-- return to the prompt.
+local function eval_blocked()
+   return __gijitEvalCoro ~= nil and
+      coroutine.status(__gijitEvalCoro) == "suspended"
+end
+
+-- the longest the scheduler sleeps between looks
+-- at its timers and at interrupt_requested.
+local maxSleep = 50000000LL
+
+----------------------------------------------------------------------------
+-- Interrupts
+--
+-- Ctrl-C at the prompt, or the :timeout limit, has the
+-- Go side (interrupt.go) install a LuaJIT hook that calls
+-- __gijitInterrupt at the next Lua instruction, in
+-- whatever coroutine that is. In the eval it panics with
+-- "interrupted", running any defers on the way back to
+-- the prompt. In a goroutine, the panic ends that
+-- goroutine, and the scheduler then abandons the eval.
+-- In the scheduler, or in __eval on the main coroutine,
+-- it asks the scheduler to abandon the eval.
+
+local interruptMsg = "interrupted"
+
+__gijitInterrupt = function()
+   local co, is_main = coroutine.running()
+   if is_main then
+      if eval_alive() then
+         interrupt_requested = true
+         return
+      end
+      -- code run without the eval coroutine.
+      panic(interruptMsg)
+   end
+   if co == scheduler_co or __coro2notes[co] == nil then
+      interrupt_requested = true
+      return
+   end
+   if co ~= __gijitEvalCoro then
+      interrupted_co[co] = true
+   end
+   panic(interruptMsg)
+end
+
+-- a Ctrl-C that came after its eval was over
+-- shouldn't stop the next one.
+local function clear_interrupt()
+   interrupt_requested = false
+end
+
 ----------------------------------------------------------------------------
 -- Scheduling
 --
@@ -250,10 +360,44 @@ local function scheduler()
    
    local i = 0
    while true do
+      if interrupt_requested then
+         interrupt_requested = false
+         if eval_alive() then
+            -- as __errHandlerForEval reports the panic.
+            abandon_eval("panic: "..interruptMsg)
+         end
+         break
+      end
+      timers_fire(__abs_now())
       local nr = #tasks_runnable
+      if nr == 0 and #tasks_preempted > 0 and eval_alive() then
+         -- everyone else has had a turn.
+         tasks_runnable = tasks_preempted
+         tasks_preempted = {}
+         goto continue
+      end
       if nr == 0 then
-         --print("scheduler: no more runnable tasks")
-         break
+         if #timers == 0 and next(tasks_to) == nil and eval_blocked() then
+            -- nothing can ever wake the eval.
+            deadlock()
+            break
+         end
+         if #timers == 0 or not eval_blocked() then
+            --print("scheduler: no more runnable tasks")
+            break
+         end
+         -- everyone is waiting; the eval coroutine may be
+         -- in time.Sleep or a select on time.After. Sleep
+         -- until the earliest timer is due, in slices so
+         -- that an interrupt isn't kept waiting.
+         local wait = timers[1].when - __abs_now()
+         if wait > maxSleep then
+            wait = maxSleep
+         end
+         if wait > 0 then
+            __abs_sleep(wait)
+         end
+         goto continue
       end
       -- jea: pick one at random
       local k = __builtin_math.random(nr)
@@ -272,6 +416,7 @@ local function scheduler()
       end
       i = i + 1
       --print("scheduler: resume was okay, i is now = ", i)      
+      ::continue::
    end
 
    local now = __abs_now()
@@ -307,12 +452,76 @@ task_park = function(co)
    tasks_runnable = newrun
 end
 
+----------------------------------------------------------------------------
+-- Preemption
+--
+-- With GIConfig.Preempt, the translator has every loop
+-- iteration call __preempt, so a goroutine (or the
+-- eval) in a tight loop yields to the scheduler every
+-- __preemptEvery iterations, instead of starving the
+-- others. LuaJIT count hooks can't yield, and don't
+-- fire in compiled traces, hence the explicit calls.
+--
+-- Without it, loops call __backEdge instead, which
+-- every __preemptEvery iterations leaves the loop's
+-- compiled trace for the interpreter, so that the hook
+-- set by an interrupt (see __gijitInterrupt) can fire.
+
+__preemptEvery = 10000
+
+local preempt_count = 0
+
+-- Calls to a function that LuaJIT may not compile can't
+-- be part of a trace: reaching one exits to the
+-- interpreter.
+local function interpreted()
+end
+jit.off(interpreted)
+
+__backEdge = function()
+   preempt_count = preempt_count + 1
+   if preempt_count < __preemptEvery then
+      return
+   end
+   preempt_count = 0
+   interpreted()
+end
+
+__preempt = function()
+   preempt_count = preempt_count + 1
+   if preempt_count < __preemptEvery then
+      return
+   end
+   preempt_count = 0
+   interpreted()
+   local co, is_main = coroutine.running()
+   if is_main or co == scheduler_co or __coro2notes[co] == nil then
+      -- not a task of the scheduler's.
+      return
+   end
+   if coroutine.isyieldable ~= nil and not coroutine.isyieldable() then
+      -- in a Lua callback from Go.
+      return
+   end
+   table.insert(tasks_preempted, co)
+   coroutine.yield()
+end
+
 local function spawn(fun, args)
    --local args = {...}
 
    local f = function()
       local okay, emsg = pcall(fun, unpack(args))
       if not okay then
+         local co = coroutine.running()
+         if interrupted_co[co] then
+            -- stopped by Ctrl-C or :timeout; take the
+            -- eval with us.
+            interrupted_co[co] = nil
+            print("goroutine "..__costring(co).." interrupted")
+            interrupt_requested = true
+            return
+         end
          --print(debug.traceback(emsg))
          error(emsg)
       end
@@ -397,6 +606,43 @@ local function altalldequeue(alt_array)
    end
 end
 
+-- The eval is blocked and no goroutine can run: report
+-- it the way the Go runtime would, and abandon the eval,
+-- so that a send from some later eval can't wake it.
+-- The goroutines stay blocked, for later evals to wake.
+local deadlockMsg = "fatal error: all goroutines are asleep - deadlock!"
+
+deadlock = function()
+   print(deadlockMsg)
+   print(__goTrace(__goroutines()))
+   abandon_eval(deadlockMsg)
+end
+
+-- abandon_eval drops the current eval, wherever it is
+-- waiting, and leaves msg as its error for the Go side.
+abandon_eval = function(msg)
+   local co = __gijitEvalCoro
+   __lastEvalErr = msg
+
+   task_park(co)
+   local keep = {}
+   for _, v in ipairs(tasks_preempted) do
+      if v ~= co then table.insert(keep, v) end
+   end
+   tasks_preempted = keep
+
+   local b = blocked_on[co]
+   if b ~= nil then
+      altalldequeue(b)
+   end
+   blocked_on[co] = nil
+   tasks_to[co] = nil
+   if __coro2notes[co] ~= nil then
+      __coro2notes[co].__abandoned = true
+   end
+   __gijitEvalCoro = nil
+end
+
 -- Can this Alt be execed without blocking?
 local function altcanexec(a)
    local c, op = a.c, a.op
@@ -500,6 +746,7 @@ select_inner = function(alt_array)
 
       local thisCo = coroutine.running()
       task_park(thisCo)
+      blocked_on[thisCo] = {reason = "select (no cases)"}
       coroutine.yield() -- go back to scheduler
    end
 
@@ -590,7 +837,9 @@ select_inner = function(alt_array)
    local current_co, is_main = coroutine.running()  
    --print("about to yield from (is_main? ",is_main," co=", current_co, " / ", __costring(current_co))
    
+   blocked_on[current_co] = alt_array
    local who = coroutine.yield()
+   blocked_on[current_co] = nil
    --print("select: resumed by who='"..who.."'")
    
    assert(alt_array.resolved > 0)
@@ -606,9 +855,12 @@ end
 ----------------------------------------------------------------------------
 -- Channel object
 
+local chan_count = 0
+
 local Channel = {
    new = function(self, buf_size, elemTyp)
-      local o = {__elemTyp=elemTyp, __name="__valChannel"};
+      chan_count = chan_count + 1
+      local o = {__elemTyp=elemTyp, __name="__valChannel", __id=chan_count};
       setmetatable(o, self);
       self.__index = self
       o._buf = CircularBuffer:new(buf_size or 0)
@@ -721,6 +973,121 @@ __resume_scheduler = function()
 end
 
 
+----------------------------------------------------------------------------
+-- Waiting with a reason
+
+-- wait_on receives from ch, like ch:recv(), but shows
+-- reason as the state of the goroutine in :goroutines,
+-- the way Go shows [sync.Mutex.Lock] or [sleep].
+local function wait_on(ch, reason)
+   local alts = {{c = ch, op = RECV}}
+   alts.reason = reason
+   local r = select(alts, true)
+   return unpack(r[2])
+end
+
+----------------------------------------------------------------------------
+-- The :goroutines inspector
+
+local function wait_reason(b)
+   if b.reason ~= nil then
+      return b.reason
+   end
+   if #b == 1 then
+      if b[1].op == RECV then
+         return "chan receive"
+      end
+      return "chan send"
+   end
+   return "select"
+end
+
+-- a channel is known by the global holding it, if any.
+local function chan_name(c)
+   for k, v in pairs(_G) do
+      if v == c and type(k) == "string" then
+         return k
+      end
+   end
+   return "chan #"..tostring(c.__id)
+end
+
+local function contains(list, co)
+   for _, v in ipairs(list) do
+      if v == co then
+         return true
+      end
+   end
+   return false
+end
+
+-- coro_state returns the state of co, in Go's words,
+-- and the channels it waits on, if any.
+local function coro_state(co)
+   if coroutine.status(co) == "dead" then
+      return "dead", ""
+   end
+   local b = blocked_on[co]
+   if b ~= nil then
+      local names = {}
+      if b.reason == nil then
+         for _, a in ipairs(b) do
+            table.insert(names, chan_name(a.c))
+         end
+      end
+      return wait_reason(b), table.concat(names, ", ")
+   end
+   if contains(tasks_runnable, co) then
+      return "runnable", ""
+   end
+   if contains(tasks_preempted, co) then
+      return "runnable, preempted", ""
+   end
+   local st = coroutine.status(co)
+   if st == "running" or st == "normal" then
+      return "running", ""
+   end
+   return "idle", ""
+end
+
+-- where is the first line of co's traceback that is
+-- in code from the prompt, or else its first frame.
+local function where(co)
+   local first
+   for line in string.gmatch(debug.traceback(co), "[^\n]+") do
+      line = line:gsub("^%s+", "")
+      if line:find('[string "--[[gi:', 1, true) then
+         return line
+      end
+      if first == nil and line ~= "stack traceback:" then
+         first = line
+      end
+   end
+   return first or "?"
+end
+
+-- __goroutines describes every coroutine, one
+-- paragraph each, for :goroutines and deadlock reports.
+-- The Lua chunk locations are left for __goTrace or
+-- the Go side to turn into Go file:line.
+__goroutines = function()
+   local out = {}
+   for i, co in ipairs(__all_coro) do
+      local notes = __coro2notes[co] or {__name = "?"}
+      if not notes.__abandoned then
+         local state, chans = coro_state(co)
+         table.insert(out, string.format("goroutine %d [%s]: %s", i, state, notes.__name))
+         if co ~= main_coro and co ~= scheduler_co then
+            table.insert(out, "\t"..where(co))
+         end
+         if chans ~= "" then
+            table.insert(out, "\twaiting on "..chans)
+         end
+      end
+   end
+   return table.concat(out, "\n").."\n"
+end
+
 ----------------------------------------------------------------------------
 -- Public interface
 
@@ -729,9 +1096,13 @@ __task = __M
 __task.resume_scheduler = __resume_scheduler
 
 __task.scheduler = scheduler
+__task.timer_add    = timer_add
+__task.timer_remove = timer_remove
 __task.spawn     = spawn
 __task.Channel   = Channel
 __task.select    = select
+__task.wait_on   = wait_on
+__task.clear_interrupt = clear_interrupt
 __task.RECV      = RECV
 __task.SEND      = SEND
 __task.NOP       = NOP
@@ -38,6 +38,99 @@ float32 = ffi.typeof("float")
 
 -- to display floats, use: tonumber() to convert to float64 that lua can print.
 
+----------------------------------
+--
+-- Go integer semantics
+--
+----------------------------------
+
+-- Every Go integer is held in an int64 cdata if signed,
+-- or a uint64 cdata if unsigned; LuaJIT's 64-bit
+-- arithmetic on those wraps around just as Go's does.
+-- The narrower types are kept in range by wrapping each
+-- result with one of these (see fixNumber in expressions.go):
+-- signed ones sign-extend from their width, unsigned
+-- ones are masked to it.
+
+-- A change of signedness keeps the bits, as in Go:
+-- uint64(int8(-1)) is 1<<64 - 1. A cast, unlike a call
+-- of the ctype, always reinterprets.
+
+__castInt64 = function(x) return ffi.cast("int64_t", x) end
+__castUint64 = function(x) return ffi.cast("uint64_t", x) end
+
+__wrapInt8 = function(x) return __bit.arshift(__bit.lshift(__castInt64(x), 56), 56) end
+__wrapInt16 = function(x) return __bit.arshift(__bit.lshift(__castInt64(x), 48), 48) end
+__wrapInt32 = function(x) return __bit.arshift(__bit.lshift(__castInt64(x), 32), 32) end
+
+__wrapUint8 = function(x) return __bit.band(__castUint64(x), 0xFFULL) end
+__wrapUint16 = function(x) return __bit.band(__castUint64(x), 0xFFFFULL) end
+__wrapUint32 = function(x) return __bit.band(__castUint64(x), 0xFFFFFFFFULL) end
+
+-- Division and remainder truncate toward zero, as in C,
+-- and LuaJIT gives MinInt64 / -1 == MinInt64 and
+-- MinInt64 % -1 == 0, as Go does. Plain Lua numbers
+-- would divide as floats, so convert them first.
+
+__divInt64 = function(x, y)
+   x, y = int64(x), int64(y)
+   if y == 0 then error("integer divide by zero") end
+   return x / y
+end
+
+__remInt64 = function(x, y)
+   x, y = int64(x), int64(y)
+   if y == 0 then error("integer divide by zero") end
+   return x % y
+end
+
+__divUint64 = function(x, y)
+   x, y = uint64(x), uint64(y)
+   if y == 0 then error("integer divide by zero") end
+   return x / y
+end
+
+__remUint64 = function(x, y)
+   x, y = uint64(x), uint64(y)
+   if y == 0 then error("integer divide by zero") end
+   return x % y
+end
+
+-- The bit library shifts by the count mod 64, but in Go
+-- a count of 64 or more shifts every bit out. The count
+-- is compared as unsigned, so a uint64 count of 1<<63
+-- shifts everything out too; a signed count has been
+-- checked by __shiftCount first.
+
+__shiftCount = function(n)
+   if n < 0 then error("negative shift amount") end
+   return n
+end
+
+__shlInt64 = function(x, n)
+   n = __castUint64(n)
+   if n >= 64 then return 0LL end
+   return __bit.lshift(int64(x), tonumber(n))
+end
+
+__shlUint64 = function(x, n)
+   n = __castUint64(n)
+   if n >= 64 then return 0ULL end
+   return __bit.lshift(uint64(x), tonumber(n))
+end
+
+__shrInt64 = function(x, n)
+   n = __castUint64(n)
+   if n >= 64 then n = 63 end
+   return __bit.arshift(int64(x), tonumber(n))
+end
+
+__shrUint64 = function(x, n)
+   n = __castUint64(n)
+   if n >= 64 then return 0ULL end
+   return __bit.rshift(uint64(x), tonumber(n))
+end
+
 --MinInt64: -9223372036854775808
 --MaxInt64: 9223372036854775807
 
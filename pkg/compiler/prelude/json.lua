@@ -0,0 +1,40 @@
+-- json.lua: encoding/json for values of types
+-- declared at the REPL.
+--
+-- Marshal and Encoder.Encode need nothing extra:
+-- luar hands the native package real Go values built
+-- by __gijitTypeToGoType, tags and all. But Unmarshal
+-- and Decoder.Decode write through a pointer, and a
+-- gijit pointer isn't one Go can follow. So when
+-- "encoding/json" is imported, import.go wraps the
+-- Luar package table with __jsonShadow(), which
+-- routes those two through __gijitGoDecode in
+-- tsys.lua. Everything else falls through to the
+-- native package.
+
+__jsonShadow = function(native)
+   local js = setmetatable({}, {__index = native})
+
+   js.Unmarshal = function(data, v)
+      return __gijitGoDecode(function(ptr)
+            return native.Unmarshal(data, ptr)
+      end, v)
+   end
+
+   -- the Decoder is a Lua table in front of the
+   -- native *json.Decoder, overriding just Decode.
+   js.NewDecoder = function(r)
+      local dec = native.NewDecoder(r)
+      local d = {}
+      d.Decode = function(v)
+         return __gijitGoDecode(function(ptr)
+               return dec.Decode(ptr)
+         end, v)
+      end
+      return setmetatable(d, {__index = function(t, k)
+                                 return dec[k]
+      end})
+   end
+
+   return js
+end
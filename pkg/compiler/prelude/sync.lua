@@ -0,0 +1,245 @@
+-- sync.lua: sync.Mutex, RWMutex, WaitGroup, Once and
+-- Cond for goroutines, integrated with the coroutine
+-- scheduler in chan.lua.
+--
+-- Goroutines are coroutines on one OS thread, so the
+-- native sync types would block the whole LuaJIT thread
+-- on a contended Lock or a Wait, and nothing could ever
+-- wake it. So when "sync" is imported, import.go wraps
+-- the Luar package table with __syncShadow(), which
+-- makes the zero values of these types (and NewCond)
+-- Lua tables whose waiters park on channels instead.
+-- Map and Pool never block and stay native.
+--
+-- As with the Timers in timer.lua, methods are closures
+-- on each value, to match the `mu.Lock()` code the
+-- translator emits for binary package types. They ignore
+-- any self argument, so calls through a sync.Locker work
+-- too. See natives/src/sync for the gopherjs versions
+-- these follow.
+
+-- a queue of parked coroutines, one channel each.
+local function newWaitQueue()
+   return {}
+end
+
+-- park the running goroutine until woken. reason is
+-- its state in :goroutines, e.g. sync.Mutex.Lock.
+local function park(q, reason)
+   local ch = __task.Channel:new(1)
+   table.insert(q, ch)
+   __task.wait_on(ch, reason)
+end
+
+-- wake the goroutine parked longest, if any.
+local function wakeOne(q)
+   local ch = table.remove(q, 1)
+   if ch ~= nil then
+      ch:send(true)
+      return true
+   end
+   return false
+end
+
+local function wakeAll(q)
+   while wakeOne(q) do
+   end
+end
+
+local function newMutex()
+   local m = {__name = "sync.Mutex", locked = false}
+   local q = newWaitQueue()
+
+   m.Lock = function()
+      while m.locked do
+         park(q, "sync.Mutex.Lock")
+      end
+      m.locked = true
+   end
+
+   m.TryLock = function()
+      if m.locked then
+         return false
+      end
+      m.locked = true
+      return true
+   end
+
+   m.Unlock = function()
+      if not m.locked then
+         panic("sync: unlock of unlocked mutex")
+      end
+      m.locked = false
+      wakeOne(q)
+   end
+   return m
+end
+
+-- waiting writers keep new readers out, as in Go,
+-- so that a stream of readers can't starve them.
+local function newRWMutex()
+   local rw = {__name = "sync.RWMutex", readers = 0, writer = false, writersWaiting = 0}
+   local rq = newWaitQueue()
+   local wq = newWaitQueue()
+
+   rw.RLock = function()
+      while rw.writer or rw.writersWaiting > 0 do
+         park(rq, "sync.RWMutex.RLock")
+      end
+      rw.readers = rw.readers + 1
+   end
+
+   rw.TryRLock = function()
+      if rw.writer or rw.writersWaiting > 0 then
+         return false
+      end
+      rw.readers = rw.readers + 1
+      return true
+   end
+
+   rw.RUnlock = function()
+      if rw.readers <= 0 then
+         panic("sync: RUnlock of unlocked RWMutex")
+      end
+      rw.readers = rw.readers - 1
+      if rw.readers == 0 then
+         wakeOne(wq)
+      end
+   end
+
+   rw.Lock = function()
+      rw.writersWaiting = rw.writersWaiting + 1
+      while rw.writer or rw.readers > 0 do
+         park(wq, "sync.RWMutex.Lock")
+      end
+      rw.writersWaiting = rw.writersWaiting - 1
+      rw.writer = true
+   end
+
+   rw.TryLock = function()
+      if rw.writer or rw.readers > 0 then
+         return false
+      end
+      rw.writer = true
+      return true
+   end
+
+   rw.Unlock = function()
+      if not rw.writer then
+         panic("sync: Unlock of unlocked RWMutex")
+      end
+      rw.writer = false
+      wakeAll(rq)
+      wakeOne(wq)
+   end
+
+   rw.RLocker = function()
+      return {Lock = rw.RLock, Unlock = rw.RUnlock}
+   end
+   return rw
+end
+
+local function newWaitGroup()
+   local wg = {__name = "sync.WaitGroup", counter = 0}
+   local q = newWaitQueue()
+
+   wg.Add = function(delta)
+      wg.counter = wg.counter + delta
+      if wg.counter < 0 then
+         panic("sync: negative WaitGroup counter")
+      end
+      if wg.counter == 0 then
+         wakeAll(q)
+      end
+   end
+
+   wg.Done = function()
+      wg.Add(-1)
+   end
+
+   wg.Wait = function()
+      while wg.counter > 0 do
+         park(q, "sync.WaitGroup.Wait")
+      end
+   end
+   return wg
+end
+
+-- a second Do waits for the first to finish; f counts
+-- as done even if it panics, as in Go.
+local function newOnce()
+   local o = {__name = "sync.Once", done = false}
+   local m = newMutex()
+
+   o.Do = function(f)
+      if o.done then
+         return
+      end
+      m.Lock()
+      if o.done then
+         m.Unlock()
+         return
+      end
+      local ok, err = pcall(f)
+      o.done = true
+      m.Unlock()
+      if not ok then
+         error(err, 0)
+      end
+   end
+   return o
+end
+
+local function newCond(l)
+   local c = {__name = "sync.Cond", L = l}
+   local q = newWaitQueue()
+
+   c.Wait = function()
+      local ch = __task.Channel:new(1)
+      table.insert(q, ch)
+      c.L:Unlock()
+      __task.wait_on(ch, "sync.Cond.Wait")
+      c.L:Lock()
+   end
+
+   c.Signal = function()
+      wakeOne(q)
+   end
+
+   c.Broadcast = function()
+      wakeAll(q)
+   end
+   return c
+end
+
+-- like the native_Go_struct_type_wrapper tables the
+-- shadow package's InitLua writes, but building ours.
+local function syncType(name, new)
+   local t = {
+      id = 0,
+      __name = "gijit_sync_type",
+      __str = name,
+      exported = true,
+      __call = function(t, src)
+         return new()
+      end,
+   }
+   return setmetatable(t, t)
+end
+
+__syncShadow = function(native)
+   local sy = setmetatable({}, {__index = native})
+
+   __type__.sync = __type__.sync or {}
+   __type__.sync.Mutex     = syncType("Mutex", newMutex)
+   __type__.sync.RWMutex   = syncType("RWMutex", newRWMutex)
+   __type__.sync.WaitGroup = syncType("WaitGroup", newWaitGroup)
+   __type__.sync.Once      = syncType("Once", newOnce)
+   __type__.sync.Cond      = syncType("Cond", function() return newCond(nil) end)
+
+   sy.NewCond = function(l)
+      return newCond(l)
+   end
+
+   return sy
+end
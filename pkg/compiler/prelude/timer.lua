@@ -0,0 +1,144 @@
+-- timer.lua: time.Sleep, time.After, time.Tick,
+-- time.AfterFunc, time.NewTimer and time.NewTicker,
+-- integrated with the coroutine scheduler in chan.lua.
+--
+-- The shadowed binary "time" package would block the
+-- whole LuaJIT thread on time.Sleep, and its channels
+-- are native Go channels that our select cannot wait
+-- on. So when "time" is imported, import.go wraps the
+-- Luar package table with __timeShadow(), which
+-- overrides just these functions. Everything else
+-- (time.Now, time.Since, ...) falls through to the
+-- native package.
+--
+-- Timers and Tickers are returned as Lua tables with
+-- a C channel field and Stop/Reset closures, to match
+-- the `t.C` and `t.Stop()` code the translator emits
+-- for *time.Timer and *time.Ticker.
+
+local function timeElemType()
+   if __type__ ~= nil and __type__.time ~= nil then
+      return __type__.time.Time
+   end
+   return nil
+end
+
+-- like a Go runtime timer send: drop the value
+-- if the buffer is full, never block.
+local function sendNoBlock(ch, v)
+   if ch._buf:len() < ch._buf.size then
+      ch:send(v)
+   end
+end
+
+__timeShadow = function(native)
+   local tm = setmetatable({}, {__index = native})
+
+   local now = function()
+      return native.Now()
+   end
+
+   -- newTimer is shared by NewTimer and AfterFunc.
+   -- fire is called with the timer when it goes off.
+   local newTimer = function(d, fire)
+      local t = {}
+      local f = function(nowNs)
+         t.__pending = nil
+         fire(t)
+      end
+      t.__pending = __task.timer_add(__abs_now() + d, f)
+
+      t.Stop = function()
+         local h = t.__pending
+         t.__pending = nil
+         if h == nil then
+            return false
+         end
+         return __task.timer_remove(h)
+      end
+
+      t.Reset = function(d2)
+         local active = t.Stop()
+         t.__pending = __task.timer_add(__abs_now() + d2, f)
+         return active
+      end
+      return t
+   end
+
+   tm.NewTimer = function(d)
+      local t = newTimer(d, function(t)
+            sendNoBlock(t.C, now())
+      end)
+      t.C = __task.Channel:new(1, timeElemType())
+      return t
+   end
+
+   tm.After = function(d)
+      return tm.NewTimer(d).C
+   end
+
+   tm.AfterFunc = function(d, f)
+      return newTimer(d, function(t)
+            __task.spawn(f, {})
+      end)
+   end
+
+   tm.NewTicker = function(d)
+      if d <= 0 then
+         error("non-positive interval for NewTicker")
+      end
+      local t = {C = __task.Channel:new(1, timeElemType()), __period = d}
+      local f
+      f = function(nowNs)
+         sendNoBlock(t.C, now())
+         -- keep to the original schedule, unless
+         -- we have fallen behind it.
+         local nxt = t.__pending.when + t.__period
+         if nxt <= nowNs then
+            nxt = nowNs + t.__period
+         end
+         t.__pending = __task.timer_add(nxt, f)
+      end
+      t.__pending = __task.timer_add(__abs_now() + d, f)
+
+      t.Stop = function()
+         local h = t.__pending
+         t.__pending = nil
+         if h ~= nil then
+            __task.timer_remove(h)
+         end
+      end
+
+      t.Reset = function(d2)
+         if d2 <= 0 then
+            error("non-positive interval for Ticker.Reset")
+         end
+         t.Stop()
+         t.__period = d2
+         t.__pending = __task.timer_add(__abs_now() + d2, f)
+      end
+      return t
+   end
+
+   tm.Tick = function(d)
+      if d <= 0 then
+         return nil
+      end
+      return tm.NewTicker(d).C
+   end
+
+   tm.Sleep = function(d)
+      if d <= 0 then
+         return
+      end
+      local co, isMain = coroutine.running()
+      if isMain then
+         -- nothing to yield to; block the thread.
+         native.Sleep(d)
+         return
+      end
+      __task.wait_on(tm.After(d), "sleep")
+   end
+
+   return tm
+end
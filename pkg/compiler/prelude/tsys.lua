@@ -1279,12 +1279,52 @@ __synthesizeMethods = function()
    __methodSynthesizers = nil;
 end;
 
+-- __ifaceKeyFor gives the map key for a value held
+-- in an interface. Interfaces hold their values
+-- unboxed, so the dynamic type comes from the value.
 __ifaceKeyFor = function(x)
-   if x == __ifaceNil then
+   if x == nil or x == __ifaceNil then
       return 'nil';
    end
-   local c = x.constructor;
-   return c.__str .. '__' .. c.keyFor(x.__val);
+   local ty = type(x)
+   if ty == "cdata" then
+      -- 3LL, 3ULL and 3+0i stay distinct.
+      return "c__" .. tostring(x);
+   elseif ty == "number" then
+      return "f__" .. __floatKey(x);
+   elseif ty == "string" then
+      return "s__" .. x;
+   elseif ty == "boolean" then
+      return "b__" .. tostring(x);
+   elseif ty == "table" then
+      local c = x.__typ
+      if c == nil then
+         -- channels and the like: identity.
+         return "id__" .. __idKey(x);
+      end
+      if c.keyFor == nil or c.comparable == false then
+         error("runtime error: hash of unhashable type " .. c.__str);
+      end
+      return c.__str .. '__' .. c.keyFor(x);
+   end
+   error("runtime error: hash of unhashable type " .. ty);
+end;
+
+-- __keyPart escapes one element of a composite key,
+-- so the "_" between elements can't be forged.
+__keyPart = function(s)
+   s = string.gsub(tostring(s), "\\", "\\\\")
+   s = string.gsub(s, "_", "\\u")
+   return s
+end;
+
+-- __mapKeyFor is the string under which a map
+-- with keys of type keyType stores key k.
+__mapKeyFor = function(keyType, k)
+   if k == nil then
+      return 'nil';
+   end
+   return tostring(keyType.keyFor(k));
 end;
 
 __identity = function(x) return x; end;
@@ -1348,6 +1388,21 @@ __newType = function(size, kind, str, named, pkg, exported, constructor)
       end;
       typ.wrapped = true;
       typ.keyFor = function(x) return tostring(x); end;
+      if kind ~= __kindBool then
+         -- a Lua number, say from #s, and the cdata
+         -- 3LL must find the same map entry.
+         local unsigned = kind >= __kindUint
+         typ.keyFor = function(x)
+            if type(x) == "number" then
+               if unsigned then
+                  x = uint64(x)
+               else
+                  x = int64(x)
+               end
+            end
+            return tostring(x)
+         end;
+      end
 
    elseif kind == __kindString then
       
@@ -1521,9 +1576,11 @@ __newType = function(size, kind, str, named, pkg, exported, constructor)
          typ.len = len;
          typ.comparable = elem.comparable;
          typ.keyFor = function(x)
-            return __mapAndJoinStrings("_", x, function(e)
-                                          return string.gsub(tostring(elem.keyFor(e)), "\\", "\\\\")
-            end)
+            local parts = {}
+            for i = 0, len-1 do
+               parts[i+1] = __keyPart(elem.keyFor(x.__array[i]))
+            end
+            return table.concat(parts, "_")
          end
          typ.copy = function(dst, src)
             __copyArray(dst, src, 0, 0, #src, elem);
@@ -1611,21 +1668,23 @@ __newType = function(size, kind, str, named, pkg, exported, constructor)
          local this={};
          this.__typ = typ         
          this.__val = {}; --no meta names, so clean. No accidental collisions.
-
-         local kff = typ.elem.keyFor
+         -- __goKeys holds the Go key for each string in __val,
+         -- so range can give back the keys we were given.
+         this.__goKeys = {};
          this.nilKeyStored = false
-         
+
          local len=0
          for k, e in pairs(entries) do
-            if k == nil then
-               this.nilKeyStored = true
-               this.nilValue = e or __intentionalNilValue
-            else 
-               local key = tostring(kff(k)) -- must be a string!
-               --print("using key ", key, " for k=", k)
-               this.__val[key] = e or __intentionalNilValue;
+            local key = __mapKeyFor(typ.key, k) -- must be a string!
+            --print("using key ", key, " for k=", k)
+            if this.__val[key] == nil then
+               len=len+1;
             end
-            len=len+1;
+            if e == nil then
+               e = __intentionalNilValue
+            end
+            this.__val[key] = e;
+            this.__goKeys[key] = k;
          end
          
          this.len=len;
@@ -1815,7 +1874,7 @@ __newType = function(size, kind, str, named, pkg, exported, constructor)
          typ.keyFor = function(x)
             local val = x.__val;
             return __mapAndJoinStrings("_", fields, function(f)
-                                          return string.gsub(tostring(f.__typ.keyFor(val[f.__prop])), "\\", "\\\\")
+                                          return __keyPart(f.__typ.keyFor(val[f.__prop]))
             end)
          end;
          typ.copy = function(dst, src)
@@ -2183,6 +2242,7 @@ end;
 
 -- return the (un-named so as to be interoperable)
 -- reflect Type that corresponds to tsys type 'typ'.
+-- The result is cached on typ as typ.__goType.
 function __gijitTypeToGoType(typ)
    --print("__gijitTypeToGoType() called with typ:")
    --__st(typ)
@@ -2194,7 +2254,26 @@ function __gijitTypeToGoType(typ)
       -- basic type, return straight away
       return rtyp
    end
-   -- recurse to construct un-named/compound types
+   if typ.__goType ~= nil then
+      return typ.__goType
+   end
+   if typ.__goTypeBusy then
+      -- reflect can't build a type that refers to itself.
+      error("recursive type " .. typ.__str .. " has no Go equivalent")
+   end
+   typ.__goTypeBusy = true
+   local ok, rt = pcall(__gijitTypeToGoTypeUncached, typ)
+   typ.__goTypeBusy = nil
+   if not ok then
+      error(rt, 0)
+   end
+   typ.__goType = rt
+   return rt
+end
+
+-- recurse to construct un-named/compound types
+function __gijitTypeToGoTypeUncached(typ)
+   local kind = typ.kind
 
    if kind ==  __kindPtr then
       return reflect.PtrTo(__gijitTypeToGoType(typ.elem))
@@ -2217,24 +2296,234 @@ function __gijitTypeToGoType(typ)
    elseif kind ==  __kindMap then 
       return reflect.MapOf(__gijitTypeToGoType(typ.key), __gijitTypeToGoType(typ.elem))
 
-      --- TODO: finish the rest
-
    elseif kind ==  __kindFunc then 
-      error("TODO: finish func types")
+      local ins = {}
+      for i, p in ipairs(typ.params) do
+         ins[i] = __gijitTypeToGoType(p)
+      end
+      local outs = {}
+      for i, r in ipairs(typ.results) do
+         outs[i] = __gijitTypeToGoType(r)
+      end
+      return reflect.FuncOf(ins, outs, typ.variadic)
 
    elseif kind ==  __kindInterface then 
-      error("TODO: finish interface types")
+      -- reflect can't make new interface types, so
+      -- any interface but error becomes interface{}.
+      if typ == __error then
+         return __rtyp.__error
+      end
+      return __rtyp.__emptyInterface
             
    elseif kind ==  __kindStruct then
-      error("TODO: finish struct types")
-      --for i,fld in ipairs(fields) do
-         -- this[fld.__prop] = args[i] or fld.__typ.zero();
-      --end         
+      local props = {}
+      local types = {}
+      local tags = {}
+      local embedded = {}
+      for i,fld in ipairs(typ.fields) do
+         props[i] = fld.__prop
+         types[i] = __gijitTypeToGoType(fld.__typ)
+         tags[i] = fld.__tag or ""
+         embedded[i] = (fld.__anonymous == true) and fld.__typ.kind == __kindStruct
+      end
+      return __gijitStructOf(props, types, tags, embedded)
+
    else
       error("invalid kind: " .. tostring(kind));
    end  
 end
 
+-- __gijitValueGoType gives luar the Go type to
+-- use when a gijit value crosses into Go as an
+-- interface{}. It returns nil for values that
+-- are not gijit structs, arrays, slices or maps,
+-- and for those with no Go equivalent.
+function __gijitValueGoType(v)
+   if type(v) ~= "table" then
+      return nil
+   end
+   local typ = rawget(v, "__typ")
+   if type(typ) ~= "table" then
+      return nil
+   end
+   local kind = typ.kind
+   if kind ~= __kindStruct and kind ~= __kindArray and
+      kind ~= __kindSlice and kind ~= __kindMap then
+      return nil
+   end
+   local ok, rt = pcall(__gijitTypeToGoType, typ)
+   if not ok then
+      return nil
+   end
+   return rt
+end
+
+-- __gijitHasMethod is true when v, a gijit value,
+-- has a method called name. adapter.go asks before
+-- passing v to Go as a Go interface.
+function __gijitHasMethod(v, name)
+   if type(v) ~= "table" or type(rawget(v, "__typ")) ~= "table" then
+      return false
+   end
+   local ok, m = pcall(function() return v[name] end)
+   return ok and type(m) == "function"
+end
+
+-- __gijitGoIfaceTypes maps the Go types that
+-- encoding/json and friends put in an interface{}
+-- to the gijit types we rebuild them as.
+local __gijitGoIfaceTypes = nil
+
+local function __gijitGoIfaceType(name)
+   if __gijitGoIfaceTypes == nil then
+      __gijitGoIfaceTypes = {
+         ["bool"] = __type__.bool,
+         ["float64"] = __type__.float64,
+         ["string"] = __type__.string,
+         ["[]interface {}"] = __sliceType(__type__.emptyInterface),
+         ["map[string]interface {}"] = __mapType(__type__.string, __type__.emptyInterface),
+      }
+   end
+   return __gijitGoIfaceTypes[name]
+end
+
+-- __gijitFromGo is the inverse of __gijitTypeToGoType:
+-- it builds the gijit value of type typ from rv, a
+-- reflect.Value of the Go type __gijitTypeToGoType(typ).
+-- Values of other Go types inside an interface come
+-- back as they are, as luar proxies.
+function __gijitFromGo(typ, rv)
+   local kind = typ.kind
+
+   if kind == __kindBool then
+      return rv.Bool()
+
+   elseif kind >= __kindInt and kind <= __kindInt64 then
+      return rv.Int()
+
+   elseif kind >= __kindUint and kind <= __kindUintptr then
+      return rv.Uint()
+
+   elseif kind == __kindFloat32 or kind == __kindFloat64 then
+      return rv.Float()
+
+   elseif kind == __kindString then
+      return rv.String()
+
+   elseif kind == __kindPtr then
+      if rv.IsNil() then
+         return typ.zero()
+      end
+      local elem = __gijitFromGo(typ.elem, rv.Elem())
+      local ek = typ.elem.kind
+      if ek == __kindStruct or ek == __kindArray then
+         -- &p is p itself for structs and arrays.
+         return elem
+      end
+      return __newDataPointer(elem, typ)
+
+   elseif kind == __kindSlice then
+      if rv.IsNil() then
+         return typ.zero()
+      end
+      local a = {}
+      for i = 0, tonumber(rv.Len()) - 1 do
+         a[i] = __gijitFromGo(typ.elem, rv.Index(i))
+      end
+      return typ(a)
+
+   elseif kind == __kindArray then
+      local a = {}
+      for i = 0, typ.len - 1 do
+         a[i] = __gijitFromGo(typ.elem, rv.Index(i))
+      end
+      return typ(a)
+
+   elseif kind == __kindMap then
+      if rv.IsNil() then
+         return typ.zero()
+      end
+      local m = typ({})
+      local keys = rv.MapKeys()
+      for i = 0, tonumber(rv.Len()) - 1 do
+         local k = keys[i]
+         m('set', __gijitFromGo(typ.key, k), __gijitFromGo(typ.elem, rv.MapIndex(k)))
+      end
+      return m
+
+   elseif kind == __kindStruct then
+      local s = typ.zero()
+      for i, fld in ipairs(typ.fields) do
+         s[fld.__prop] = __gijitFromGo(fld.__typ, rv.Field(i-1))
+      end
+      return s
+
+   elseif kind == __kindInterface then
+      if rv.IsNil() then
+         return typ.zero()
+      end
+      local e = rv.Elem()
+      local dyn = __gijitGoIfaceType(e.Type().String())
+      if dyn == nil then
+         return e.Interface()
+      end
+      return __gijitFromGo(dyn, e)
+   end
+
+   -- chans and funcs stay Go values.
+   return rv.Interface()
+end
+
+-- __gijitGoDecode lets a Go decoder fill the gijit
+-- value v, which is the target of a pointer: v is a
+-- pointer, or a struct or array (as &p is p for those).
+-- decode is called with a real Go pointer, pre-loaded
+-- with v's current contents, and whatever it leaves
+-- there is copied back into v. Values that aren't
+-- gijit pointers go to decode unchanged.
+function __gijitGoDecode(decode, v)
+   if type(v) ~= "table" then
+      return decode(v)
+   end
+   local typ = rawget(v, "__typ")
+   if type(typ) ~= "table" then
+      return decode(v)
+   end
+
+   local target, cur, store
+   if typ.kind == __kindPtr then
+      target = typ.elem
+      cur = v.__get()
+      store = function(x) v.__set(x) end
+   elseif typ.kind == __kindStruct then
+      target = typ
+      cur = v
+      store = function(x) typ.copy(v, x) end
+   elseif typ.kind == __kindArray then
+      target = typ
+      cur = v
+      store = function(x)
+         for i = 0, typ.len - 1 do
+            v.__array[i] = x.__array[i]
+         end
+      end
+   else
+      return decode(v)
+   end
+
+   local goTyp = __gijitTypeToGoType(target)
+   local p = reflect.New(goTyp)
+   if cur ~= nil and cur ~= target.zero() and target.kind ~= __kindInterface then
+      -- like Go, keep what the input doesn't mention.
+      pcall(function()
+            p.Elem().Set(reflect.ValueOf(cur).Convert(goTyp))
+      end)
+   end
+   local err = decode(p.Interface())
+   store(__gijitFromGo(target, p.Elem()))
+   return err
+end
+
 __theNilChan={__name="__theNilChan"}
 
 function __Chan(elem, capacity, elemReflectType)
@@ -2448,8 +2737,45 @@ end;
 -- stored as map value in place of nil, so
 -- we can recognized stored nil values in maps.
 __intentionalNilValue = {}
-__start_new_map_iter_after_nil={}
-__map_iter_with_nil_key={}
+
+-- __mapRange iterates over the map t for a Go range
+-- statement. Unlike pairs(), it visits a stored nil key
+-- too: the first value it gives is only a cursor, never
+-- nil until the end, and the Go key and value follow it.
+function __mapRange(t)
+   if t == nil then
+      return function() return nil end
+   end
+   if getmetatable(t) ~= __valueMapMT then
+      -- a native Go map.
+      local f, s, k = pairs(t)
+      return function()
+         local v
+         k, v = f(s, k)
+         if k == nil then
+            return nil
+         end
+         return k, k, v
+      end
+   end
+   local nilDone = not t.nilKeyStored
+   local ks
+   return function()
+      if not nilDone then
+         nilDone = true
+         return __intentionalNilValue, nil, t.nilValue
+      end
+      local v
+      ks, v = next(t.__val, ks)
+      if ks == nil then
+         return nil
+      end
+      if v == __intentionalNilValue then
+         v = nil
+      end
+      return ks, t.__goKeys[ks], v
+   end
+end
 
 __valueMapMT = {
    __name = "__valueMapMT",
@@ -2472,8 +2798,8 @@ __valueMapMT = {
 
       -- invar: k is not nil
 
-      local ks = t.__typ.elem.keyFor(k)
-      --local ks = tostring(k)
+      local ks = __mapKeyFor(t.keyType, k)
+      t.__goKeys[ks] = k
       if v ~= nil then
          if t.__val[ks] == nil then
             -- new key
@@ -2515,8 +2841,7 @@ __valueMapMT = {
       
       -- k is not nil.
 
-      local ks = t.__typ.elem.keyFor(k)      
-      --local ks = tostring(k)
+      local ks = __mapKeyFor(t.keyType, k)
       
       local val = t.__val[ks]
       if val == __intentionalNilValue then
@@ -2541,7 +2866,7 @@ __valueMapMT = {
       end
       
       for i, v in pairs(r) do
-         s = s .. kquo..tostring(i)..kquo.. ": " .. vquo..tostring(v) ..vquo.. ", "
+         s = s .. kquo..tostring(t.__goKeys[i])..kquo.. ": " .. vquo..tostring(v) ..vquo.. ", "
       end
       return s .. "}"
    end,
@@ -2556,51 +2881,28 @@ __valueMapMT = {
      --print("map __pairs called!")
       -- this makes a map work in a for k,v in pairs() do loop.
 
-      -- Iterator function takes the table and an index and returns the next index and associated value
-      -- or nil to end iteration
-
-      local function stateless_iter(t, k)
-         
-         --print("map stateless_iter called, with k ="..tostring(k))
-         --__st(k, "k")
-
-         --if k == __map_iter_with_nil_key then
-         --   t.nilKeyStored
-         --end
-         if k == __start_new_map_iter_after_nil then
-            return next(t.__val, nil)
+      -- __val is keyed by the strings from keyFor, so we
+      -- walk it with our own cursor and hand back the Go
+      -- key that each string stands for, from __goKeys.
+      --
+      -- A stored nil key can't be handed back here, since
+      -- a nil first value ends a Lua for loop; a Go range
+      -- statement uses __mapRange, which visits it.
+
+      local ks
+      local function iter()
+         local v
+         ks, v = next(t.__val, ks)
+         if ks == nil then
+            return nil
          end
-         
-         --  Implement your own key,value selection logic in place of next
-         
-         -- we need to account for the fact that a nil key can be stored.
-         -- a) how to start
-         -- b) how to replace the __intentionalNilValue value and keep going
-         -- c) how to finish.
-         -- d) how to turn stored string keys back into their key type. Actually
-         --    this is handled by front end generated code, so we don't need
-         --    to worry about that here.
-
-         -- early termination is a problem: if we have nil key in our map,
-         -- then the Lua for loop will terminate. So we'll miss that key.
-         -- So I think we need to write our while loop.
-         
-         -- for now, just get a basic iteration going.
-         
-         local nextKey
-         local nextVal
-         nextKey, nextVal = next(t.__val, k)
-        --print("map iter, back from next(t.__val, k), is")
-
-         --__st(nextKey, "nextKey")
-         --__st(nextVal, "nextVal")
-         
-         return nextKey, nextVal
+         if v == __intentionalNilValue then
+            v = nil
+         end
+         return t.__goKeys[ks], v
       end
-      
-      -- Return an iterator function, the table, starting point (nil
-      -- tells next to start).
-      return stateless_iter, t, nil
+
+      return iter, t, nil
    end,
 
    __call = function(t, ...)
@@ -2612,8 +2914,15 @@ __valueMapMT = {
       -- we use __call('get', k, zeroVal) instead of __index
       -- so that we can return multiple values
       -- to match Go's "a, ok := mymap[k]" call.
+      --
+      -- Likewise __call('set', k, v) for m[k] = v, as
+      -- __newindex is skipped for keys like "len" that
+      -- are fields of the map itself.
       
-      if oper == "get" then
+      if oper == "set" then
+         __valueMapMT.__newindex(t, k, zeroVal)
+         
+      elseif oper == "get" then
 
          --print("get called for key", k)
          if k == nil then
@@ -2626,7 +2935,7 @@ __valueMapMT = {
          end
          
          -- k is not nil.
-         local ks = tostring(k)      
+         local ks = __mapKeyFor(t.keyType, k)
          local val = t.__val[ks]
          if val == __intentionalNilValue then
             --print("val is the __intentionalNilValue")
@@ -2659,7 +2968,7 @@ __valueMapMT = {
             return
          end
 
-         local ks = tostring(k)           
+         local ks = __mapKeyFor(t.keyType, k)
          if t.__val[ks] == nil then
             -- key not present
             return
@@ -2667,6 +2976,7 @@ __valueMapMT = {
          
          -- key present and key is not nil
          t.__val[ks] = nil
+         t.__goKeys[ks] = nil
          t.len = t.len - 1
          
          --print("len at end of delete is ", t.len)
@@ -3256,10 +3566,24 @@ __throw = function(err)  error(err); end;
 
 __lastEvalErr = ""
 
+-- __gijitGoTrace, when registered, rewrites the Lua
+-- locations in an error or traceback as Go file:line.
+__goTrace = function(s)
+   if __gijitGoTrace ~= nil and type(s) == "string" then
+      return __gijitGoTrace(s)
+   end
+   return s
+end
+
 __errHandlerForEval = function(err)
+   if getmetatable(err) == __recovMT then
+      -- an unrecovered panic; the Go side wants a string.
+      err = "panic: "..tostring(err[1])
+   end
+   err = __goTrace(err)
    __lastEvalErr = err
    print("error! __errHandlerForEval sees err =", err)
-   print(debug.traceback(coroutine.running(), err))
+   print(__goTrace(debug.traceback(coroutine.running(), err)))
    return err
 end
 
@@ -3308,6 +3632,44 @@ __gijitMainEval = function(code)
    
 end
 
+-- for txn.go: __gijitTxSave saves the globals and types
+-- an input is about to (re)define, and __gijitTxRestore
+-- puts them back if it fails, or at :undo. The latest
+-- keep saves are kept.
+local txSaved = {}
+
+__gijitTxSave = function(vars, typs, keep)
+   local s = {vars = {}, typs = {}}
+   for _, name in ipairs(vars) do
+      s.vars[name] = {rawget(_G, name)}
+   end
+   for _, name in ipairs(typs) do
+      s.typs[name] = {__type__[name]}
+   end
+   table.insert(txSaved, s)
+   while #txSaved > keep do
+      table.remove(txSaved, 1)
+   end
+end
+
+__gijitTxRestore = function()
+   local s = table.remove(txSaved)
+   if s == nil then
+      return
+   end
+   for name, v in pairs(s.vars) do
+      rawset(_G, name, v[1])
+   end
+   for name, v in pairs(s.typs) do
+      __type__[name] = v[1]
+   end
+end
+
+-- after :forget, there is no going back.
+__gijitTxClear = function()
+   txSaved = {}
+end
+
 __eval_next_count = 1
 
 __eval = function(code)
@@ -3329,6 +3691,7 @@ __eval = function(code)
    -- eval coro yields, so that it can effect
    -- the actual receive during chan receive <- ops.
 
+   __task.clear_interrupt()
    __task_ready(__gijitEvalCoro)
    __task.resume_scheduler()
 
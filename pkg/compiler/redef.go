@@ -0,0 +1,180 @@
+package compiler
+
+// redef.go: redefinition-aware dependency tracking.
+//
+// Redefining a func, method or type at the prompt is allowed,
+// but declarations made earlier were translated against the
+// old definition, and their Lua is stale. So for each top-level
+// declaration we record its Go source and the package-level
+// names its Lua used (the Decl.DceDeps). When an input redefines
+// a name, IncrState.Tr re-translates the funcs, methods and types
+// that depended on it, and warns about those it cannot fix: ones
+// that no longer type-check, and variables whose values were
+// computed with the old definition.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gijit/gi/pkg/types"
+)
+
+// topDecl is one top-level declaration made at the prompt.
+type topDecl struct {
+	key  string // unique, e.g. "main.f", "(*main.S).Hi", "main.S"
+	name string // as it appears in DceDeps: "main.f", "main.Hi___tilde_", "main.S"
+	kind string // "func", "method", "type" or "var"
+	src  string // Go source to re-translate; empty for vars.
+	typ  string // for vars, the type; a new value of the same type is no redefinition.
+	deps []string
+	seq  int // the input that declared it
+
+	staleVia string // set by dependents(): the redefined name it used.
+}
+
+type declGraph struct {
+	decls     map[string]*topDecl
+	seq       int
+	redefined []string // names redefined by the latest input
+
+	journals []*srcJournal // recording for txns; see txn.go.
+}
+
+func newDeclGraph() *declGraph {
+	return &declGraph{decls: make(map[string]*topDecl)}
+}
+
+// startInput is called at the top of each IncrementallyCompile.
+func (g *declGraph) startInput() {
+	g.seq++
+	g.redefined = nil
+}
+
+func (g *declGraph) define(td *topDecl) {
+	td.seq = g.seq
+	if old, ok := g.decls[td.key]; ok && old.seq != g.seq {
+		if td.kind != "var" || old.typ != td.typ {
+			g.redefined = append(g.redefined, td.name)
+		}
+	}
+	g.recordDecl(td.key)
+	g.decls[td.key] = td
+}
+
+func (g *declGraph) defineVar(o *types.Var, deps []string) {
+	qual := o.Pkg().Path() + "." + o.Name()
+	g.define(&topDecl{
+		key:  qual,
+		name: qual,
+		kind: "var",
+		typ:  o.Type().String(),
+		deps: deps,
+	})
+}
+
+// dependents returns, in the order they were declared, the
+// declarations from earlier inputs that used a name redefined
+// by the latest input, either directly, or through a func,
+// method or type that will itself be re-translated.
+func (g *declGraph) dependents() []*topDecl {
+	return g.usersOf(g.redefined, g.seq)
+}
+
+// usersOf returns, in the order they were declared, the
+// declarations that used one of names, directly or through
+// a func, method or type, other than those from input seq.
+func (g *declGraph) usersOf(names []string, seq int) []*topDecl {
+	if len(names) == 0 {
+		return nil
+	}
+	stale := make(map[string]bool)
+	for _, nm := range names {
+		stale[nm] = true
+	}
+	hit := make(map[*topDecl]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, td := range g.decls {
+			if td.seq == seq || hit[td] {
+				continue
+			}
+			for _, dep := range td.deps {
+				if stale[dep] && dep != td.name {
+					hit[td] = true
+					td.staleVia = dep
+					if td.kind != "var" {
+						stale[td.name] = true
+					}
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	out := make([]*topDecl, 0, len(hit))
+	for td := range hit {
+		out = append(out, td)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].seq != out[j].seq {
+			return out[i].seq < out[j].seq
+		}
+		return out[i].key < out[j].key
+	})
+	return out
+}
+
+// shortDeclName turns "main.Hi___tilde_" into "Hi", for messages.
+func shortDeclName(name string) string {
+	name = strings.TrimSuffix(name, "___tilde_")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// retranslateDependents is called by TrWithPrepend after an input
+// that redefined something. It returns the Lua for the dependents
+// that re-translated cleanly, and prints a warning for the rest.
+func (tr *IncrState) retranslateDependents() []byte {
+	if tr.retranslating || tr.CurPkg.Arch == nil || tr.CurPkg.Arch.DeclGraph == nil {
+		return nil
+	}
+	res, _ := tr.retranslate(tr.CurPkg.Arch.DeclGraph.dependents())
+	return res
+}
+
+// retranslate re-translates the funcs, methods and types
+// among deps, returning the Lua for those that still
+// compile and the names of those that don't. It prints a
+// warning for each of the latter, and for each var.
+func (tr *IncrState) retranslate(deps []*topDecl) (res []byte, broken []string) {
+	if len(deps) == 0 {
+		return nil, nil
+	}
+	tr.retranslating = true
+	defer func() { tr.retranslating = false }()
+
+	for _, td := range deps {
+		via := shortDeclName(td.staleVia)
+		if td.kind == "var" {
+			fmt.Printf("warning: '%s' was computed with the old definition of '%s'. Assign it again to update it.\n", shortDeclName(td.name), via)
+			continue
+		}
+		by, err := tr.TrWithPrepend([]byte(td.src), false)
+		if err != nil {
+			what := shortDeclName(td.key)
+			if td.kind == "method" {
+				what = strings.Replace(td.key, tr.CurPkg.pack.ImportPath+".", "", -1)
+			}
+			fmt.Printf("warning: '%s' uses the redefined '%s' and no longer compiles, so it keeps the old definition: %v\n", what, via, err)
+			broken = append(broken, what)
+			continue
+		}
+		pp("re-translated %s '%s' after redefinition of '%s'", td.kind, td.key, via)
+		res = append(res, by...)
+		res = append(res, '\n')
+	}
+	return res, broken
+}
@@ -68,3 +68,35 @@ func Test301RedefinitionOfStruct(t *testing.T) {
 		//fmt.Printf("\n pass j=%v complete.\n", j)
 	})
 }
+
+func Test302RedefinitionRetranslatesDependents(t *testing.T) {
+
+	cv.Convey(`redefining a type should re-translate the funcs and methods that used the old definition; an incompatible redefinition should leave dependents alone with a warning, not an error`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		for _, src := range []string{
+			`type S struct{ X int }`,
+			`func (s S) Get() int { return s.X }`,
+			`func mk() S { return S{X: 1} }`,
+			`type S struct{ X, Y int }`,
+			`s := mk()`,
+			`got := s.Get()`,
+			`func g() int { return 2 }`,
+			`func f() int { return g() * 3 }`,
+			`func g() string { return "changed" }`,
+		} {
+			translation, err := inc.Tr([]byte(src))
+			panicOn(err)
+			LuaRunAndReport(vm, string(translation))
+		}
+		LuaMustInt64(vm, "got", 1)
+
+		deps := inc.CurPkg.Arch.DeclGraph.dependents()
+		cv.So(len(deps), cv.ShouldEqual, 1)
+		cv.So(deps[0].key, cv.ShouldEqual, "main.f")
+	})
+}
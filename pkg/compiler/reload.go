@@ -0,0 +1,310 @@
+package compiler
+
+// reload.go: hot reload of packages imported from source.
+//
+// Once the REPL starts watching, the Session's fsnotify
+// watcher follows the directory of every package built from
+// source, and a change to a file there marks the package
+// stale. Before the next input, the REPL calls Reload on the
+// stale packages. That rebuilds each one, and every source
+// package importing it, runs their Lua again, which re-runs
+// each __init and rebinds each package table, points the
+// imports made at the prompt at the new packages, and
+// re-translates the definitions made at the prompt that
+// used them, reporting those that no longer type-check.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// watchDir notes where the source of pkg lives, and
+// watches it if watching is on.
+func (s *Session) watchDir(pkg *PackageData) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	s.pkgDirs[pkg.ImportPath] = pkg.Dir
+	if s.Watcher != nil {
+		if err := s.Watcher.Add(pkg.Dir); err != nil {
+			s.options.PrintError("cannot watch %s: %v\n", pkg.Dir, err)
+		}
+	}
+}
+
+// StartWatching watches the directories of the packages
+// built from source so far, and of those built later.
+func (s *Session) StartWatching() error {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.Watcher != nil {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range s.pkgDirs {
+		if err := w.Add(dir); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	s.Watcher = w
+	go s.watch(w)
+	return nil
+}
+
+// StopWatching stops the watching that StartWatching
+// began. Packages already marked stale stay so.
+func (s *Session) StopWatching() {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.Watcher != nil {
+		s.Watcher.Close()
+		s.Watcher = nil
+	}
+}
+
+// Watching lists the packages whose source is watched,
+// or nil if watching is off.
+func (s *Session) Watching() []string {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.Watcher == nil {
+		return nil
+	}
+	paths := []string{}
+	for path := range s.pkgDirs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (s *Session) watch(w *fsnotify.Watcher) {
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 || filepath.Base(ev.Name)[0] == '.' {
+				continue
+			}
+			if !strings.HasSuffix(ev.Name, ".go") && !strings.HasSuffix(ev.Name, ".inc.gijit") {
+				continue
+			}
+			dir := filepath.Dir(ev.Name)
+			s.watchMu.Lock()
+			for path, pkgDir := range s.pkgDirs {
+				if pkgDir == dir {
+					s.stale[path] = true
+				}
+			}
+			s.watchMu.Unlock()
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			s.options.PrintError("watcher error: %s\n", err.Error())
+		}
+	}
+}
+
+// StaleImports returns, and forgets, the packages whose
+// files changed since the last call.
+func (s *Session) StaleImports() []string {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	var paths []string
+	for path := range s.stale {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	s.stale = make(map[string]bool)
+	return paths
+}
+
+// Reload rebuilds the source packages paths, and those
+// that import them, and translates what will bring the
+// Lua up to date: the packages' code, followed by the
+// definitions made at the prompt that used them. Those of
+// the definitions that no longer type-check are listed in
+// broken, and keep their old translation. If a package no
+// longer builds, nothing changes and err says why.
+func (tr *IncrState) Reload(paths []string) (lua []byte, reloaded, broken []string, err error) {
+	s := tr.Session
+
+	affected := make(map[string]bool)
+	for _, path := range paths {
+		if _, ok := s.archiveKeys[path]; ok {
+			affected[path] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for path := range s.archiveKeys {
+			a := s.Archives[path]
+			if affected[path] || a == nil {
+				continue
+			}
+			for _, imp := range a.Imports {
+				if affected[imp] {
+					affected[path] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	// dependencies first.
+	seen := make(map[string]bool)
+	var visit func(path string)
+	visit = func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		for _, imp := range s.Archives[path].Imports {
+			if affected[imp] {
+				visit(imp)
+			}
+		}
+		reloaded = append(reloaded, path)
+	}
+	var sorted []string
+	for path := range affected {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	for _, path := range sorted {
+		visit(path)
+	}
+	if len(reloaded) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	old := make(map[string]*Archive)
+	for _, path := range reloaded {
+		old[path] = s.Archives[path]
+		s.forgetArchive(path)
+	}
+	var code bytes.Buffer
+	for _, path := range reloaded {
+		err := tr.rebuild(path, &code)
+		if err != nil {
+			for _, p := range reloaded {
+				s.forgetArchive(p)
+				s.Archives[p] = old[p]
+				s.Types[p] = old[p].Pkg
+				tr.CurPkg.importContext.Packages[p] = old[p].Pkg
+			}
+			return nil, nil, nil, fmt.Errorf("reload of %s: %v", path, err)
+		}
+	}
+	for _, path := range reloaded {
+		// their code is in lua now; an import at the
+		// prompt mustn't run it a second time.
+		s.Archives[path].NewCodeText = nil
+	}
+
+	a := tr.CurPkg.Arch
+	if a == nil {
+		return code.Bytes(), reloaded, nil, nil
+	}
+	repoint := make(map[*types.Package]*types.Package)
+	for _, path := range reloaded {
+		repoint[old[path].Pkg] = s.Archives[path].Pkg
+	}
+	scope := a.Pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if pn, ok := obj.(*types.PkgName); ok {
+			if pkg := repoint[pn.Imported()]; pkg != nil {
+				scope.Replace(types.NewPkgName(pn.Pos(), pn.Pkg(), pn.Name(), pkg))
+			}
+		} else if pkg := repoint[obj.Pkg()]; pkg != nil {
+			// dot-imported.
+			if o := pkg.Scope().Lookup(name); o != nil {
+				scope.Replace(o)
+			} else {
+				scope.DeleteByName(name)
+			}
+		}
+	}
+	imports := a.Pkg.Imports()
+	for i, imp := range imports {
+		if pkg := repoint[imp]; pkg != nil {
+			imports[i] = pkg
+		}
+	}
+	a.Pkg.SetImports(imports)
+
+	var used []string
+	for _, td := range a.DeclGraph.decls {
+		for _, dep := range td.deps {
+			for _, path := range reloaded {
+				if strings.HasPrefix(dep, path+".") {
+					used = append(used, dep)
+				}
+			}
+		}
+	}
+	retrans, broken := tr.retranslate(a.DeclGraph.usersOf(used, -1))
+	retrans, _ = stripLineMarkers(retrans)
+	code.Write(retrans)
+
+	// the saves :undo would restore predate the reload.
+	tr.undo = nil
+	code.WriteString("\n__gijitTxClear();\n")
+	return code.Bytes(), reloaded, broken, nil
+}
+
+// rebuild builds the package path from source and writes
+// its Lua to w.
+func (tr *IncrState) rebuild(path string, w io.Writer) (err error) {
+	defer func() {
+		// the type checker panics on errors.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	a, err := tr.ImportSourcePackage(path, "", 0)
+	if err != nil {
+		return err
+	}
+	return WriteProgramCode([]*Archive{a}, &SourceMapFilter{Writer: w}, false)
+}
+
+// ReloadAndRun reloads paths as Reload does, and runs the
+// Lua it translates on lvm.
+func (tr *IncrState) ReloadAndRun(lvm *LuaVm, paths []string) (reloaded, broken []string, err error) {
+	lua, reloaded, broken, err := tr.Reload(paths)
+	if err != nil || len(lua) == 0 {
+		return nil, nil, err
+	}
+	err = LuaRun(lvm, string(lua), true)
+	if err == nil {
+		err = lastEvalError(lvm)
+	}
+	return reloaded, broken, err
+}
+
+// forgetArchive drops the archive built for path, so
+// that the next import of path builds it afresh.
+func (s *Session) forgetArchive(path string) {
+	if a := s.Archives[path]; a != nil && a.Pkg != nil {
+		delete(s.Archives, a.Pkg.Path())
+	}
+	delete(s.Archives, path)
+	delete(s.Types, path)
+	delete(s.ic.CurPkg.importContext.Packages, path)
+}
@@ -0,0 +1,106 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test2700ReloadEditedSourceImport(t *testing.T) {
+
+	cv.Convey(`editing a source-imported package should reload it, and the packages importing it, so that funcs declared at the prompt call the new code; those that no longer type-check should be reported`, t, func() {
+
+		dir, err := ioutil.TempDir("", "gijit-reload")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+		writeFixture(dir, map[string]string{
+			"app/go.mod": "module example.com/app\n",
+			"app/shout/shout.go": `package shout
+
+import "example.com/app/bang"
+
+func Shout(s string) string { return bang.Bang(s) }
+`,
+			"app/bang/bang.go": "package bang\n\nfunc Bang(s string) string { return s + \"!\" }\n",
+		})
+
+		wd, err := os.Getwd()
+		panicOn(err)
+		defer os.Chdir(wd)
+		panicOn(os.Chdir(filepath.Join(dir, "app")))
+
+		vm, cfg := newVmAnywhere()
+		defer vm.Close()
+		inc := NewIncrState(vm, cfg)
+		inc.Session.options.CacheDir = filepath.Join(dir, "cache")
+		panicOn(inc.Session.StartWatching())
+		defer inc.Session.StopWatching()
+
+		run := func(src string) {
+			translation, err := inc.Tr([]byte(src))
+			panicOn(err)
+			LuaRunAndReport(vm, string(translation))
+			cv.So(lastEvalError(vm), cv.ShouldBeNil)
+		}
+		run(`
+import "example.com/app/shout"
+func yell(s string) string { return shout.Shout(s) }
+a := yell("hi")
+`)
+		LuaMustString(vm, "a", "hi!")
+		cv.So(inc.Session.Watching(), cv.ShouldResemble, []string{"example.com/app/bang", "example.com/app/shout"})
+
+		writeFixture(dir, map[string]string{
+			"app/bang/bang.go": "package bang\n\nfunc Bang(s string) string { return s + \"!!\" }\n",
+		})
+		var stale []string
+		for i := 0; i < 100 && len(stale) == 0; i++ {
+			time.Sleep(50 * time.Millisecond)
+			stale = inc.Session.StaleImports()
+		}
+		cv.So(stale, cv.ShouldResemble, []string{"example.com/app/bang"})
+
+		reloaded, broken, err := inc.ReloadAndRun(vm, stale)
+		panicOn(err)
+		cv.So(reloaded, cv.ShouldResemble, []string{"example.com/app/bang", "example.com/app/shout"})
+		cv.So(broken, cv.ShouldBeEmpty)
+		run(`b := yell("hi")`)
+		LuaMustString(vm, "b", "hi!!")
+
+		// a change of signature breaks yell, which keeps
+		// its old translation, but the new Shout is what
+		// the prompt type-checks against.
+		writeFixture(dir, map[string]string{
+			"app/shout/shout.go": `package shout
+
+import "example.com/app/bang"
+
+func Shout(n int) (s string) {
+	for i := 0; i < n; i++ {
+		s = bang.Bang(s)
+	}
+	return
+}
+`,
+		})
+		reloaded, broken, err = inc.ReloadAndRun(vm, []string{"example.com/app/shout"})
+		panicOn(err)
+		cv.So(reloaded, cv.ShouldResemble, []string{"example.com/app/shout"})
+		cv.So(broken, cv.ShouldResemble, []string{"yell"})
+		run(`c := shout.Shout(3)`)
+		LuaMustString(vm, "c", "!!!!!!")
+
+		// a package that no longer builds is left as it was.
+		writeFixture(dir, map[string]string{
+			"app/bang/bang.go": "package bang\n\nfunc Bang(s string) string { return s + 1 }\n",
+		})
+		_, _, err = inc.ReloadAndRun(vm, []string{"example.com/app/bang"})
+		cv.So(err, cv.ShouldNotBeNil)
+		run(`d := shout.Shout(1)`)
+		LuaMustString(vm, "d", "!!")
+	})
+}
@@ -18,7 +18,14 @@ type GIConfig struct {
 	NoPrelude      bool
 	NoLuar         bool
 
+	// Preempt has loops yield to the goroutine scheduler
+	// now and then, so that a goroutine in a tight loop
+	// can't starve the others; see __preempt in chan.lua.
+	Preempt bool
+
 	Dev bool // dev mode, don't use statically cached prelude
+
+	KernelConnFile string // run as a Jupyter kernel, see repl_kernel.go
 }
 
 var defaultTestMode bool // set to true by init() for tests, in repl_test.go.
@@ -39,6 +46,8 @@ func (c *GIConfig) DefineFlags(fs *flag.FlagSet) {
 	fs.BoolVar(&c.IsTestMode, "t", false, "load test mode functions and types")
 	fs.BoolVar(&c.NoLiner, "no-liner", false, "turn off liner, e.g. under emacs")
 	fs.BoolVar(&c.NoPrelude, "np", false, "no prelude; skip loading the prelude .lua files and Luar. implies -r raw mode too.")
+	fs.StringVar(&c.KernelConnFile, "kernel", "", "run as a Jupyter kernel, using the given Jupyter connection file.")
+	fs.BoolVar(&c.Preempt, "preempt", false, "preempt goroutines: loops yield to the scheduler periodically, so one goroutine can't starve the others.")
 	fs.BoolVar(&c.Dev, "d", false, "dev mode uses the pkg/compiler/prelude/*.lua files, skipping the statically cached pkg/compiler/prelude_static.go version.")
 }
 
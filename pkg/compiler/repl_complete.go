@@ -0,0 +1,299 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// replMetaCommands are offered when TAB is pressed
+// after a ':' at the start of the line. Keep in
+// sync with the :help text in repl_luajit.go.
+var replMetaCommands = []string{
+	":?", ":ast", ":cache", ":clear", ":do", ":doc", ":forget", ":g", ":gls", ":glst",
+	":go", ":goroutines", ":h", ":help", ":info", ":load", ":ls", ":lst", ":noast",
+	":prelude", ":q", ":r", ":reload", ":reset", ":rm", ":save",
+	":source", ":stacks", ":timeout", ":type", ":undo", ":v", ":vv", ":watch",
+}
+
+var goKeywords = []string{
+	"break", "case", "chan", "const", "continue", "default",
+	"defer", "else", "fallthrough", "for", "func", "go", "goto",
+	"if", "import", "interface", "map", "package", "range",
+	"return", "select", "struct", "switch", "type", "var",
+}
+
+// complete is the liner WordCompleter for the Repl.
+// liner gives pos in runes.
+func (r *Repl) complete(line string, pos int) (head string, completions []string, tail string) {
+	return completeLine(r.inc, line, byteOffset(line, pos))
+}
+
+// byteOffset returns the byte offset in s of the rune
+// at index pos, or len(s) if s has no more runes.
+func byteOffset(s string, pos int) int {
+	n := 0
+	for i := range s {
+		if n == pos {
+			return i
+		}
+		n++
+	}
+	return len(s)
+}
+
+// completeLine completes the word to the left of pos in
+// line. Go identifiers, package members, struct fields and
+// methods come from the incremental type checker's view of
+// the current package, so anything declared at the prompt
+// is offered. A leading ':' completes meta-commands, and the
+// arguments of :source, :do, :load and :save complete file paths.
+func completeLine(inc *IncrState, line string, pos int) (head string, completions []string, tail string) {
+	if pos > len(line) {
+		pos = len(line)
+	}
+	before, tail := line[:pos], line[pos:]
+
+	trimmed := strings.TrimLeft(before, " \t")
+	if strings.HasPrefix(trimmed, ":") {
+		return completeMeta(before, tail)
+	}
+
+	// find the start of the dotted word under the cursor.
+	start := len(before)
+	for start > 0 {
+		c := before[start-1]
+		if c == '.' || c == '_' || isAsciiAlnum(c) {
+			start--
+			continue
+		}
+		break
+	}
+	head = before[:start]
+	word := before[start:]
+
+	dot := strings.LastIndex(word, ".")
+	if dot < 0 {
+		return head, prefixed("", identCompletions(inc), word), tail
+	}
+	x, sel := word[:dot], word[dot+1:]
+	if x == "" {
+		return head, nil, tail
+	}
+	return head, prefixed(x+".", memberCompletions(inc, x), sel), tail
+}
+
+func isAsciiAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// prefixed returns pre+name for each name that starts
+// with partial, sorted and without duplicates.
+func prefixed(pre string, names []string, partial string) (out []string) {
+	seen := make(map[string]bool)
+	for _, nm := range names {
+		if strings.HasPrefix(nm, partial) && !seen[nm] {
+			seen[nm] = true
+			out = append(out, pre+nm)
+		}
+	}
+	sort.Strings(out)
+	return
+}
+
+// curTypesPkg returns the package being built at the
+// prompt, or nil before anything has been compiled.
+func curTypesPkg(inc *IncrState) *types.Package {
+	if inc == nil || inc.CurPkg == nil || inc.CurPkg.Arch == nil {
+		return nil
+	}
+	return inc.CurPkg.Arch.Pkg
+}
+
+func identCompletions(inc *IncrState) []string {
+	names := append([]string{}, goKeywords...)
+	names = append(names, types.Universe.Names()...)
+	if pkg := curTypesPkg(inc); pkg != nil {
+		names = append(names, pkg.Scope().Names()...)
+	}
+	return names
+}
+
+// memberCompletions lists what may follow "x.": the exported
+// members of package x, or else the fields and methods of
+// the type of expression x.
+func memberCompletions(inc *IncrState, x string) []string {
+	pkg := curTypesPkg(inc)
+	if pkg == nil {
+		return nil
+	}
+
+	if imp := importedPackage(pkg, x); imp != nil {
+		var names []string
+		for _, nm := range imp.Scope().Names() {
+			if ast.IsExported(nm) {
+				names = append(names, nm)
+			}
+		}
+		return names
+	}
+
+	tv, err := types.Eval(inc.CurPkg.fileSet, pkg, token.NoPos, x)
+	if err != nil || tv.Type == nil {
+		return nil
+	}
+	visible := func(obj types.Object) bool {
+		return obj.Exported() || obj.Pkg() == pkg
+	}
+
+	var names []string
+	typ := tv.Type
+	if !tv.IsType() {
+		if st, ok := derefStruct(typ); ok {
+			collectFields(st, visible, &names, 0)
+		}
+	}
+
+	// variables are addressable, so pointer-receiver
+	// methods are callable on them too.
+	ms := typ
+	if _, isPtr := typ.(*types.Pointer); !isPtr && !types.IsInterface(typ) {
+		ms = types.NewPointer(typ)
+	}
+	mset := types.NewMethodSet(ms)
+	for i := 0; i < mset.Len(); i++ {
+		obj := mset.At(i).Obj()
+		if visible(obj) {
+			names = append(names, obj.Name())
+		}
+	}
+	return names
+}
+
+// importedPackage returns the package x names, if it is
+// an import visible at the prompt.
+func importedPackage(pkg *types.Package, x string) *types.Package {
+	if strings.Contains(x, ".") {
+		return nil
+	}
+	if obj := pkg.Scope().Lookup(x); obj != nil {
+		if pn, ok := obj.(*types.PkgName); ok {
+			return pn.Imported()
+		}
+		// a variable or type by that name shadows any import.
+		return nil
+	}
+	for _, imp := range pkg.Imports() {
+		if imp.Name() == x {
+			return imp
+		}
+	}
+	return nil
+}
+
+func derefStruct(typ types.Type) (*types.Struct, bool) {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	return st, ok
+}
+
+// collectFields adds the fields of st, including those
+// promoted from embedded structs.
+func collectFields(st *types.Struct, visible func(types.Object) bool, names *[]string, depth int) {
+	if depth > 8 {
+		return
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if visible(f) {
+			*names = append(*names, f.Name())
+		}
+		if f.Anonymous() {
+			if emb, ok := derefStruct(f.Type()); ok {
+				collectFields(emb, visible, names, depth+1)
+			}
+		}
+	}
+}
+
+// completeMeta completes ':' commands, and the file
+// path arguments of :source, :do, :load and :save.
+func completeMeta(before, tail string) (string, []string, string) {
+	trimmed := strings.TrimLeft(before, " \t")
+	lead := before[:len(before)-len(trimmed)]
+
+	sp := strings.IndexAny(trimmed, " \t")
+	if sp < 0 {
+		return lead, prefixed("", replMetaCommands, trimmed), tail
+	}
+	cmd := trimmed[:sp]
+	var exts []string
+	switch cmd {
+	case ":source", ":load", ":save":
+		exts = []string{".go"}
+	case ":do":
+		exts = []string{".lua"}
+	default:
+		return before, nil, tail
+	}
+
+	// the args are a comma separated list;
+	// complete the last one.
+	argStart := strings.LastIndex(before, ",") + 1
+	if argStart <= len(lead)+sp {
+		argStart = len(lead) + sp
+	}
+	for argStart < len(before) && (before[argStart] == ' ' || before[argStart] == '\t') {
+		argStart++
+	}
+	return before[:argStart], completePath(before[argStart:], exts), tail
+}
+
+// completePath lists the directories, and the files
+// ending in one of exts, that start with partial.
+func completePath(partial string, exts []string) (out []string) {
+	dir, base := filepath.Split(partial)
+	lookIn := dir
+	if lookIn == "" {
+		lookIn = "."
+	}
+	if strings.HasPrefix(lookIn, "~/") {
+		home := os.Getenv("HOME")
+		if home != "" {
+			lookIn = home + lookIn[1:]
+		}
+	}
+	fis, err := ioutil.ReadDir(lookIn)
+	if err != nil {
+		return nil
+	}
+	for _, fi := range fis {
+		nm := fi.Name()
+		if !strings.HasPrefix(nm, base) {
+			continue
+		}
+		if strings.HasPrefix(nm, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if fi.IsDir() {
+			out = append(out, dir+nm+string(os.PathSeparator))
+			continue
+		}
+		for _, ext := range exts {
+			if strings.HasSuffix(nm, ext) {
+				out = append(out, dir+nm)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return
+}
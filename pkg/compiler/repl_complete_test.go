@@ -0,0 +1,56 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1300TabCompletionFromTypeChecker(t *testing.T) {
+
+	cv.Convey(`tab completion should offer package members, struct fields, methods, top-level names and meta-commands`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		code := `
+import "fmt"
+type Inner struct { Depth int }
+type Pt struct { Inner; Alpha int; beta string }
+func (p *Pt) Get() int { return p.Alpha }
+var pt Pt
+var ptrToPt = &pt
+`
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+
+		head, comp, tail := completeLine(inc, "x := fmt.Sprin", 14)
+		cv.So(head, cv.ShouldEqual, "x := ")
+		cv.So(comp, cv.ShouldResemble, []string{"fmt.Sprint", "fmt.Sprintf", "fmt.Sprintln"})
+		cv.So(tail, cv.ShouldEqual, "")
+
+		_, comp, _ = completeLine(inc, "pt.", 3)
+		cv.So(comp, cv.ShouldResemble, []string{"pt.Alpha", "pt.Depth", "pt.Get", "pt.Inner", "pt.beta"})
+
+		_, comp, _ = completeLine(inc, "ptrToPt.G", 9)
+		cv.So(comp, cv.ShouldResemble, []string{"ptrToPt.Get"})
+
+		_, comp, tail = completeLine(inc, "ptrT + 1", 4)
+		cv.So(comp, cv.ShouldResemble, []string{"ptrToPt"})
+		cv.So(tail, cv.ShouldEqual, " + 1")
+
+		_, comp, _ = completeLine(inc, ":so", 3)
+		cv.So(comp, cv.ShouldResemble, []string{":source"})
+
+		// liner counts the cursor position in runes.
+		r := &Repl{inc: inc}
+		line := `s := "héllo, 世界" + ptrT + "!"`
+		head, comp, tail = r.complete(line, len([]rune(`s := "héllo, 世界" + ptrT`)))
+		cv.So(head, cv.ShouldEqual, `s := "héllo, 世界" + `)
+		cv.So(comp, cv.ShouldResemble, []string{"ptrToPt"})
+		cv.So(tail, cv.ShouldEqual, ` + "!"`)
+	})
+}
@@ -0,0 +1,292 @@
+package compiler
+
+// repl_info.go: the :type, :doc and :info commands,
+// the Go analog of GHCi's :t and :i.
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/doc"
+	"github.com/gijit/gi/pkg/gostd/build"
+	"github.com/gijit/gi/pkg/parser"
+	"github.com/gijit/gi/pkg/printer"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// typeOfExpr type-checks expr against the current
+// package, as it stands at the prompt, and describes
+// its type: `:type expr`.
+func typeOfExpr(inc *IncrState, expr string) (string, error) {
+	pkg := curTypesPkg(inc)
+	var fset *token.FileSet
+	if pkg != nil {
+		fset = inc.CurPkg.fileSet
+	}
+	// with a nil pkg, Eval uses the Universe scope.
+	tv, err := types.Eval(fset, pkg, token.NoPos, expr)
+	if err != nil {
+		return "", err
+	}
+	qual := types.RelativeTo(pkg)
+	switch {
+	case tv.IsType():
+		return fmt.Sprintf("%s is a type: %s", expr, types.TypeString(tv.Type.Underlying(), qual)), nil
+	case tv.Value != nil:
+		return fmt.Sprintf("%s : %s = %s", expr, types.TypeString(tv.Type, qual), tv.Value), nil
+	}
+	return fmt.Sprintf("%s : %s", expr, types.TypeString(tv.Type, qual)), nil
+}
+
+// lookupObject finds name, or pkg.name, as seen from the prompt.
+func lookupObject(inc *IncrState, name string) (types.Object, error) {
+	pkg := curTypesPkg(inc)
+	parts := strings.Split(name, ".")
+	switch len(parts) {
+	case 1:
+		if pkg != nil {
+			if obj := pkg.Scope().Lookup(name); obj != nil {
+				return obj, nil
+			}
+		}
+		if obj := types.Universe.Lookup(name); obj != nil {
+			return obj, nil
+		}
+	case 2:
+		if pkg != nil {
+			if imp := importedPackage(pkg, parts[0]); imp != nil {
+				if obj := imp.Scope().Lookup(parts[1]); obj != nil {
+					return obj, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("'%s' not found; is it declared, or its package imported?", name)
+}
+
+// infoOf describes name: for a type, its underlying
+// type, fields and method set: `:info T`.
+func infoOf(inc *IncrState, name string) (string, error) {
+	obj, err := lookupObject(inc, name)
+	if err != nil {
+		return "", err
+	}
+	pkg := curTypesPkg(inc)
+	qual := types.RelativeTo(pkg)
+
+	var b bytes.Buffer
+	tn, isType := obj.(*types.TypeName)
+	if !isType {
+		fmt.Fprintf(&b, "%s\n", types.ObjectString(obj, qual))
+	} else {
+		T := tn.Type()
+		under := T.Underlying()
+		fmt.Fprintf(&b, "type %s %s\n", tn.Name(), kindOfType(under))
+
+		if st, ok := under.(*types.Struct); ok && st.NumFields() > 0 {
+			fmt.Fprintf(&b, "fields:\n")
+			for i := 0; i < st.NumFields(); i++ {
+				f := st.Field(i)
+				emb := ""
+				if f.Anonymous() {
+					emb = " (embedded)"
+				}
+				fmt.Fprintf(&b, "    %s %s%s\n", f.Name(), types.TypeString(f.Type(), qual), emb)
+			}
+		} else if _, isNamed := T.(*types.Named); isNamed {
+			fmt.Fprintf(&b, "underlying type: %s\n", types.TypeString(under, qual))
+		}
+
+		ms := T
+		if !types.IsInterface(T) {
+			ms = types.NewPointer(T)
+		}
+		mset := types.NewMethodSet(ms)
+		if mset.Len() > 0 {
+			fmt.Fprintf(&b, "methods:\n")
+			for i := 0; i < mset.Len(); i++ {
+				fmt.Fprintf(&b, "    %s\n", types.ObjectString(mset.At(i).Obj(), qual))
+			}
+		}
+	}
+
+	switch {
+	case obj.Pkg() == nil:
+		fmt.Fprintf(&b, "predeclared.\n")
+	case obj.Pkg() == pkg:
+		fmt.Fprintf(&b, "declared at the gi prompt.\n")
+	default:
+		fmt.Fprintf(&b, "declared in package %q.\n", omitAnyShadowPathPrefix(obj.Pkg().Path(), false))
+	}
+	return b.String(), nil
+}
+
+func kindOfType(under types.Type) string {
+	switch under.(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	case *types.Signature:
+		return "func"
+	case *types.Map:
+		return "map"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	case *types.Chan:
+		return "chan"
+	case *types.Pointer:
+		return "pointer"
+	case *types.Basic:
+		return under.String()
+	}
+	return ""
+}
+
+// docOf returns the documentation for pkg, pkg.Name or
+// pkg.Type.Method, from the package's Go source: `:doc`.
+// pkg may be an imported package's name, or an import path.
+func docOf(inc *IncrState, name string) (string, error) {
+	// split off the import path, which may itself have dots.
+	pkgPart, rest := name, ""
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		pkgPart, rest = name[:slash+1+dot], name[slash+1+dot+1:]
+	}
+
+	pkgPath := pkgPart
+	if pkg := curTypesPkg(inc); pkg != nil {
+		if imp := importedPackage(pkg, pkgPart); imp != nil {
+			pkgPath = omitAnyShadowPathPrefix(imp.Path(), false)
+		} else if obj := pkg.Scope().Lookup(pkgPart); obj != nil {
+			return fmt.Sprintf("%s\n(declared at the gi prompt, so there is no doc comment.)\n",
+				types.ObjectString(obj, types.RelativeTo(pkg))), nil
+		}
+	}
+
+	dpkg, fset, err := loadDocPackage(pkgPath)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	show := func(decl interface{}, comment string) {
+		printer.Fprint(&b, fset, decl)
+		fmt.Fprintf(&b, "\n")
+		if comment != "" {
+			doc.ToText(&b, comment, "    ", "\t", 72)
+		}
+	}
+
+	var sel []string
+	if rest != "" {
+		sel = strings.Split(rest, ".")
+	}
+	switch len(sel) {
+	case 0:
+		fmt.Fprintf(&b, "package %s // import %q\n\n", dpkg.Name, pkgPath)
+		doc.ToText(&b, dpkg.Doc, "", "\t", 72)
+		return b.String(), nil
+
+	case 1:
+		for _, f := range dpkg.Funcs {
+			if f.Name == sel[0] {
+				show(f.Decl, f.Doc)
+				return b.String(), nil
+			}
+		}
+		for _, t := range dpkg.Types {
+			if t.Name == sel[0] {
+				show(t.Decl, t.Doc)
+				for _, f := range t.Funcs {
+					fmt.Fprintf(&b, "\n")
+					show(f.Decl, "")
+				}
+				for _, m := range t.Methods {
+					fmt.Fprintf(&b, "\n")
+					show(m.Decl, "")
+				}
+				return b.String(), nil
+			}
+			for _, f := range t.Funcs {
+				// constructors are grouped under their type.
+				if f.Name == sel[0] {
+					show(f.Decl, f.Doc)
+					return b.String(), nil
+				}
+			}
+		}
+		for _, vals := range [][]*doc.Value{dpkg.Consts, dpkg.Vars} {
+			for _, v := range vals {
+				for _, nm := range v.Names {
+					if nm == sel[0] {
+						show(v.Decl, v.Doc)
+						return b.String(), nil
+					}
+				}
+			}
+		}
+
+	case 2:
+		for _, t := range dpkg.Types {
+			if t.Name != sel[0] {
+				continue
+			}
+			for _, m := range t.Methods {
+				if m.Name == sel[1] {
+					show(m.Decl, m.Doc)
+					return b.String(), nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("no documentation found for '%s' in package %q", rest, pkgPath)
+}
+
+// loadDocPackage parses the non-test Go source of
+// the package at importPath, with comments.
+func loadDocPackage(importPath string) (*doc.Package, *token.FileSet, error) {
+	bp, err := build.Import(importPath, ".", 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not find source for package %q: %v", importPath, err)
+	}
+	fset := token.NewFileSet()
+	apkg := &ast.Package{
+		Name:  bp.Name,
+		Files: make(map[string]*ast.File),
+	}
+	for _, fn := range bp.GoFiles {
+		path := filepath.Join(bp.Dir, fn)
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		apkg.Files[path] = f
+	}
+	return doc.New(apkg, importPath, 0), fset, nil
+}
+
+// showInfoCmd runs :type, :doc or :info for the Repl.
+func (r *Repl) showInfoCmd(cmd, arg string) {
+	var s string
+	var err error
+	switch cmd {
+	case ":type":
+		s, err = typeOfExpr(r.inc, arg)
+	case ":doc":
+		s, err = docOf(r.inc, arg)
+	case ":info":
+		s, err = infoOf(r.inc, arg)
+	}
+	if err != nil {
+		fmt.Printf("%s error: %v\n", cmd, err)
+		return
+	}
+	fmt.Printf("%s\n", strings.TrimRight(s, "\n"))
+}
@@ -0,0 +1,55 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1301TypeDocAndInfoCommands(t *testing.T) {
+
+	cv.Convey(`:type, :doc and :info should describe expressions, imported functions and types declared at the prompt`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		code := `
+import "strings"
+type Pt struct { X, Y int }
+func (p *Pt) Sum() int { return p.X + p.Y }
+var pt Pt
+`
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+
+		s, err := typeOfExpr(inc, "pt.Sum")
+		panicOn(err)
+		cv.So(s, cv.ShouldEqual, "pt.Sum : func() int")
+
+		s, err = typeOfExpr(inc, "1 << 3")
+		panicOn(err)
+		cv.So(s, cv.ShouldEqual, "1 << 3 : untyped int = 8")
+
+		_, err = typeOfExpr(inc, "notDeclared + 1")
+		cv.So(err, cv.ShouldNotBeNil)
+
+		s, err = infoOf(inc, "Pt")
+		panicOn(err)
+		cv.So(s, cv.ShouldContainSubstring, "type Pt struct")
+		cv.So(s, cv.ShouldContainSubstring, "X int")
+		cv.So(s, cv.ShouldContainSubstring, "func (*Pt).Sum() int")
+		cv.So(s, cv.ShouldContainSubstring, "declared at the gi prompt")
+
+		s, err = infoOf(inc, "strings.Repeat")
+		panicOn(err)
+		cv.So(s, cv.ShouldContainSubstring, `declared in package "strings"`)
+
+		s, err = docOf(inc, "strings.Repeat")
+		panicOn(err)
+		cv.So(s, cv.ShouldContainSubstring, "func Repeat(s string, count int) string")
+		cv.So(s, cv.ShouldContainSubstring, "Repeat returns a new string")
+	})
+}
@@ -0,0 +1,492 @@
+package compiler
+
+// repl_kernel.go: `gi -kernel <connection-file>` runs gijit
+// as a Jupyter kernel, speaking version 5.3 of the Jupyter
+// messaging protocol over ZMTP (see zmtp.go).
+//
+// To install it, put a kernel.json like this in, e.g.,
+// ~/.local/share/jupyter/kernels/gijit/kernel.json
+//
+//   {
+//    "argv": ["gi", "-kernel", "{connection_file}"],
+//    "display_name": "Go (gijit)",
+//    "language": "go"
+//   }
+//
+// Each execute_request runs the cell through Repl.EvalCell.
+// Whatever the cell prints goes back as a stdout stream, a
+// failure as an error, and the run time as display data.
+// A complete_request gets what TAB offers at the prompt.
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gijit/gi/pkg/front"
+)
+
+const jupyterProtocolVersion = "5.3"
+
+var jupyterDelim = []byte("<IDS|MSG>")
+
+// KernelConnInfo is the connection file that
+// Jupyter writes and passes to `gi -kernel`.
+type KernelConnInfo struct {
+	Transport       string `json:"transport"`
+	IP              string `json:"ip"`
+	ShellPort       int    `json:"shell_port"`
+	IOPubPort       int    `json:"iopub_port"`
+	StdinPort       int    `json:"stdin_port"`
+	ControlPort     int    `json:"control_port"`
+	HBPort          int    `json:"hb_port"`
+	SignatureScheme string `json:"signature_scheme"`
+	Key             string `json:"key"`
+}
+
+type kernelHeader struct {
+	MsgID    string `json:"msg_id"`
+	Username string `json:"username"`
+	Session  string `json:"session"`
+	Date     string `json:"date"`
+	MsgType  string `json:"msg_type"`
+	Version  string `json:"version"`
+}
+
+// kernelMsg is one decoded Jupyter wire message.
+type kernelMsg struct {
+	ids       [][]byte
+	header    kernelHeader
+	rawHeader []byte
+	content   []byte
+}
+
+type kernelReq struct {
+	c   *zmtpConn
+	msg *kernelMsg
+}
+
+// Kernel serves one Jupyter session.
+type Kernel struct {
+	cfg     *GIConfig
+	conn    KernelConnInfo
+	key     []byte
+	session string
+	r       *Repl
+
+	shell, control, stdin, iopub, hb *zmtpSocket
+
+	reqs      chan *kernelReq
+	done      chan struct{}
+	execCount int
+}
+
+// KernelMain runs gijit as a Jupyter kernel until
+// the front end sends a shutdown_request.
+func (cfg *GIConfig) KernelMain(connFile string) {
+	k, err := NewKernel(cfg, connFile)
+	panicOn(err)
+	k.Serve()
+	k.Close()
+}
+
+// NewKernel reads the connection file and starts listening
+// on the ports it names. Call Serve to handle requests.
+func NewKernel(cfg *GIConfig, connFile string) (*Kernel, error) {
+	by, err := ioutil.ReadFile(connFile)
+	if err != nil {
+		return nil, err
+	}
+	k := &Kernel{
+		cfg:     cfg,
+		session: newKernelUUID(),
+		reqs:    make(chan *kernelReq),
+		done:    make(chan struct{}),
+	}
+	err = json.Unmarshal(by, &k.conn)
+	if err != nil {
+		return nil, fmt.Errorf("bad kernel connection file '%s': %v", connFile, err)
+	}
+	if k.conn.Transport != "" && k.conn.Transport != "tcp" {
+		return nil, fmt.Errorf("unsupported kernel transport '%s'; only tcp is available", k.conn.Transport)
+	}
+	if k.conn.Key != "" && k.conn.SignatureScheme != "hmac-sha256" {
+		return nil, fmt.Errorf("unsupported kernel signature scheme '%s'; only hmac-sha256 is available", k.conn.SignatureScheme)
+	}
+	k.key = []byte(k.conn.Key)
+
+	cfg.NoLiner = true
+	cfg.Quiet = true
+	k.r = NewRepl(cfg)
+
+	addr := func(port int) string {
+		return fmt.Sprintf("%s:%d", k.conn.IP, port)
+	}
+	serial := func(c *zmtpConn, frames [][]byte) {
+		msg, err := k.decode(frames)
+		if err != nil {
+			p("kernel dropping message: '%v'", err)
+			return
+		}
+		select {
+		case k.reqs <- &kernelReq{c: c, msg: msg}:
+		case <-k.done:
+		}
+	}
+	echo := func(c *zmtpConn, frames [][]byte) {
+		c.WriteMsg(frames)
+	}
+	k.shell, err = zmtpListen(addr(k.conn.ShellPort), "ROUTER", serial)
+	if err == nil {
+		k.control, err = zmtpListen(addr(k.conn.ControlPort), "ROUTER", serial)
+	}
+	if err == nil {
+		// we never send input_request, so nothing arrives here.
+		k.stdin, err = zmtpListen(addr(k.conn.StdinPort), "ROUTER", nil)
+	}
+	if err == nil {
+		k.iopub, err = zmtpListen(addr(k.conn.IOPubPort), "PUB", nil)
+	}
+	if err == nil {
+		k.hb, err = zmtpListen(addr(k.conn.HBPort), "REP", echo)
+	}
+	if err != nil {
+		k.Close()
+		return nil, err
+	}
+	return k, nil
+}
+
+// Serve handles shell and control requests, one at a time,
+// on the calling goroutine, until a shutdown_request.
+func (k *Kernel) Serve() {
+	defer close(k.done)
+	for req := range k.reqs {
+		if !k.handle(req) {
+			return
+		}
+	}
+}
+
+// Close stops listening and shuts down the LuaJIT vm.
+func (k *Kernel) Close() {
+	for _, s := range []*zmtpSocket{k.shell, k.control, k.stdin, k.iopub, k.hb} {
+		if s != nil {
+			s.Close()
+		}
+	}
+	if k.r != nil {
+		k.r.lvm.Close()
+	}
+}
+
+// handle returns false on shutdown.
+func (k *Kernel) handle(req *kernelReq) bool {
+	msg := req.msg
+	reply := func(typ string, content interface{}) {
+		k.send(req.c, msg, typ, content)
+	}
+	k.publish(msg, "status", map[string]interface{}{"execution_state": "busy"})
+	defer k.publish(msg, "status", map[string]interface{}{"execution_state": "idle"})
+
+	switch msg.header.MsgType {
+	case "kernel_info_request":
+		reply("kernel_info_reply", map[string]interface{}{
+			"status":                 "ok",
+			"protocol_version":       jupyterProtocolVersion,
+			"implementation":         "gijit",
+			"implementation_version": Version(),
+			"language_info": map[string]interface{}{
+				"name":           "go",
+				"version":        runtime.Version(),
+				"mimetype":       "text/x-go",
+				"file_extension": ".go",
+			},
+			"banner":     "gijit: a go interpreter, just-in-time. https://github.com/gijit/gi",
+			"help_links": []interface{}{},
+		})
+
+	case "execute_request":
+		reply("execute_reply", k.execute(msg))
+
+	case "is_complete_request":
+		var content struct {
+			Code string `json:"code"`
+		}
+		if err := k.decodeContent(msg, &content); err != nil {
+			reply("is_complete_reply", badRequestReply(err))
+			break
+		}
+		status := "complete"
+		eof, syntaxErr, _, _ := front.TopLevelParseGoSource([]byte(content.Code))
+		switch {
+		case syntaxErr:
+			status = "invalid"
+		case eof:
+			status = "incomplete"
+		}
+		rc := map[string]interface{}{"status": status}
+		if status == "incomplete" {
+			rc["indent"] = "    "
+		}
+		reply("is_complete_reply", rc)
+
+	case "complete_request":
+		var content struct {
+			Code      string `json:"code"`
+			CursorPos int    `json:"cursor_pos"`
+		}
+		if err := k.decodeContent(msg, &content); err != nil {
+			reply("complete_reply", badRequestReply(err))
+			break
+		}
+		reply("complete_reply", k.complete(content.Code, content.CursorPos))
+
+	case "inspect_request":
+		reply("inspect_reply", map[string]interface{}{
+			"status":   "ok",
+			"found":    false,
+			"data":     map[string]interface{}{},
+			"metadata": map[string]interface{}{},
+		})
+
+	case "history_request":
+		reply("history_reply", map[string]interface{}{
+			"status":  "ok",
+			"history": []interface{}{},
+		})
+
+	case "comm_info_request":
+		reply("comm_info_reply", map[string]interface{}{
+			"status": "ok",
+			"comms":  map[string]interface{}{},
+		})
+
+	case "shutdown_request":
+		var content struct {
+			Restart bool `json:"restart"`
+		}
+		// shut down regardless; without a restart, if
+		// we can't tell.
+		k.decodeContent(msg, &content)
+		reply("shutdown_reply", map[string]interface{}{
+			"status":  "ok",
+			"restart": content.Restart,
+		})
+		return false
+
+	default:
+		p("kernel ignoring unknown message type '%s'", msg.header.MsgType)
+	}
+	return true
+}
+
+func (k *Kernel) execute(msg *kernelMsg) map[string]interface{} {
+	var content struct {
+		Code   string `json:"code"`
+		Silent bool   `json:"silent"`
+	}
+	if err := k.decodeContent(msg, &content); err != nil {
+		rc := badRequestReply(err)
+		rc["execution_count"] = k.execCount
+		return rc
+	}
+
+	if !content.Silent {
+		k.execCount++
+	}
+	k.publish(msg, "execute_input", map[string]interface{}{
+		"code":            content.Code,
+		"execution_count": k.execCount,
+	})
+
+	var elapsed time.Duration
+	var evalErr error
+	out, err := captureStdout(k.r.lvm, func() {
+		elapsed, evalErr = k.r.EvalCell(content.Code)
+	})
+	if err != nil {
+		evalErr = err
+	}
+
+	if out != "" && !content.Silent {
+		k.publish(msg, "stream", map[string]interface{}{
+			"name": "stdout",
+			"text": out,
+		})
+	}
+
+	if evalErr != nil {
+		errContent := map[string]interface{}{
+			"ename":     "Error",
+			"evalue":    evalErr.Error(),
+			"traceback": strings.Split(evalErr.Error(), "\n"),
+		}
+		k.publish(msg, "error", errContent)
+		errContent["status"] = "error"
+		errContent["execution_count"] = k.execCount
+		return errContent
+	}
+
+	if !content.Silent {
+		k.publish(msg, "display_data", map[string]interface{}{
+			"data": map[string]interface{}{
+				"text/plain": fmt.Sprintf("elapsed: '%v'", elapsed),
+			},
+			"metadata": map[string]interface{}{},
+		})
+	}
+	return map[string]interface{}{
+		"status":           "ok",
+		"execution_count":  k.execCount,
+		"user_expressions": map[string]interface{}{},
+		"payload":          []interface{}{},
+	}
+}
+
+// decodeContent unmarshals msg's content into v,
+// logging the error if it doesn't decode.
+func (k *Kernel) decodeContent(msg *kernelMsg, v interface{}) error {
+	err := json.Unmarshal(msg.content, v)
+	if err != nil {
+		p("kernel could not decode '%s': '%v'", msg.header.MsgType, err)
+	}
+	return err
+}
+
+// badRequestReply is the content of the error reply to
+// a request whose content didn't decode.
+func badRequestReply(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"status":    "error",
+		"ename":     "BadRequest",
+		"evalue":    err.Error(),
+		"traceback": []string{},
+	}
+}
+
+// complete answers a complete_request with what TAB
+// offers at the prompt; see completeLine. Jupyter counts
+// cursor positions in code points, not bytes.
+func (k *Kernel) complete(code string, cursorPos int) map[string]interface{} {
+	pos := byteOffset(code, cursorPos)
+	head, matches, _ := completeLine(k.r.inc, code, pos)
+	if matches == nil {
+		matches = []string{}
+	}
+	return map[string]interface{}{
+		"status":       "ok",
+		"matches":      matches,
+		"cursor_start": utf8.RuneCountInString(head),
+		"cursor_end":   utf8.RuneCountInString(code[:pos]),
+		"metadata":     map[string]interface{}{},
+	}
+}
+
+// decode splits the wire frames:
+// ids..., <IDS|MSG>, hmac, header, parent_header, metadata, content, buffers...
+func (k *Kernel) decode(frames [][]byte) (*kernelMsg, error) {
+	i := 0
+	for i < len(frames) && string(frames[i]) != string(jupyterDelim) {
+		i++
+	}
+	if len(frames)-i < 6 {
+		return nil, fmt.Errorf("malformed jupyter message: %v frames", len(frames))
+	}
+	sig := frames[i+1]
+	parts := frames[i+2 : i+6]
+	if len(k.key) > 0 {
+		want := k.sign(parts)
+		if !hmac.Equal(sig, want) {
+			return nil, fmt.Errorf("bad jupyter message signature")
+		}
+	}
+	msg := &kernelMsg{
+		ids:       frames[:i],
+		rawHeader: parts[0],
+		content:   parts[3],
+	}
+	err := json.Unmarshal(parts[0], &msg.header)
+	if err != nil {
+		return nil, fmt.Errorf("bad jupyter message header: %v", err)
+	}
+	return msg, nil
+}
+
+func (k *Kernel) sign(parts [][]byte) []byte {
+	if len(k.key) == 0 {
+		return []byte{}
+	}
+	mac := hmac.New(sha256.New, k.key)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	sum := mac.Sum(nil)
+	return []byte(hex.EncodeToString(sum))
+}
+
+func (k *Kernel) encode(ids [][]byte, parent *kernelMsg, typ string, content interface{}) ([][]byte, error) {
+	hdr, err := json.Marshal(&kernelHeader{
+		MsgID:    newKernelUUID(),
+		Username: "kernel",
+		Session:  k.session,
+		Date:     time.Now().UTC().Format(time.RFC3339Nano),
+		MsgType:  typ,
+		Version:  jupyterProtocolVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	parentHdr := []byte("{}")
+	if parent != nil {
+		parentHdr = parent.rawHeader
+	}
+	body, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	parts := [][]byte{hdr, parentHdr, []byte("{}"), body}
+	frames := append([][]byte{}, ids...)
+	frames = append(frames, jupyterDelim, k.sign(parts))
+	return append(frames, parts...), nil
+}
+
+// send replies to parent on the connection it came in on.
+func (k *Kernel) send(c *zmtpConn, parent *kernelMsg, typ string, content interface{}) {
+	frames, err := k.encode(parent.ids, parent, typ, content)
+	if err != nil {
+		p("kernel could not encode '%s': '%v'", typ, err)
+		return
+	}
+	err = c.WriteMsg(frames)
+	if err != nil {
+		p("kernel could not send '%s': '%v'", typ, err)
+	}
+}
+
+// publish broadcasts on iopub; the topic frame is the message type.
+func (k *Kernel) publish(parent *kernelMsg, typ string, content interface{}) {
+	frames, err := k.encode([][]byte{[]byte(typ)}, parent, typ, content)
+	if err != nil {
+		p("kernel could not encode '%s': '%v'", typ, err)
+		return
+	}
+	k.iopub.Publish(frames)
+}
+
+func newKernelUUID() string {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	panicOn(err)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,54 @@
+//go:build !windows
+// +build !windows
+
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// captureStdout runs f with file descriptor 1 redirected
+// to a pipe, and returns what was written there. This
+// catches Lua's print and io.write as well as Go's
+// fmt.Printf, since both end up writing to fd 1.
+// lvm's C stdio buffer is flushed before fd 1 is restored.
+func captureStdout(lvm *LuaVm, f func()) (string, error) {
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	saved, err := unix.Dup(1)
+	if err != nil {
+		rd.Close()
+		wr.Close()
+		return "", err
+	}
+	err = unix.Dup2(int(wr.Fd()), 1)
+	if err != nil {
+		unix.Close(saved)
+		rd.Close()
+		wr.Close()
+		return "", err
+	}
+
+	// drain as we go, so a chatty f cannot
+	// fill the pipe and block forever.
+	got := make(chan []byte)
+	go func() {
+		by, _ := ioutil.ReadAll(rd)
+		got <- by
+	}()
+
+	f()
+
+	LuaRun(lvm, "io.stdout:flush()", false)
+	unix.Dup2(saved, 1)
+	unix.Close(saved)
+	wr.Close()
+	by := <-got
+	rd.Close()
+	return string(by), nil
+}
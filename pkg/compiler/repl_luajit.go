@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"runtime"
 	"strconv"
@@ -95,18 +94,10 @@ func (cfg *GIConfig) LuajitMain() {
 	}
 }
 
+// TranslatorMain writes the Lua for the Go file at
+// path to stdout; see TranslateFile.
 func (cfg *GIConfig) TranslatorMain(path string) {
-	lvm, err := NewLuaVmWithPrelude(cfg)
-	panicOn(err)
-
-	inc := NewIncrState(lvm, cfg)
-
-	goprog, err := ioutil.ReadFile(path)
-	panicOn(err)
-
-	translation, err := TranslateAndCatchPanic(inc, goprog)
-	panicOn(err)
-	println(translation)
+	panicOn(cfg.TranslateFile(path, os.Stdout))
 }
 
 type Repl struct {
@@ -138,6 +129,14 @@ type Repl struct {
 	prevSrc      string
 	prompterLine string
 	reader       *bufio.Reader
+
+	// okSrc holds the Go inputs that translated
+	// and ran without error, for :save.
+	okSrc []string
+
+	// timeout, when positive, interrupts any eval
+	// that runs longer. Set by :timeout.
+	timeout time.Duration
 }
 
 func NewRepl(cfg *GIConfig) *Repl {
@@ -182,6 +181,7 @@ func NewRepl(cfg *GIConfig) *Repl {
 
 	if !r.cfg.NoLiner {
 		r.prompter = NewPrompter(r.goPrompt)
+		r.prompter.prompter.SetWordCompleter(r.complete)
 		for i := range r.history {
 			r.prompter.prompter.AppendHistory(r.history[i])
 		}
@@ -189,6 +189,11 @@ func NewRepl(cfg *GIConfig) *Repl {
 	r.setPrompt()
 	r.prevSrc = ""
 	r.prompterLine = ""
+
+	// reload source imports as they are edited.
+	if err := inc.Session.StartWatching(); err != nil {
+		fmt.Printf("warning: edited source imports won't be reloaded: %v\n", err)
+	}
 	return r
 }
 
@@ -287,6 +292,56 @@ readtop:
 		}
 		return "", nil
 	}
+	for _, sl := range []string{":save", ":load"} {
+		if low == sl || strings.HasPrefix(low, sl+" ") {
+			path := strings.TrimSpace(string(cmd[len(sl):]))
+			if path == "" {
+				fmt.Printf("%s needs a file path. See :help\n", sl)
+				return "", nil
+			}
+			if home := os.Getenv("HOME"); home != "" {
+				path = strings.Replace(path, "~/", home+"/", 1)
+			}
+			if sl == ":save" {
+				r.saveSession(path)
+			} else {
+				r.loadSession(path)
+			}
+			return "", nil
+		}
+	}
+	if low == ":forget" || strings.HasPrefix(low, ":forget ") {
+		name := strings.TrimSpace(string(cmd[len(":forget"):]))
+		if name == "" {
+			fmt.Printf(":forget needs a name. See :help\n")
+			return "", nil
+		}
+		r.forgetCmd(name)
+		return "", nil
+	}
+	if low == ":cache" || strings.HasPrefix(low, ":cache ") {
+		r.cacheCmd(strings.TrimSpace(string(cmd[len(":cache"):])))
+		return "", nil
+	}
+	if low == ":watch" || strings.HasPrefix(low, ":watch ") {
+		r.watchCmd(strings.TrimSpace(string(cmd[len(":watch"):])))
+		return "", nil
+	}
+	if low == ":timeout" || strings.HasPrefix(low, ":timeout ") {
+		r.timeoutCmd(strings.TrimSpace(string(cmd[len(":timeout"):])))
+		return "", nil
+	}
+	for _, ic := range []string{":type", ":doc", ":info"} {
+		if low == ic || strings.HasPrefix(low, ic+" ") {
+			arg := strings.TrimSpace(string(cmd[len(ic):]))
+			if arg == "" {
+				fmt.Printf("%s needs an argument. See :help\n", ic)
+				return "", nil
+			}
+			r.showInfoCmd(ic, arg)
+			return "", nil
+		}
+	}
 	switch low {
 	case ":ast":
 		r.inc.PrintAST = true
@@ -361,8 +416,14 @@ readtop:
 		r.displayCmd(`lst`)
 		goto readtop
 	case ":stacks":
-		showLuaStacks(r.lvm.vm)
+		showLuaStacks(r.lvm)
+		goto readtop
+	case ":goroutines":
+		fmt.Print(goroutinesReport(r.lvm))
 		goto readtop
+	case ":undo":
+		r.undoCmd()
+		return "", nil
 	case ":r":
 		r.cfg.RawLua = true
 		r.cfg.CalculatorMode = false
@@ -421,12 +482,24 @@ these special commands.
  :rm 3-4         Remove commands 3-4 from history.
  :do <path>      Run dofile(path) on a .lua file.
  :source <path>  Re-play Go code from a file.
+ :save <path>    Save this session's successful inputs as a Go program.
+ :load <path>    Run a Go file saved by :save, skipping inputs that fail.
  :ls             List all global user variables.
  :gls            List all global variables (include __ prefixed).
  :stacks         Show lua stacks for each coroutine.
+ :goroutines     List goroutines: state, channels waited on, Go line.
+ :timeout 5s     Interrupt any eval running over 5s, like ctrl-c (0 for no limit).
+ :undo           Take back the last input that ran without error.
+ :forget f       Remove the top-level var, func or type f, to declare it afresh.
+ :cache          Show the cache of compiled source packages; ':cache clear' empties it.
+ :watch          List the source imports reloaded when edited; ':watch off' (or on).
+ :type expr      Show the Go type of expr, without running it.
+ :doc fmt.Printf Show the documentation for a package or its members.
+ :info T         Show the fields, methods and underlying type of T.
  = 3 + 4         Calculate the expression after the '=' (one line).
  ==              Multiple entry calculator mode. ':' to exit.
  import "fmt"    Import the binary, pre-compiled package.
+ TAB             Complete names, fields, methods, :commands and file paths.
  ctrl-d to exit  History is saved in ~/.gitit.hist
 `)
 		return "", nil
@@ -496,6 +569,21 @@ func (r *Repl) setPrompt() {
 }
 
 func (r *Repl) Eval(src string) error {
+	_, err := r.eval(src, false)
+	return err
+}
+
+// EvalCell evaluates src as one complete unit, the way
+// a notebook cell is run: input that ends mid-statement
+// is an error rather than a request for more lines, and
+// translation and runtime errors are returned instead of
+// printed. The elapsed run time is returned, not printed.
+func (r *Repl) EvalCell(src string) (elapsed time.Duration, err error) {
+	r.prevSrc = ""
+	return r.eval(src, true)
+}
+
+func (r *Repl) eval(src string, isCell bool) (elapsed time.Duration, err error) {
 
 	var use string
 	isContinuation := len(r.prevSrc) > 0
@@ -509,30 +597,37 @@ func (r *Repl) Eval(src string) error {
 		eof, syntaxErr, empty, err := front.TopLevelParseGoSource([]byte(src))
 		if empty {
 			r.prevSrc = ""
-			return nil
+			return 0, nil
 		}
 		//fmt.Printf("eof = %v, syntaxErr = %v\n", eof, syntaxErr)
 		if eof && !syntaxErr {
+			if isCell {
+				return 0, fmt.Errorf("unexpected end of input: '%s'", strings.TrimSpace(src))
+			}
 			r.prompt = r.goMorePrompt
 			// get another line of input
 			r.prevSrc = src
-			return nil
+			return 0, nil
 		}
 		r.prevSrc = ""
 
 		r.setPrompt()
+		r.reloadStale()
 		translation, err := TranslateAndCatchPanic(r.inc, []byte(src))
 		if err != nil {
+			if isCell {
+				return 0, err
+			}
 			fmt.Printf("oops: '%v' on input '%s'\n", err, strings.TrimSpace(src))
 			translation = "\n"
 			// still write, so we get another prompt
 
 			// hmm, or maybe not
-			return err
+			return 0, err
 		} else {
 			p("got translation of line from Go into lua: '%s'\n", strings.TrimSpace(string(translation)))
 		}
-		use = translation
+		use = r.inc.tagChunk(translation)
 
 	} else {
 		// raw mode, under :r
@@ -561,17 +656,43 @@ func (r *Repl) Eval(src string) error {
 	r.t0 = time.Now()
 
 	useEval := !r.cfg.RawLua
-	err := LuaRun(r.lvm, use, useEval)
+	if useEval {
+		r.inc.beginRun(r.lvm)
+	}
+	done := r.lvm.interruptible(r.timeout)
+	err = LuaRun(r.lvm, use, useEval)
+	timedOut := done()
 	if err != nil {
-		fmt.Printf("error from LuaRun: supplied lua with: '%s'\nlua stack:\n%v\n", use[:len(use)-1], err)
-		return nil
+		if useEval {
+			r.inc.endRun(r.lvm, true)
+		}
+		if isCell {
+			return 0, err
+		}
+		fmt.Printf("error from LuaRun: supplied lua with: '%s'\nlua stack:\n%v\n", use[:len(use)-1], r.lvm.chunkMaps.goTrace(err.Error()))
+		return 0, nil
 	}
 	r.t1 = time.Now()
+	elapsed = r.t1.Sub(r.t0)
+	if useEval {
+		err = lastEvalError(r.lvm)
+		if err == nil {
+			r.okSrc = append(r.okSrc, src)
+		}
+		r.inc.endRun(r.lvm, err != nil)
+	}
+	if err == ErrInterrupted && timedOut {
+		fmt.Printf("stopped after the %v :timeout\n", r.timeout)
+	}
+	if isCell || err == ErrDeadlock {
+		// chan.lua has already printed the goroutines.
+		return elapsed, err
+	}
 	fmt.Printf("\n")
 	r.reader.Reset(os.Stdin)
-	fmt.Printf("elapsed: '%v'\n", r.t1.Sub(r.t0))
+	fmt.Printf("elapsed: '%v'\n", elapsed)
 
-	return nil
+	return elapsed, nil
 }
 
 // :ls, :gls, :lst, :glst implementation
@@ -580,7 +701,11 @@ func (r *Repl) displayCmd(cmd string) {
 	panicOn(err)
 }
 
-func showLuaStacks(vm *golua.State) {
+// showLuaStacks shows the value stack and the traceback
+// of each coroutine. Tracebacks of translated Go show the
+// Go file, line and function.
+func showLuaStacks(lvm *LuaVm) {
+	vm := lvm.vm
 	top := vm.GetTop()
 	vm.GetGlobal("__all_coro")
 	if vm.IsNil(-1) {
@@ -603,10 +728,151 @@ func showLuaStacks(vm *golua.State) {
 		fmt.Printf("        __all_coro %v: '%s' (%s)\n", i, name, status)
 		fmt.Printf("===================================\n"+
 			"%s\n", DumpLuaStackAsString(thr, ignoreTopmost))
+		fmt.Printf("%s\n", lvm.chunkMaps.goTrace(luaTraceback(vm, -1)))
 	})
 	vm.SetTop(top)
 }
 
+// undoCmd implements :undo.
+func (r *Repl) undoCmd() {
+	err := r.inc.Undo(r.lvm)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	if n := len(r.okSrc); n > 0 {
+		fmt.Printf("undid: %s\n", strings.TrimSpace(r.okSrc[n-1]))
+		r.okSrc = r.okSrc[:n-1]
+	}
+}
+
+// forgetCmd implements :forget.
+func (r *Repl) forgetCmd(name string) {
+	users, err := r.inc.Forget(r.lvm, name)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	fmt.Printf("forgot '%s'.\n", name)
+	r.okSrc = append(r.okSrc, forgetMark+name)
+	if len(users) > 0 {
+		fmt.Printf("warning: %s used '%s'; declare it again, or forget them too.\n", strings.Join(users, ", "), name)
+	}
+}
+
+// timeoutCmd implements :timeout. With no argument it
+// shows the current limit.
+func (r *Repl) timeoutCmd(arg string) {
+	switch arg {
+	case "":
+	case "0", "off":
+		r.timeout = 0
+	default:
+		d, err := time.ParseDuration(arg)
+		if err != nil || d < 0 {
+			fmt.Printf(":timeout needs a duration such as 500ms or 2s, or 0 for none.\n")
+			return
+		}
+		r.timeout = d
+	}
+	if r.timeout == 0 {
+		fmt.Printf("timeout: none; ctrl-c interrupts an eval.\n")
+		return
+	}
+	fmt.Printf("timeout: %v\n", r.timeout)
+}
+
+// cacheCmd implements :cache and :cache clear.
+func (r *Repl) cacheCmd(arg string) {
+	s := r.inc.Session
+	switch arg {
+	case "":
+	case "clear":
+		if err := s.ClearCache(); err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+	default:
+		fmt.Printf("unknown :cache command '%s'. See :help\n", arg)
+		return
+	}
+	n, size, err := s.CacheSize()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	fmt.Printf("cache: %s holds %d compiled packages, %d bytes.\n", s.cacheDir(), n, size)
+	if paths := s.CachedImports(); arg == "" && len(paths) > 0 {
+		fmt.Printf("loaded from the cache: %s\n", strings.Join(paths, ", "))
+	}
+}
+
+// reloadStale reloads the source imports edited since
+// the last input.
+func (r *Repl) reloadStale() {
+	paths := r.inc.Session.StaleImports()
+	if len(paths) == 0 {
+		return
+	}
+	reloaded, _, err := r.inc.ReloadAndRun(r.lvm, paths)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	if len(reloaded) > 0 {
+		fmt.Printf("reloaded: %s\n", strings.Join(reloaded, ", "))
+	}
+}
+
+// watchCmd implements :watch.
+func (r *Repl) watchCmd(arg string) {
+	s := r.inc.Session
+	switch arg {
+	case "":
+	case "on":
+		if err := s.StartWatching(); err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+	case "off":
+		s.StopWatching()
+	default:
+		fmt.Printf("unknown :watch command '%s'. See :help\n", arg)
+		return
+	}
+	paths := s.Watching()
+	switch {
+	case paths == nil:
+		fmt.Printf("watch: off; edited source imports are not reloaded.\n")
+	case len(paths) == 0:
+		fmt.Printf("watch: on; no source imports yet.\n")
+	default:
+		fmt.Printf("watch: on, for %s\n", strings.Join(paths, ", "))
+	}
+}
+
+// goroutinesReport lists each goroutine with its state,
+// the channels it waits on, and where it is in the Go
+// typed at the prompt; see __goroutines in chan.lua.
+func goroutinesReport(lvm *LuaVm) (s string) {
+	lvm.goro.doFunc(func() {
+		vm := lvm.vm
+		top := vm.GetTop()
+		defer vm.SetTop(top)
+		vm.GetGlobal("__goroutines")
+		if !vm.IsFunction(-1) {
+			s = "no goroutine scheduler: is the prelude loaded?\n"
+			return
+		}
+		if err := vm.Call(0, 1); err != nil {
+			s = fmt.Sprintf("error listing goroutines: %v\n", err)
+			return
+		}
+		s = vm.ToString(-1)
+	})
+	return lvm.chunkMaps.goTrace(s)
+}
+
 // Call f with each __all_coro array value in term on the top of
 // the stack, the f(i, name) call will have i set to 1, 2, 3, ...
 // in turn.
@@ -0,0 +1,376 @@
+package compiler
+
+// repl_session.go: :save and :load. :save writes the inputs
+// that ran without error as a compilable Go program; :load
+// replays such a file (or any single-file main package)
+// one declaration and one statement at a time.
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/format"
+	"github.com/gijit/gi/pkg/parser"
+	"github.com/gijit/gi/pkg/printer"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// sessionGoFile renders srcs, the inputs that succeeded at
+// the prompt, as package main. Type, const and func declarations
+// stay at the top level, in the order they were made (types
+// and consts ahead of funcs); a later redefinition replaces
+// an earlier one. Variables, which are package level at the
+// prompt, are declared at the top level with their final type,
+// and assigned in main() in the original order, along with the
+// statements. Bare expressions are printed.
+// A forgetMark entry, left by :forget, drops the declarations
+// of its name made before it. If the result doesn't format as
+// Go, it is returned unformatted, along with the error.
+func sessionGoFile(inc *IncrState, srcs []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	render := func(n interface{}) string {
+		var b bytes.Buffer
+		printer.Fprint(&b, fset, n)
+		return b.String()
+	}
+
+	imports := make(map[string]string) // path -> name, "" if default.
+	var varNames []string
+	varSeen := make(map[string]bool)
+	addVar := func(id *ast.Ident) {
+		if id.Name != "_" && !varSeen[id.Name] {
+			varSeen[id.Name] = true
+			varNames = append(varNames, id.Name)
+		}
+	}
+
+	type topDecl struct {
+		key  string
+		text string
+	}
+	var decls []*topDecl
+	addDecl := func(key, text string) {
+		for _, d := range decls {
+			if d.key == key {
+				d.text = "" // redefined later
+			}
+		}
+		decls = append(decls, &topDecl{key: key, text: text})
+	}
+
+	type mainStmt struct {
+		text    string
+		defines []string // the vars it declares or assigns.
+	}
+	var mainStmts []*mainStmt
+	addStmt := func(text string, defines ...*ast.Ident) {
+		st := &mainStmt{text: text}
+		for _, id := range defines {
+			st.defines = append(st.defines, id.Name)
+		}
+		mainStmts = append(mainStmts, st)
+	}
+	needFmt := false
+
+	forget := func(name string) {
+		for _, d := range decls {
+			kind := d.key[:strings.Index(d.key, " ")]
+			names := d.key[len(kind)+1:]
+			if kind == "method" {
+				// a forgotten type takes its methods with it.
+				names = strings.TrimPrefix(names[:strings.LastIndex(names, ".")], "*")
+			}
+			for _, nm := range strings.Split(names, ",") {
+				if nm == name {
+					d.text = ""
+				}
+			}
+		}
+		if varSeen[name] {
+			delete(varSeen, name)
+			keep := varNames[:0]
+			for _, nm := range varNames {
+				if nm != name {
+					keep = append(keep, nm)
+				}
+			}
+			varNames = keep
+		}
+		keep := mainStmts[:0]
+		for _, st := range mainStmts {
+			drop := false
+			for _, nm := range st.defines {
+				drop = drop || nm == name
+			}
+			if !drop {
+				keep = append(keep, st)
+			}
+		}
+		mainStmts = keep
+	}
+
+	genDecl := func(d *ast.GenDecl) {
+		switch d.Tok {
+		case token.IMPORT:
+			for _, spec := range d.Specs {
+				is := spec.(*ast.ImportSpec)
+				path, _ := strconv.Unquote(is.Path.Value)
+				name := ""
+				if is.Name != nil {
+					name = is.Name.Name
+				}
+				imports[path] = name
+			}
+		case token.VAR:
+			for _, spec := range d.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for _, id := range vs.Names {
+					addVar(id)
+				}
+				if len(vs.Values) > 0 {
+					as := &ast.AssignStmt{Tok: token.ASSIGN, Rhs: vs.Values}
+					for _, id := range vs.Names {
+						as.Lhs = append(as.Lhs, id)
+					}
+					addStmt(render(as), vs.Names...)
+				}
+			}
+		default:
+			var names []string
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, id := range s.Names {
+						names = append(names, id.Name)
+					}
+				}
+			}
+			addDecl(d.Tok.String()+" "+strings.Join(names, ","), render(d))
+		}
+	}
+
+	for _, src := range srcs {
+		if strings.HasPrefix(src, forgetMark) {
+			forget(src[len(forgetMark):])
+			continue
+		}
+		f, err := parser.ParseFile(fset, "", src, 0)
+		if err != nil {
+			continue
+		}
+		for i, node := range f.Nodes {
+			if ds, ok := node.(*ast.DeclStmt); ok {
+				node = ds.Decl
+			}
+			switch n := node.(type) {
+			case *ast.GenDecl:
+				genDecl(n)
+				continue
+			case *ast.FuncDecl:
+				key := "func " + n.Name.Name
+				if n.Recv != nil && len(n.Recv.List) > 0 {
+					key = "method " + render(n.Recv.List[0].Type) + "." + n.Name.Name
+				}
+				addDecl(key, render(n))
+				continue
+			case *ast.AssignStmt:
+				var defines []*ast.Ident
+				for _, e := range n.Lhs {
+					if id, ok := e.(*ast.Ident); ok {
+						if n.Tok == token.DEFINE {
+							addVar(id)
+						}
+						defines = append(defines, id)
+					}
+				}
+				if n.Tok == token.DEFINE {
+					n.Tok = token.ASSIGN
+				}
+				addStmt(render(n), defines...)
+				continue
+			}
+			if i < len(f.IsExpr) && f.IsExpr[i] {
+				if _, isCall := node.(*ast.CallExpr); !isCall {
+					needFmt = true
+					addStmt("fmt.Println(" + render(node) + ")")
+					continue
+				}
+			}
+			addStmt(render(node))
+		}
+	}
+	if needFmt {
+		if _, ok := imports["fmt"]; !ok {
+			imports["fmt"] = ""
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// gijit session, written by :save on %s.\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "package main\n\n")
+
+	if len(imports) > 0 {
+		var paths []string
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		fmt.Fprintf(&b, "import (\n")
+		for _, path := range paths {
+			if name := imports[path]; name != "" {
+				fmt.Fprintf(&b, "\t%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(&b, "\t%q\n", path)
+			}
+		}
+		fmt.Fprintf(&b, ")\n\n")
+	}
+
+	// types and consts first, then the vars, which may
+	// have those types, then the funcs and methods, which
+	// may use the vars; :load replays them in this order.
+	isFunc := func(d *topDecl) bool {
+		return strings.HasPrefix(d.key, "func ") || strings.HasPrefix(d.key, "method ")
+	}
+	for _, d := range decls {
+		if d.text != "" && !isFunc(d) {
+			fmt.Fprintf(&b, "%s\n\n", d.text)
+		}
+	}
+
+	pkg := curTypesPkg(inc)
+	if pkg != nil && len(varNames) > 0 {
+		qual := func(p *types.Package) string {
+			if p == pkg {
+				return ""
+			}
+			return p.Name()
+		}
+		fmt.Fprintf(&b, "var (\n")
+		for _, nm := range varNames {
+			if v, ok := pkg.Scope().Lookup(nm).(*types.Var); ok {
+				fmt.Fprintf(&b, "\t%s %s\n", nm, types.TypeString(v.Type(), qual))
+			}
+		}
+		fmt.Fprintf(&b, ")\n\n")
+	}
+
+	for _, d := range decls {
+		if d.text != "" && isFunc(d) {
+			fmt.Fprintf(&b, "%s\n\n", d.text)
+		}
+	}
+
+	fmt.Fprintf(&b, "func main() {\n")
+	for _, st := range mainStmts {
+		fmt.Fprintf(&b, "%s\n", st.text)
+	}
+	fmt.Fprintf(&b, "}\n")
+
+	pretty, err := format.Source(b.Bytes())
+	if err != nil {
+		// still worth saving; the user can fix it up.
+		return b.Bytes(), fmt.Errorf("the session is not valid Go: %v", err)
+	}
+	return pretty, nil
+}
+
+// forgetMark starts the entry :forget leaves in the
+// inputs :save writes, followed by the forgotten name.
+const forgetMark = "//gijit:forget "
+
+// loadSessionChunks splits the Go file at path into the
+// inputs :load evaluates, in order: each import, each
+// top-level declaration except main, and then each
+// statement in the body of main.
+func loadSessionChunks(path string) ([]string, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	render := func(n interface{}) string {
+		var b bytes.Buffer
+		printer.Fprint(&b, fset, n)
+		return b.String()
+	}
+
+	var chunks []string
+	var mainBody *ast.BlockStmt
+	for _, node := range f.Nodes {
+		switch n := node.(type) {
+		case *ast.GenDecl:
+			if n.Tok == token.IMPORT {
+				for _, spec := range n.Specs {
+					chunks = append(chunks, "import "+render(spec))
+				}
+				continue
+			}
+		case *ast.FuncDecl:
+			if n.Recv == nil && n.Name.Name == "main" {
+				mainBody = n.Body
+				continue
+			}
+		}
+		chunks = append(chunks, render(node))
+	}
+	if mainBody != nil {
+		for _, stmt := range mainBody.List {
+			chunks = append(chunks, render(stmt))
+		}
+	}
+	return chunks, nil
+}
+
+// saveSession is :save <path>.
+func (r *Repl) saveSession(path string) {
+	by, err := sessionGoFile(r.inc, r.okSrc)
+	if err != nil {
+		// written anyway, to be fixed up by hand.
+		fmt.Printf(":save error: %v\n", err)
+	}
+	err = ioutil.WriteFile(path, by, 0644)
+	if err != nil {
+		fmt.Printf(":save error: %v\n", err)
+		return
+	}
+	n := 0
+	for _, src := range r.okSrc {
+		if !strings.HasPrefix(src, forgetMark) {
+			n++
+		}
+	}
+	fmt.Printf("saved %v successful inputs to '%s'.\n", n, path)
+}
+
+// loadSession is :load <path>. Chunks that fail
+// are reported and skipped.
+func (r *Repl) loadSession(path string) {
+	chunks, err := loadSessionChunks(path)
+	if err != nil {
+		fmt.Printf(":load error: %v\n", err)
+		return
+	}
+	skipped := 0
+	for _, chunk := range chunks {
+		_, err := r.EvalCell(chunk)
+		if err != nil {
+			skipped++
+			fmt.Printf(":load skipping '%s': %v\n", strings.TrimSpace(chunk), err)
+		}
+	}
+	fmt.Printf("loaded '%s': %v of %v inputs ran, %v skipped.\n", path, len(chunks)-skipped, len(chunks), skipped)
+}
@@ -0,0 +1,132 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1302SaveAndLoadSession(t *testing.T) {
+
+	cv.Convey(`:save should write the successful inputs as package main, and :load should restore them`, t, func() {
+
+		// don't mess up the user's regular ~/.gijit.hist file with our test.
+		origHome := os.Getenv("HOME")
+		dir, err := ioutil.TempDir("", "gijit-save-test")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+		os.Setenv("HOME", dir)
+		defer os.Setenv("HOME", origHome)
+
+		cfg := NewGIConfig()
+		cfg.Quiet = true
+		cfg.NoLiner = true
+		r := NewRepl(cfg)
+		defer r.lvm.Close()
+
+		for _, src := range []string{
+			`import "strings"`,
+			`type Pt struct { X, Y int }`,
+			`func double(x int) int { return 2 * x }`,
+			`a := double(21)`,
+			`pt := Pt{X: 1}`,
+			`pt.Y = a`,
+			`s := strings.Repeat("ab", 2)`,
+			`a + 1`,
+		} {
+			_, err := r.EvalCell(src)
+			panicOn(err)
+		}
+
+		path := filepath.Join(dir, "session.go")
+		r.saveSession(path)
+		by, err := ioutil.ReadFile(path)
+		panicOn(err)
+		saved := string(by)
+		cv.So(saved, cv.ShouldContainSubstring, "package main")
+		cv.So(saved, cv.ShouldContainSubstring, `"strings"`)
+		cv.So(saved, cv.ShouldContainSubstring, `"fmt"`)
+		cv.So(saved, cv.ShouldContainSubstring, "var (")
+		cv.So(saved, cv.ShouldContainSubstring, "pt Pt")
+		cv.So(saved, cv.ShouldContainSubstring, "a = double(21)")
+		cv.So(saved, cv.ShouldContainSubstring, "fmt.Println(a + 1)")
+
+		// :load into a fresh repl.
+		r2 := NewRepl(cfg)
+		defer r2.lvm.Close()
+		r2.loadSession(path)
+		LuaMustInt64(r2.lvm, "a", 42)
+		_, err = r2.EvalCell("y := pt.Y")
+		panicOn(err)
+		LuaMustInt64(r2.lvm, "y", 42)
+		LuaMustString(r2.lvm, "s", "abab")
+		_, err = r2.EvalCell("d := double(pt.X)")
+		panicOn(err)
+		LuaMustInt64(r2.lvm, "d", 2)
+
+		// saving the loaded session writes the same program.
+		path2 := filepath.Join(dir, "session2.go")
+		r2.saveSession(path2)
+		by2, err := ioutil.ReadFile(path2)
+		panicOn(err)
+		cv.So(string(by2), cv.ShouldContainSubstring, "pt Pt")
+		cv.So(string(by2), cv.ShouldContainSubstring, "a = double(21)")
+	})
+}
+
+func Test1304ForgetThenSave(t *testing.T) {
+
+	cv.Convey(`after :forget, :save should write only the later declaration of the forgotten name`, t, func() {
+
+		// don't mess up the user's regular ~/.gijit.hist file with our test.
+		origHome := os.Getenv("HOME")
+		dir, err := ioutil.TempDir("", "gijit-forget-save-test")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+		os.Setenv("HOME", dir)
+		defer os.Setenv("HOME", origHome)
+
+		cfg := NewGIConfig()
+		cfg.Quiet = true
+		cfg.NoLiner = true
+		r := NewRepl(cfg)
+		defer r.lvm.Close()
+
+		for _, src := range []string{
+			`func f() int { return 3 }`,
+			`n := f()`,
+			`n = 4`,
+		} {
+			_, err := r.EvalCell(src)
+			panicOn(err)
+		}
+		r.forgetCmd("f")
+		r.forgetCmd("n")
+		for _, src := range []string{
+			`func f() string { return "s" }`,
+			`n := f()`,
+		} {
+			_, err := r.EvalCell(src)
+			panicOn(err)
+		}
+
+		path := filepath.Join(dir, "session.go")
+		r.saveSession(path)
+		by, err := ioutil.ReadFile(path)
+		panicOn(err)
+		saved := string(by)
+		cv.So(strings.Count(saved, "func f()"), cv.ShouldEqual, 1)
+		cv.So(saved, cv.ShouldContainSubstring, `func f() string`)
+		cv.So(saved, cv.ShouldContainSubstring, "n string")
+		cv.So(saved, cv.ShouldNotContainSubstring, "n = 4")
+
+		r2 := NewRepl(cfg)
+		defer r2.lvm.Close()
+		r2.loadSession(path)
+		LuaMustString(r2.lvm, "n", "s")
+	})
+}
@@ -1004,8 +1004,8 @@ m := 1%a
 		cv.So(string(translation), matchesLuaSrc,
 			`
 	a = 0LL;
-    b = __integerByZeroCheck(1LL / a);
-    m = __integerByZeroCheck(1LL % a);
+    b = __divInt64(1LL, a);
+    m = __remInt64(1LL, a);
 `)
 
 		codeWithCatch := `
@@ -161,6 +161,8 @@ func sourceGoFiles(files []string) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		// so positions, and runtime errors, refer to f.
+		fmt.Fprintf(&buf, "//line %s:1\n", f)
 		_, err = io.Copy(&buf, bytes.NewBuffer(by))
 		if err != nil {
 			return nil, err
@@ -1,10 +1,15 @@
 package compiler
 
 import (
-	golua "github.com/glycerine/golua/lua"
-	"github.com/glycerine/luar"
 	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
+
+	"github.com/gijit/gi/pkg/ast"
+	golua "github.com/glycerine/golua/lua"
+	"github.com/glycerine/luar"
 )
 
 // must be kept in sync with tsys.lua
@@ -67,6 +72,57 @@ func registerBasicReflectTypes(vm *golua.State) {
 	m["__kindComplex64"] = reflect.TypeOf(complex64(0))
 	m["__kindComplex128"] = reflect.TypeOf(complex128(0))
 
+	// interfaces, in __gijitTypeToGoType.
+	m["__emptyInterface"] = reflect.TypeOf((*interface{})(nil)).Elem()
+	m["__error"] = reflect.TypeOf((*error)(nil)).Elem()
+
 	luar.Register(vm, "__rtyp", m)
 
+	luar.Register(vm, "", luar.Map{
+		"__gijitStructOf": structOfForLua,
+	})
+}
+
+// structOfForLua is reflect.StructOf for __gijitTypeToGoType
+// in tsys.lua, which passes the fields as parallel arrays.
+// props are the Lua field names. reflect can't make
+// unexported fields, so those get a capitalized name,
+// a lua tag that lets luar find them again, and a
+// json tag of "-" so encoding/json still skips them.
+func structOfForLua(props []string, types []reflect.Type, tags []string, embedded []bool) reflect.Type {
+	taken := make(map[string]bool)
+	for _, prop := range props {
+		taken[prop] = true
+	}
+	fields := make([]reflect.StructField, len(props))
+	for i, prop := range props {
+		name := prop
+		tag := tags[i]
+		anon := embedded[i]
+		if !ast.IsExported(name) {
+			r, size := utf8.DecodeRuneInString(name)
+			name = string(unicode.ToUpper(r)) + name[size:]
+			if !ast.IsExported(name) {
+				// _x, or a letter with no upper case.
+				name = "X" + name
+			}
+			for taken[name] {
+				name += "_"
+			}
+			taken[name] = true
+			anon = false
+			tag = `json:"-" ` + tag
+		}
+		if name != prop {
+			tag += ` lua:"` + prop + `"`
+		}
+		tag = strings.TrimSpace(tag)
+		fields[i] = reflect.StructField{
+			Name:      name,
+			Type:      types[i],
+			Tag:       reflect.StructTag(tag),
+			Anonymous: anon,
+		}
+	}
+	return reflect.StructOf(fields)
 }
@@ -0,0 +1,226 @@
+package compiler
+
+// run.go: the non-interactive side of the gi command.
+// gi run executes a main package, gi translate writes the
+// Lua for one, gi -e evaluates an expression and prints
+// its value, and gi script.go runs a script: a file of
+// top-level statements, perhaps started by a #! line.
+// Each returns the exit code for the process.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/compiler/shadow"
+	"github.com/gijit/gi/pkg/parser"
+	"github.com/gijit/gi/pkg/token"
+	golua "github.com/glycerine/golua/lua"
+	"github.com/glycerine/luar"
+)
+
+// Exit codes, as go run and Go binaries use them.
+const (
+	ExitOK      = 0
+	ExitCompile = 1 // didn't parse, type-check or translate
+	ExitPanic   = 2 // panicked, or deadlocked
+)
+
+// programSource turns the Go file src into one input for
+// the prompt that runs it as a program would run: the file
+// itself, followed by calls to its init funcs, in order, and
+// to main. Since the prompt would take a second init for a
+// redefinition of the first, each is renamed. hasMain is
+// false if src declares no func main, in which case nothing
+// is added. A #! line at the top is taken as a comment.
+func programSource(path string, src []byte) (prog []byte, hasMain bool, err error) {
+	if bytes.HasPrefix(src, []byte("#!")) {
+		src = append([]byte("//"), src[2:]...)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	if f.Name != nil && f.Name.Name != "main" {
+		return nil, false, fmt.Errorf("%s: package %s is not a main package", path, f.Name.Name)
+	}
+
+	var inits []int
+	for _, node := range f.Nodes {
+		fd, ok := node.(*ast.FuncDecl)
+		if !ok || fd.Recv != nil {
+			continue
+		}
+		switch fd.Name.Name {
+		case "main":
+			hasMain = true
+		case "init":
+			inits = append(inits, fset.Position(fd.Name.Pos()).Offset)
+		}
+	}
+
+	var b bytes.Buffer
+	// so positions, and runtime errors, refer to path.
+	fmt.Fprintf(&b, "//line %s:1\n", path)
+	prev := 0
+	for i, off := range inits {
+		b.Write(src[prev:off])
+		fmt.Fprintf(&b, "__gijitInit%d", i)
+		prev = off + len("init")
+	}
+	b.Write(src[prev:])
+	if !hasMain {
+		return b.Bytes(), false, nil
+	}
+	b.WriteString("\n")
+	for i := range inits {
+		fmt.Fprintf(&b, "__gijitInit%d()\n", i)
+	}
+	b.WriteString("main()\n")
+	return b.Bytes(), true, nil
+}
+
+// setOsForProgram makes args what the program sees as
+// os.Args, once it imports "os", and has its os.Exit flush
+// stdout first.
+func setOsForProgram(args []string) {
+	if sp, ok := shadow.Lookup("os"); ok {
+		sp.Pkg["Args"] = args
+		sp.Pkg["Exit"] = exitFlushing
+	}
+}
+
+// exitFlushing is os.Exit for the programs gi runs. Lua's
+// print writes through C's stdio, which Go's os.Exit
+// wouldn't flush.
+func exitFlushing(L *golua.State) int {
+	var code int
+	if _, err := luar.LuaToGo(L, 1, &code); err != nil {
+		code = ExitPanic
+	}
+	L.DoString("io.stdout:flush()")
+	os.Exit(code)
+	return 0
+}
+
+// RunMain runs the main package in the Go file at path,
+// with os.Args set to path followed by args. Errors go to
+// stderr; the exit code says what kind they were.
+func (cfg *GIConfig) RunMain(path string, args []string) int {
+	return cfg.runFile(path, args, true)
+}
+
+// RunScript runs the Go file at path as RunMain does, but
+// it needs neither a package clause nor a func main: its
+// statements run in order, as at the prompt, and then main,
+// if it has one. A package clause must still say main.
+func (cfg *GIConfig) RunScript(path string, args []string) int {
+	return cfg.runFile(path, args, false)
+}
+
+func (cfg *GIConfig) runFile(path string, args []string, needMain bool) int {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return ExitCompile
+	}
+	prog, hasMain, err := programSource(path, src)
+	if err == nil && needMain && !hasMain {
+		err = fmt.Errorf("%s: function main is undeclared in the main package", path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return ExitCompile
+	}
+	setOsForProgram(append([]string{path}, args...))
+	return cfg.runProgram(prog)
+}
+
+// runProgram translates prog, and runs it on a
+// new vm.
+func (cfg *GIConfig) runProgram(prog []byte) int {
+	lvm, err := NewLuaVmWithPrelude(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return ExitCompile
+	}
+	defer lvm.Close()
+	inc := NewIncrState(lvm, cfg)
+
+	translation, err := TranslateAndCatchPanic(inc, prog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return ExitCompile
+	}
+	err = LuaRun(lvm, translation, true)
+	if err == nil {
+		err = lastEvalError(lvm)
+	}
+	// print writes through C's stdio, which Go's
+	// exit wouldn't flush.
+	LuaRun(lvm, "io.stdout:flush()", false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return ExitPanic
+	}
+	return ExitOK
+}
+
+// TranslateFile writes to w the Lua for the Go file at
+// path. For a main package, that is the Lua gi run would
+// run; for other files, that of their statements and
+// declarations entered at the prompt.
+func (cfg *GIConfig) TranslateFile(path string, w io.Writer) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	prog, _, err := programSource(path, src)
+	if err != nil {
+		// not a main package; take it as it is.
+		prog = src
+	}
+
+	lvm, err := NewLuaVmWithPrelude(cfg)
+	if err != nil {
+		return err
+	}
+	defer lvm.Close()
+	inc := NewIncrState(lvm, cfg)
+
+	translation, err := TranslateAndCatchPanic(inc, prog)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, translation)
+	return err
+}
+
+// EvalMain evaluates src, Go statements or an expression,
+// and prints the value(s) of an expression to stdout.
+func (cfg *GIConfig) EvalMain(src string) int {
+	in, err := NewInterpreter(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return ExitCompile
+	}
+	defer in.Close()
+
+	ans, ran, err := in.eval(src)
+	LuaRun(in.lvm, "io.stdout:flush()", false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		if ran {
+			return ExitPanic
+		}
+		return ExitCompile
+	}
+	if len(ans) > 0 {
+		fmt.Println(ans...)
+	}
+	return ExitOK
+}
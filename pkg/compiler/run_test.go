@@ -0,0 +1,160 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gijit/gi/pkg/compiler/shadow"
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+// restoreOsShadow returns a func that puts back what the
+// os shadow has now, which the tests here change.
+func restoreOsShadow() func() {
+	sp, _ := shadow.Lookup("os")
+	saved := make(map[string]interface{})
+	for _, name := range []string{"Args", "Exit", "Stdin"} {
+		saved[name] = sp.Pkg[name]
+	}
+	return func() {
+		for name, v := range saved {
+			sp.Pkg[name] = v
+		}
+	}
+}
+
+func Test2800RunMainPackage(t *testing.T) {
+
+	cv.Convey(`gi run should run a main package's inits, in order, then main, with os.Args set; and the exit code should tell compile errors from panics`, t, func() {
+
+		dir, err := ioutil.TempDir("", "gijit-run")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+		writeFixture(dir, map[string]string{
+			"prog.go": `package main
+
+import "os"
+
+var order string
+var n int
+var last string
+
+func init() { order += "a" }
+
+func main() {
+	order += "m"
+	n = len(os.Args)
+	last = os.Args[n-1]
+}
+
+func init() { order += "b" }
+`,
+			"bad.go":    "package main\n\nfunc main() { var s string = 1 }\n",
+			"panics.go": "package main\n\nfunc main() { panic(\"oh no\") }\n",
+			"nomain.go": "package main\n\nfunc helper() {}\n",
+			"lib.go":    "package lib\n\nfunc main() {}\n",
+		})
+		fn := filepath.Join(dir, "prog.go")
+
+		defer restoreOsShadow()()
+		setOsForProgram([]string{fn, "x", "y"})
+
+		src, err := ioutil.ReadFile(fn)
+		panicOn(err)
+		prog, hasMain, err := programSource(fn, src)
+		panicOn(err)
+		cv.So(hasMain, cv.ShouldBeTrue)
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+		translation, err := inc.Tr(prog)
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+		cv.So(lastEvalError(vm), cv.ShouldBeNil)
+		LuaMustString(vm, "order", "abm")
+		LuaMustInt64(vm, "n", 3)
+		LuaMustString(vm, "last", "y")
+
+		cfg := NewGIConfig()
+		cfg.Quiet = true
+		cv.So(cfg.RunMain(fn, nil), cv.ShouldEqual, ExitOK)
+		cv.So(cfg.RunMain(filepath.Join(dir, "bad.go"), nil), cv.ShouldEqual, ExitCompile)
+		cv.So(cfg.RunMain(filepath.Join(dir, "panics.go"), nil), cv.ShouldEqual, ExitPanic)
+		cv.So(cfg.RunMain(filepath.Join(dir, "nomain.go"), nil), cv.ShouldEqual, ExitCompile)
+		cv.So(cfg.RunMain(filepath.Join(dir, "lib.go"), nil), cv.ShouldEqual, ExitCompile)
+		cv.So(cfg.RunMain(filepath.Join(dir, "missing.go"), nil), cv.ShouldEqual, ExitCompile)
+
+		cv.So(cfg.EvalMain("1 + 2"), cv.ShouldEqual, ExitOK)
+		cv.So(cfg.EvalMain("1 +"), cv.ShouldEqual, ExitCompile)
+		cv.So(cfg.EvalMain(`panic("oh no")`), cv.ShouldEqual, ExitPanic)
+	})
+}
+
+func Test2801RunScript(t *testing.T) {
+
+	cv.Convey(`a gi script should run with or without package main, skipping its #! line, see its arguments in os.Args, and read stdin through bufio`, t, func() {
+
+		dir, err := ioutil.TempDir("", "gijit-script")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+		writeFixture(dir, map[string]string{
+			"count": `#!/usr/bin/env gi
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+sc := bufio.NewScanner(os.Stdin)
+lines := 0
+words := 0
+for sc.Scan() {
+	lines++
+	words += len(strings.Fields(sc.Text()))
+}
+arg := os.Args[1]
+`,
+			"bad": "#!/usr/bin/env gi\nvar s string = 1\n",
+		})
+		fn := filepath.Join(dir, "count")
+		defer restoreOsShadow()()
+
+		r, w, err := os.Pipe()
+		panicOn(err)
+		defer r.Close()
+		go func() {
+			w.Write([]byte("one two\nthree\n"))
+			w.Close()
+		}()
+		sp, _ := shadow.Lookup("os")
+		sp.Pkg["Stdin"] = r
+		setOsForProgram([]string{fn, "-v"})
+
+		src, err := ioutil.ReadFile(fn)
+		panicOn(err)
+		prog, hasMain, err := programSource(fn, src)
+		panicOn(err)
+		cv.So(hasMain, cv.ShouldBeFalse)
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+		translation, err := inc.Tr(prog)
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+		cv.So(lastEvalError(vm), cv.ShouldBeNil)
+		LuaMustInt64(vm, "lines", 2)
+		LuaMustInt64(vm, "words", 3)
+		LuaMustString(vm, "arg", "-v")
+
+		cfg := NewGIConfig()
+		cfg.Quiet = true
+		cv.So(cfg.RunScript(filepath.Join(dir, "bad"), nil), cv.ShouldEqual, ExitCompile)
+		cv.So(cfg.RunMain(fn, nil), cv.ShouldEqual, ExitCompile)
+	})
+}
@@ -0,0 +1,67 @@
+package shadow
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Caller calls the named method of the interpreted value
+// behind an adapter, with args, and stores its results
+// through the pointers in results. It is how an adapter's
+// methods reach Lua; the compiler supplies it.
+type Caller func(method string, args []interface{}, results []interface{})
+
+// adapters maps each Go interface type to its adapter
+// constructor, a func(Caller) I.
+var adapters = make(map[reflect.Type]reflect.Value)
+
+// RegisterAdapters records a package's adapter constructors,
+// the GijitShadow_NewAdapter_ funcs that gen-gijit-shadow-import
+// writes for each exported interface, so that interpreted
+// values can be passed where Go wants that interface.
+func RegisterAdapters(m map[string]interface{}) {
+	mut.Lock()
+	defer mut.Unlock()
+	for name, ctor := range m {
+		cv := reflect.ValueOf(ctor)
+		ct := cv.Type()
+		if ct.Kind() != reflect.Func || ct.NumIn() != 1 || ct.In(0) != callerType ||
+			ct.NumOut() != 1 || ct.Out(0).Kind() != reflect.Interface {
+			panic(fmt.Sprintf("shadow.RegisterAdapters: '%s' is a %v, not a func(Caller) interface", name, ct))
+		}
+		adapters[ct.Out(0)] = cv
+	}
+}
+
+// Adapter returns a value of interface type iface whose
+// methods forward to call, if iface has a registered adapter.
+func Adapter(iface reflect.Type, call Caller) (reflect.Value, bool) {
+	mut.Lock()
+	ctor, ok := adapters[iface]
+	mut.Unlock()
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return ctor.Call([]reflect.Value{reflect.ValueOf(call)})[0], true
+}
+
+var callerType = reflect.TypeOf(Caller(nil))
+
+// error belongs to no package, so its adapter is here.
+
+type gijitShadowAdapterError struct {
+	call Caller
+}
+
+func (a *gijitShadowAdapterError) Error() (r0 string) {
+	a.call("Error", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func init() {
+	RegisterAdapters(map[string]interface{}{
+		"error": func(call Caller) error {
+			return &gijitShadowAdapterError{call: call}
+		},
+	})
+}
@@ -0,0 +1,148 @@
+package shadow_bufio
+
+import "bufio"
+import "github.com/gijit/gi/pkg/compiler/shadow"
+
+var Pkg = make(map[string]interface{})
+var Ctor = make(map[string]interface{})
+
+func init() {
+    Pkg["ErrAdvanceTooFar"] = bufio.ErrAdvanceTooFar
+    Pkg["ErrBufferFull"] = bufio.ErrBufferFull
+    Pkg["ErrFinalToken"] = bufio.ErrFinalToken
+    Pkg["ErrInvalidUnreadByte"] = bufio.ErrInvalidUnreadByte
+    Pkg["ErrInvalidUnreadRune"] = bufio.ErrInvalidUnreadRune
+    Pkg["ErrNegativeAdvance"] = bufio.ErrNegativeAdvance
+    Pkg["ErrNegativeCount"] = bufio.ErrNegativeCount
+    Pkg["ErrTooLong"] = bufio.ErrTooLong
+    Pkg["MaxScanTokenSize"] = bufio.MaxScanTokenSize
+    Pkg["NewReadWriter"] = bufio.NewReadWriter
+    Pkg["NewReader"] = bufio.NewReader
+    Pkg["NewReaderSize"] = bufio.NewReaderSize
+    Pkg["NewScanner"] = bufio.NewScanner
+    Pkg["NewWriter"] = bufio.NewWriter
+    Pkg["NewWriterSize"] = bufio.NewWriterSize
+    Ctor["ReadWriter"] = GijitShadow_NewStruct_ReadWriter
+    Ctor["Reader"] = GijitShadow_NewStruct_Reader
+    Pkg["ScanBytes"] = bufio.ScanBytes
+    Pkg["ScanLines"] = bufio.ScanLines
+    Pkg["ScanRunes"] = bufio.ScanRunes
+    Pkg["ScanWords"] = bufio.ScanWords
+    Ctor["Scanner"] = GijitShadow_NewStruct_Scanner
+    Ctor["Writer"] = GijitShadow_NewStruct_Writer
+
+}
+func GijitShadow_NewStruct_ReadWriter(src *bufio.ReadWriter) *bufio.ReadWriter {
+    if src == nil {
+	   return &bufio.ReadWriter{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_Reader(src *bufio.Reader) *bufio.Reader {
+    if src == nil {
+	   return &bufio.Reader{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_Scanner(src *bufio.Scanner) *bufio.Scanner {
+    if src == nil {
+	   return &bufio.Scanner{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_Writer(src *bufio.Writer) *bufio.Writer {
+    if src == nil {
+	   return &bufio.Writer{}
+    }
+    a := *src
+    return &a
+}
+
+
+
+ func InitLua() string {
+  return `
+__type__.bufio ={};
+
+-----------------
+-- struct ReadWriter
+-----------------
+
+__type__.bufio.ReadWriter = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "ReadWriter",
+ __str = "ReadWriter",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__bufio.ReadWriter(src)
+ end,
+};
+setmetatable(__type__.bufio.ReadWriter, __type__.bufio.ReadWriter);
+
+
+-----------------
+-- struct Reader
+-----------------
+
+__type__.bufio.Reader = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "Reader",
+ __str = "Reader",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__bufio.Reader(src)
+ end,
+};
+setmetatable(__type__.bufio.Reader, __type__.bufio.Reader);
+
+
+-----------------
+-- struct Scanner
+-----------------
+
+__type__.bufio.Scanner = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "Scanner",
+ __str = "Scanner",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__bufio.Scanner(src)
+ end,
+};
+setmetatable(__type__.bufio.Scanner, __type__.bufio.Scanner);
+
+
+-----------------
+-- struct Writer
+-----------------
+
+__type__.bufio.Writer = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "Writer",
+ __str = "Writer",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__bufio.Writer(src)
+ end,
+};
+setmetatable(__type__.bufio.Writer, __type__.bufio.Writer);
+
+
+`}
+
+func init() {
+	shadow.Register("bufio", Pkg, Ctor, InitLua)
+}
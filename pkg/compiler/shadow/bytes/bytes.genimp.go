@@ -1,6 +1,7 @@
 package shadow_bytes
 
 import "bytes"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -117,4 +118,8 @@ __type__.bytes.Reader = {
 setmetatable(__type__.bytes.Reader, __type__.bytes.Reader);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("bytes", Pkg, Ctor, InitLua)
+}
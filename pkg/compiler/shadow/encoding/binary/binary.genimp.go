@@ -1,13 +1,16 @@
 package shadow_binary
 
 import "encoding/binary"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
 
 func init() {
     Pkg["BigEndian"] = binary.BigEndian
     Pkg["ByteOrder"] = GijitShadow_InterfaceConvertTo2_ByteOrder
+    Adapter["ByteOrder"] = GijitShadow_NewAdapter_ByteOrder
     Pkg["LittleEndian"] = binary.LittleEndian
     Pkg["MaxVarintLen16"] = binary.MaxVarintLen16
     Pkg["MaxVarintLen32"] = binary.MaxVarintLen32
@@ -33,6 +36,50 @@ func GijitShadow_InterfaceConvertTo1_ByteOrder(x interface{}) binary.ByteOrder {
 }
 
 
+type GijitShadow_Adapter_ByteOrder struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ByteOrder) PutUint16(p0 []byte, p1 uint16) {
+	a.call("PutUint16", []interface{}{p0, p1}, []interface{}{})
+	return
+}
+
+func (a *GijitShadow_Adapter_ByteOrder) PutUint32(p0 []byte, p1 uint32) {
+	a.call("PutUint32", []interface{}{p0, p1}, []interface{}{})
+	return
+}
+
+func (a *GijitShadow_Adapter_ByteOrder) PutUint64(p0 []byte, p1 uint64) {
+	a.call("PutUint64", []interface{}{p0, p1}, []interface{}{})
+	return
+}
+
+func (a *GijitShadow_Adapter_ByteOrder) String() (r0 string) {
+	a.call("String", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_ByteOrder) Uint16(p0 []byte) (r0 uint16) {
+	a.call("Uint16", []interface{}{p0}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_ByteOrder) Uint32(p0 []byte) (r0 uint32) {
+	a.call("Uint32", []interface{}{p0}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_ByteOrder) Uint64(p0 []byte) (r0 uint64) {
+	a.call("Uint64", []interface{}{p0}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_ByteOrder(call shadow.Caller) binary.ByteOrder {
+	return &GijitShadow_Adapter_ByteOrder{call: call}
+}
+
+
 
  func InitLua() string {
   return `
@@ -72,4 +119,9 @@ __type__.binary.LittleEndian = {
 setmetatable(__type__.binary.LittleEndian, __type__.binary.LittleEndian);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("encoding/binary", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
@@ -1,15 +1,21 @@
 package shadow_encoding
 
 import "encoding"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
 
 func init() {
     Pkg["BinaryMarshaler"] = GijitShadow_InterfaceConvertTo2_BinaryMarshaler
+    Adapter["BinaryMarshaler"] = GijitShadow_NewAdapter_BinaryMarshaler
     Pkg["BinaryUnmarshaler"] = GijitShadow_InterfaceConvertTo2_BinaryUnmarshaler
+    Adapter["BinaryUnmarshaler"] = GijitShadow_NewAdapter_BinaryUnmarshaler
     Pkg["TextMarshaler"] = GijitShadow_InterfaceConvertTo2_TextMarshaler
+    Adapter["TextMarshaler"] = GijitShadow_NewAdapter_TextMarshaler
     Pkg["TextUnmarshaler"] = GijitShadow_InterfaceConvertTo2_TextUnmarshaler
+    Adapter["TextUnmarshaler"] = GijitShadow_NewAdapter_TextUnmarshaler
 
 }
 func GijitShadow_InterfaceConvertTo2_BinaryMarshaler(x interface{}) (y encoding.BinaryMarshaler, b bool) {
@@ -22,6 +28,20 @@ func GijitShadow_InterfaceConvertTo1_BinaryMarshaler(x interface{}) encoding.Bin
 }
 
 
+type GijitShadow_Adapter_BinaryMarshaler struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_BinaryMarshaler) MarshalBinary() (r0 []byte, r1 error) {
+	a.call("MarshalBinary", []interface{}{}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_BinaryMarshaler(call shadow.Caller) encoding.BinaryMarshaler {
+	return &GijitShadow_Adapter_BinaryMarshaler{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_BinaryUnmarshaler(x interface{}) (y encoding.BinaryUnmarshaler, b bool) {
 	y, b = x.(encoding.BinaryUnmarshaler)
 	return
@@ -32,6 +52,20 @@ func GijitShadow_InterfaceConvertTo1_BinaryUnmarshaler(x interface{}) encoding.B
 }
 
 
+type GijitShadow_Adapter_BinaryUnmarshaler struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_BinaryUnmarshaler) UnmarshalBinary(p0 []byte) (r0 error) {
+	a.call("UnmarshalBinary", []interface{}{p0}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_BinaryUnmarshaler(call shadow.Caller) encoding.BinaryUnmarshaler {
+	return &GijitShadow_Adapter_BinaryUnmarshaler{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_TextMarshaler(x interface{}) (y encoding.TextMarshaler, b bool) {
 	y, b = x.(encoding.TextMarshaler)
 	return
@@ -42,6 +76,20 @@ func GijitShadow_InterfaceConvertTo1_TextMarshaler(x interface{}) encoding.TextM
 }
 
 
+type GijitShadow_Adapter_TextMarshaler struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_TextMarshaler) MarshalText() (r0 []byte, r1 error) {
+	a.call("MarshalText", []interface{}{}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_TextMarshaler(call shadow.Caller) encoding.TextMarshaler {
+	return &GijitShadow_Adapter_TextMarshaler{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_TextUnmarshaler(x interface{}) (y encoding.TextUnmarshaler, b bool) {
 	y, b = x.(encoding.TextUnmarshaler)
 	return
@@ -52,9 +100,28 @@ func GijitShadow_InterfaceConvertTo1_TextUnmarshaler(x interface{}) encoding.Tex
 }
 
 
+type GijitShadow_Adapter_TextUnmarshaler struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_TextUnmarshaler) UnmarshalText(p0 []byte) (r0 error) {
+	a.call("UnmarshalText", []interface{}{p0}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_TextUnmarshaler(call shadow.Caller) encoding.TextUnmarshaler {
+	return &GijitShadow_Adapter_TextUnmarshaler{call: call}
+}
+
+
 
  func InitLua() string {
   return `
 __type__.encoding ={};
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("encoding", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
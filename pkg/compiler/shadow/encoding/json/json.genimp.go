@@ -0,0 +1,351 @@
+package shadow_json
+
+import "encoding/json"
+import "github.com/gijit/gi/pkg/compiler/shadow"
+
+var Pkg = make(map[string]interface{})
+var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
+
+func init() {
+    Pkg["Compact"] = json.Compact
+    Ctor["Decoder"] = GijitShadow_NewStruct_Decoder
+    Ctor["Encoder"] = GijitShadow_NewStruct_Encoder
+    Pkg["HTMLEscape"] = json.HTMLEscape
+    Pkg["Indent"] = json.Indent
+    Ctor["InvalidUTF8Error"] = GijitShadow_NewStruct_InvalidUTF8Error
+    Ctor["InvalidUnmarshalError"] = GijitShadow_NewStruct_InvalidUnmarshalError
+    Pkg["Marshal"] = json.Marshal
+    Pkg["MarshalIndent"] = json.MarshalIndent
+    Pkg["Marshaler"] = GijitShadow_InterfaceConvertTo2_Marshaler
+    Adapter["Marshaler"] = GijitShadow_NewAdapter_Marshaler
+    Ctor["MarshalerError"] = GijitShadow_NewStruct_MarshalerError
+    Pkg["NewDecoder"] = json.NewDecoder
+    Pkg["NewEncoder"] = json.NewEncoder
+    Ctor["SyntaxError"] = GijitShadow_NewStruct_SyntaxError
+    Pkg["Unmarshal"] = json.Unmarshal
+    Ctor["UnmarshalFieldError"] = GijitShadow_NewStruct_UnmarshalFieldError
+    Ctor["UnmarshalTypeError"] = GijitShadow_NewStruct_UnmarshalTypeError
+    Pkg["Unmarshaler"] = GijitShadow_InterfaceConvertTo2_Unmarshaler
+    Adapter["Unmarshaler"] = GijitShadow_NewAdapter_Unmarshaler
+    Ctor["UnsupportedTypeError"] = GijitShadow_NewStruct_UnsupportedTypeError
+    Ctor["UnsupportedValueError"] = GijitShadow_NewStruct_UnsupportedValueError
+    Pkg["Valid"] = json.Valid
+
+}
+func GijitShadow_InterfaceConvertTo2_Marshaler(x interface{}) (y json.Marshaler, b bool) {
+	y, b = x.(json.Marshaler)
+	return
+}
+
+func GijitShadow_InterfaceConvertTo1_Marshaler(x interface{}) json.Marshaler {
+	return x.(json.Marshaler)
+}
+
+
+type GijitShadow_Adapter_Marshaler struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Marshaler) MarshalJSON() (r0 []byte, r1 error) {
+	a.call("MarshalJSON", []interface{}{}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_Marshaler(call shadow.Caller) json.Marshaler {
+	return &GijitShadow_Adapter_Marshaler{call: call}
+}
+
+func GijitShadow_InterfaceConvertTo2_Unmarshaler(x interface{}) (y json.Unmarshaler, b bool) {
+	y, b = x.(json.Unmarshaler)
+	return
+}
+
+func GijitShadow_InterfaceConvertTo1_Unmarshaler(x interface{}) json.Unmarshaler {
+	return x.(json.Unmarshaler)
+}
+
+
+type GijitShadow_Adapter_Unmarshaler struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Unmarshaler) UnmarshalJSON(p0 []byte) (r0 error) {
+	a.call("UnmarshalJSON", []interface{}{p0}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_Unmarshaler(call shadow.Caller) json.Unmarshaler {
+	return &GijitShadow_Adapter_Unmarshaler{call: call}
+}
+
+func GijitShadow_NewStruct_Decoder(src *json.Decoder) *json.Decoder {
+    if src == nil {
+	   return &json.Decoder{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_Encoder(src *json.Encoder) *json.Encoder {
+    if src == nil {
+	   return &json.Encoder{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_InvalidUTF8Error(src *json.InvalidUTF8Error) *json.InvalidUTF8Error {
+    if src == nil {
+	   return &json.InvalidUTF8Error{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_InvalidUnmarshalError(src *json.InvalidUnmarshalError) *json.InvalidUnmarshalError {
+    if src == nil {
+	   return &json.InvalidUnmarshalError{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_MarshalerError(src *json.MarshalerError) *json.MarshalerError {
+    if src == nil {
+	   return &json.MarshalerError{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_SyntaxError(src *json.SyntaxError) *json.SyntaxError {
+    if src == nil {
+	   return &json.SyntaxError{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_UnmarshalFieldError(src *json.UnmarshalFieldError) *json.UnmarshalFieldError {
+    if src == nil {
+	   return &json.UnmarshalFieldError{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_UnmarshalTypeError(src *json.UnmarshalTypeError) *json.UnmarshalTypeError {
+    if src == nil {
+	   return &json.UnmarshalTypeError{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_UnsupportedTypeError(src *json.UnsupportedTypeError) *json.UnsupportedTypeError {
+    if src == nil {
+	   return &json.UnsupportedTypeError{}
+    }
+    a := *src
+    return &a
+}
+
+
+func GijitShadow_NewStruct_UnsupportedValueError(src *json.UnsupportedValueError) *json.UnsupportedValueError {
+    if src == nil {
+	   return &json.UnsupportedValueError{}
+    }
+    a := *src
+    return &a
+}
+
+
+
+ func InitLua() string {
+  return `
+__type__.json ={};
+
+-----------------
+-- struct Decoder
+-----------------
+
+__type__.json.Decoder = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "Decoder",
+ __str = "Decoder",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.Decoder(src)
+ end,
+};
+setmetatable(__type__.json.Decoder, __type__.json.Decoder);
+
+
+-----------------
+-- struct Encoder
+-----------------
+
+__type__.json.Encoder = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "Encoder",
+ __str = "Encoder",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.Encoder(src)
+ end,
+};
+setmetatable(__type__.json.Encoder, __type__.json.Encoder);
+
+
+-----------------
+-- struct InvalidUTF8Error
+-----------------
+
+__type__.json.InvalidUTF8Error = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "InvalidUTF8Error",
+ __str = "InvalidUTF8Error",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.InvalidUTF8Error(src)
+ end,
+};
+setmetatable(__type__.json.InvalidUTF8Error, __type__.json.InvalidUTF8Error);
+
+
+-----------------
+-- struct InvalidUnmarshalError
+-----------------
+
+__type__.json.InvalidUnmarshalError = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "InvalidUnmarshalError",
+ __str = "InvalidUnmarshalError",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.InvalidUnmarshalError(src)
+ end,
+};
+setmetatable(__type__.json.InvalidUnmarshalError, __type__.json.InvalidUnmarshalError);
+
+
+-----------------
+-- struct MarshalerError
+-----------------
+
+__type__.json.MarshalerError = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "MarshalerError",
+ __str = "MarshalerError",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.MarshalerError(src)
+ end,
+};
+setmetatable(__type__.json.MarshalerError, __type__.json.MarshalerError);
+
+
+-----------------
+-- struct SyntaxError
+-----------------
+
+__type__.json.SyntaxError = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "SyntaxError",
+ __str = "SyntaxError",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.SyntaxError(src)
+ end,
+};
+setmetatable(__type__.json.SyntaxError, __type__.json.SyntaxError);
+
+
+-----------------
+-- struct UnmarshalFieldError
+-----------------
+
+__type__.json.UnmarshalFieldError = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "UnmarshalFieldError",
+ __str = "UnmarshalFieldError",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.UnmarshalFieldError(src)
+ end,
+};
+setmetatable(__type__.json.UnmarshalFieldError, __type__.json.UnmarshalFieldError);
+
+
+-----------------
+-- struct UnmarshalTypeError
+-----------------
+
+__type__.json.UnmarshalTypeError = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "UnmarshalTypeError",
+ __str = "UnmarshalTypeError",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.UnmarshalTypeError(src)
+ end,
+};
+setmetatable(__type__.json.UnmarshalTypeError, __type__.json.UnmarshalTypeError);
+
+
+-----------------
+-- struct UnsupportedTypeError
+-----------------
+
+__type__.json.UnsupportedTypeError = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "UnsupportedTypeError",
+ __str = "UnsupportedTypeError",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.UnsupportedTypeError(src)
+ end,
+};
+setmetatable(__type__.json.UnsupportedTypeError, __type__.json.UnsupportedTypeError);
+
+
+-----------------
+-- struct UnsupportedValueError
+-----------------
+
+__type__.json.UnsupportedValueError = {
+ id=0,
+ __name = "native_Go_struct_type_wrapper",
+ __native_type = "UnsupportedValueError",
+ __str = "UnsupportedValueError",
+ exported = true,
+ __call = function(t, src)
+   return __ctor__json.UnsupportedValueError(src)
+ end,
+};
+setmetatable(__type__.json.UnsupportedValueError, __type__.json.UnsupportedValueError);
+
+`}
+
+func init() {
+	shadow.Register("encoding/json", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
@@ -1,6 +1,7 @@
 package shadow_errors
 
 import "errors"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -14,4 +15,8 @@ func init() {
   return `
 __type__.errors ={};
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("errors", Pkg, Ctor, InitLua)
+}
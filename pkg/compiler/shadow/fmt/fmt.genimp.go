@@ -1,13 +1,16 @@
 package shadow_fmt
 
 import "fmt"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
 
 func init() {
     Pkg["Errorf"] = fmt.Errorf
     Pkg["Formatter"] = GijitShadow_InterfaceConvertTo2_Formatter
+    Adapter["Formatter"] = GijitShadow_NewAdapter_Formatter
     Pkg["Fprint"] = fmt.Fprint
     Pkg["Fprintf"] = fmt.Fprintf
     Pkg["Fprintln"] = fmt.Fprintln
@@ -15,14 +18,17 @@ func init() {
     Pkg["Fscanf"] = fmt.Fscanf
     Pkg["Fscanln"] = fmt.Fscanln
     Pkg["GoStringer"] = GijitShadow_InterfaceConvertTo2_GoStringer
+    Adapter["GoStringer"] = GijitShadow_NewAdapter_GoStringer
     Pkg["Print"] = fmt.Print
     Pkg["Printf"] = fmt.Printf
     Pkg["Println"] = fmt.Println
     Pkg["Scan"] = fmt.Scan
     Pkg["ScanState"] = GijitShadow_InterfaceConvertTo2_ScanState
+    Adapter["ScanState"] = GijitShadow_NewAdapter_ScanState
     Pkg["Scanf"] = fmt.Scanf
     Pkg["Scanln"] = fmt.Scanln
     Pkg["Scanner"] = GijitShadow_InterfaceConvertTo2_Scanner
+    Adapter["Scanner"] = GijitShadow_NewAdapter_Scanner
     Pkg["Sprint"] = fmt.Sprint
     Pkg["Sprintf"] = fmt.Sprintf
     Pkg["Sprintln"] = fmt.Sprintln
@@ -30,7 +36,9 @@ func init() {
     Pkg["Sscanf"] = fmt.Sscanf
     Pkg["Sscanln"] = fmt.Sscanln
     Pkg["State"] = GijitShadow_InterfaceConvertTo2_State
+    Adapter["State"] = GijitShadow_NewAdapter_State
     Pkg["Stringer"] = GijitShadow_InterfaceConvertTo2_Stringer
+    Adapter["Stringer"] = GijitShadow_NewAdapter_Stringer
 
 }
 func GijitShadow_InterfaceConvertTo2_Formatter(x interface{}) (y fmt.Formatter, b bool) {
@@ -43,6 +51,20 @@ func GijitShadow_InterfaceConvertTo1_Formatter(x interface{}) fmt.Formatter {
 }
 
 
+type GijitShadow_Adapter_Formatter struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Formatter) Format(p0 fmt.State, p1 rune) {
+	a.call("Format", []interface{}{p0, p1}, []interface{}{})
+	return
+}
+
+func GijitShadow_NewAdapter_Formatter(call shadow.Caller) fmt.Formatter {
+	return &GijitShadow_Adapter_Formatter{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_GoStringer(x interface{}) (y fmt.GoStringer, b bool) {
 	y, b = x.(fmt.GoStringer)
 	return
@@ -53,6 +75,20 @@ func GijitShadow_InterfaceConvertTo1_GoStringer(x interface{}) fmt.GoStringer {
 }
 
 
+type GijitShadow_Adapter_GoStringer struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_GoStringer) GoString() (r0 string) {
+	a.call("GoString", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_GoStringer(call shadow.Caller) fmt.GoStringer {
+	return &GijitShadow_Adapter_GoStringer{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ScanState(x interface{}) (y fmt.ScanState, b bool) {
 	y, b = x.(fmt.ScanState)
 	return
@@ -63,6 +99,45 @@ func GijitShadow_InterfaceConvertTo1_ScanState(x interface{}) fmt.ScanState {
 }
 
 
+type GijitShadow_Adapter_ScanState struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ScanState) Read(p0 []byte) (r0 int, r1 error) {
+	a.call("Read", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) ReadRune() (r0 rune, r1 int, r2 error) {
+	a.call("ReadRune", []interface{}{}, []interface{}{&r0, &r1, &r2})
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) SkipSpace() {
+	a.call("SkipSpace", []interface{}{}, []interface{}{})
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) Token(p0 bool, p1 func(rune) bool) (r0 []byte, r1 error) {
+	a.call("Token", []interface{}{p0, p1}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) UnreadRune() (r0 error) {
+	a.call("UnreadRune", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) Width() (r0 int, r1 bool) {
+	a.call("Width", []interface{}{}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ScanState(call shadow.Caller) fmt.ScanState {
+	return &GijitShadow_Adapter_ScanState{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Scanner(x interface{}) (y fmt.Scanner, b bool) {
 	y, b = x.(fmt.Scanner)
 	return
@@ -73,6 +148,20 @@ func GijitShadow_InterfaceConvertTo1_Scanner(x interface{}) fmt.Scanner {
 }
 
 
+type GijitShadow_Adapter_Scanner struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Scanner) Scan(p0 fmt.ScanState, p1 rune) (r0 error) {
+	a.call("Scan", []interface{}{p0, p1}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_Scanner(call shadow.Caller) fmt.Scanner {
+	return &GijitShadow_Adapter_Scanner{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_State(x interface{}) (y fmt.State, b bool) {
 	y, b = x.(fmt.State)
 	return
@@ -83,6 +172,35 @@ func GijitShadow_InterfaceConvertTo1_State(x interface{}) fmt.State {
 }
 
 
+type GijitShadow_Adapter_State struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_State) Flag(p0 int) (r0 bool) {
+	a.call("Flag", []interface{}{p0}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_State) Precision() (r0 int, r1 bool) {
+	a.call("Precision", []interface{}{}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_State) Width() (r0 int, r1 bool) {
+	a.call("Width", []interface{}{}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_State) Write(p0 []byte) (r0 int, r1 error) {
+	a.call("Write", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_State(call shadow.Caller) fmt.State {
+	return &GijitShadow_Adapter_State{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Stringer(x interface{}) (y fmt.Stringer, b bool) {
 	y, b = x.(fmt.Stringer)
 	return
@@ -93,9 +211,28 @@ func GijitShadow_InterfaceConvertTo1_Stringer(x interface{}) fmt.Stringer {
 }
 
 
+type GijitShadow_Adapter_Stringer struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Stringer) String() (r0 string) {
+	a.call("String", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_Stringer(call shadow.Caller) fmt.Stringer {
+	return &GijitShadow_Adapter_Stringer{call: call}
+}
+
+
 
  func InitLua() string {
   return `
 __type__.fmt ={};
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("fmt", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
@@ -1,6 +1,7 @@
 package shadow_blas
 
 import "gonum.org/v1/gonum/blas"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -174,3 +175,7 @@ func GijitShadow_InterfaceConvertTo1_Float64Level3(x interface{}) blas.Float64Le
 func GijitShadow_NewStruct_SrotmParams() *blas.SrotmParams {
 	return &blas.SrotmParams{}
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/blas", Pkg, nil, nil)
+}
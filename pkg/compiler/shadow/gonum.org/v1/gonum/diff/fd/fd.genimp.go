@@ -1,6 +1,7 @@
 package shadow_fd
 
 import "gonum.org/v1/gonum/diff/fd"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -34,3 +35,7 @@ func GijitShadow_NewStruct_Point() *fd.Point {
 func GijitShadow_NewStruct_Settings() *fd.Settings {
 	return &fd.Settings{}
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/diff/fd", Pkg, nil, nil)
+}
@@ -1,6 +1,7 @@
 package shadow_floats
 
 import "gonum.org/v1/gonum/floats"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -55,3 +56,7 @@ func init() {
 	Pkg["Within"] = floats.Within
 
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/floats", Pkg, nil, nil)
+}
@@ -1,6 +1,7 @@
 package shadow_graph
 
 import "gonum.org/v1/gonum/graph"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -390,3 +391,7 @@ func GijitShadow_InterfaceConvertTo2_WeightedUndirectedMultigraph(x interface{})
 func GijitShadow_InterfaceConvertTo1_WeightedUndirectedMultigraph(x interface{}) graph.WeightedUndirectedMultigraph {
 	return x.(graph.WeightedUndirectedMultigraph)
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/graph", Pkg, nil, nil)
+}
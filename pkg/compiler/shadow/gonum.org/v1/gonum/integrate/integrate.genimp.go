@@ -1,6 +1,7 @@
 package shadow_integrate
 
 import "gonum.org/v1/gonum/integrate"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -8,3 +9,7 @@ func init() {
 	Pkg["Trapezoidal"] = integrate.Trapezoidal
 
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/integrate", Pkg, nil, nil)
+}
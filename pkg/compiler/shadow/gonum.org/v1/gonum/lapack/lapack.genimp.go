@@ -1,6 +1,7 @@
 package shadow_lapack
 
 import "gonum.org/v1/gonum/lapack"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -27,3 +28,7 @@ func GijitShadow_InterfaceConvertTo2_Float64(x interface{}) (y lapack.Float64, b
 func GijitShadow_InterfaceConvertTo1_Float64(x interface{}) lapack.Float64 {
 	return x.(lapack.Float64)
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/lapack", Pkg, nil, nil)
+}
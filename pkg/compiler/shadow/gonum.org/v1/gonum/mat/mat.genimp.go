@@ -1,6 +1,7 @@
 package shadow_mat
 
 import "gonum.org/v1/gonum/mat"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -486,3 +487,7 @@ func GijitShadow_InterfaceConvertTo2_Vector(x interface{}) (y mat.Vector, b bool
 func GijitShadow_InterfaceConvertTo1_Vector(x interface{}) mat.Vector {
 	return x.(mat.Vector)
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/mat", Pkg, nil, nil)
+}
@@ -1,6 +1,7 @@
 package shadow_optimize
 
 import "gonum.org/v1/gonum/optimize"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -226,3 +227,7 @@ func GijitShadow_InterfaceConvertTo2_StepSizer(x interface{}) (y optimize.StepSi
 func GijitShadow_InterfaceConvertTo1_StepSizer(x interface{}) optimize.StepSizer {
 	return x.(optimize.StepSizer)
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/optimize", Pkg, nil, nil)
+}
@@ -1,6 +1,7 @@
 package shadow_stat
 
 import "gonum.org/v1/gonum/stat"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -54,3 +55,7 @@ func GijitShadow_NewStruct_CC() *stat.CC {
 func GijitShadow_NewStruct_PC() *stat.PC {
 	return &stat.PC{}
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/stat", Pkg, nil, nil)
+}
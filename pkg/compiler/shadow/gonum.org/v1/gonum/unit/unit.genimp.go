@@ -1,6 +1,7 @@
 package shadow_unit
 
 import "gonum.org/v1/gonum/unit"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 
@@ -44,3 +45,7 @@ func GijitShadow_InterfaceConvertTo2_Uniter(x interface{}) (y unit.Uniter, b boo
 func GijitShadow_InterfaceConvertTo1_Uniter(x interface{}) unit.Uniter {
 	return x.(unit.Uniter)
 }
+
+func init() {
+	shadow.Register("gonum.org/v1/gonum/unit", Pkg, nil, nil)
+}
@@ -1,15 +1,21 @@
 package shadow_io
 
 import "io"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
 
 func init() {
     Pkg["ByteReader"] = GijitShadow_InterfaceConvertTo2_ByteReader
+    Adapter["ByteReader"] = GijitShadow_NewAdapter_ByteReader
     Pkg["ByteScanner"] = GijitShadow_InterfaceConvertTo2_ByteScanner
+    Adapter["ByteScanner"] = GijitShadow_NewAdapter_ByteScanner
     Pkg["ByteWriter"] = GijitShadow_InterfaceConvertTo2_ByteWriter
+    Adapter["ByteWriter"] = GijitShadow_NewAdapter_ByteWriter
     Pkg["Closer"] = GijitShadow_InterfaceConvertTo2_Closer
+    Adapter["Closer"] = GijitShadow_NewAdapter_Closer
     Pkg["Copy"] = io.Copy
     Pkg["CopyBuffer"] = io.CopyBuffer
     Pkg["CopyN"] = io.CopyN
@@ -29,28 +35,44 @@ func init() {
     Ctor["PipeWriter"] = GijitShadow_NewStruct_PipeWriter
     Pkg["ReadAtLeast"] = io.ReadAtLeast
     Pkg["ReadCloser"] = GijitShadow_InterfaceConvertTo2_ReadCloser
+    Adapter["ReadCloser"] = GijitShadow_NewAdapter_ReadCloser
     Pkg["ReadFull"] = io.ReadFull
     Pkg["ReadSeeker"] = GijitShadow_InterfaceConvertTo2_ReadSeeker
+    Adapter["ReadSeeker"] = GijitShadow_NewAdapter_ReadSeeker
     Pkg["ReadWriteCloser"] = GijitShadow_InterfaceConvertTo2_ReadWriteCloser
+    Adapter["ReadWriteCloser"] = GijitShadow_NewAdapter_ReadWriteCloser
     Pkg["ReadWriteSeeker"] = GijitShadow_InterfaceConvertTo2_ReadWriteSeeker
+    Adapter["ReadWriteSeeker"] = GijitShadow_NewAdapter_ReadWriteSeeker
     Pkg["ReadWriter"] = GijitShadow_InterfaceConvertTo2_ReadWriter
+    Adapter["ReadWriter"] = GijitShadow_NewAdapter_ReadWriter
     Pkg["Reader"] = GijitShadow_InterfaceConvertTo2_Reader
+    Adapter["Reader"] = GijitShadow_NewAdapter_Reader
     Pkg["ReaderAt"] = GijitShadow_InterfaceConvertTo2_ReaderAt
+    Adapter["ReaderAt"] = GijitShadow_NewAdapter_ReaderAt
     Pkg["ReaderFrom"] = GijitShadow_InterfaceConvertTo2_ReaderFrom
+    Adapter["ReaderFrom"] = GijitShadow_NewAdapter_ReaderFrom
     Pkg["RuneReader"] = GijitShadow_InterfaceConvertTo2_RuneReader
+    Adapter["RuneReader"] = GijitShadow_NewAdapter_RuneReader
     Pkg["RuneScanner"] = GijitShadow_InterfaceConvertTo2_RuneScanner
+    Adapter["RuneScanner"] = GijitShadow_NewAdapter_RuneScanner
     Ctor["SectionReader"] = GijitShadow_NewStruct_SectionReader
     Pkg["SeekCurrent"] = io.SeekCurrent
     Pkg["SeekEnd"] = io.SeekEnd
     Pkg["SeekStart"] = io.SeekStart
     Pkg["Seeker"] = GijitShadow_InterfaceConvertTo2_Seeker
+    Adapter["Seeker"] = GijitShadow_NewAdapter_Seeker
     Pkg["TeeReader"] = io.TeeReader
     Pkg["WriteCloser"] = GijitShadow_InterfaceConvertTo2_WriteCloser
+    Adapter["WriteCloser"] = GijitShadow_NewAdapter_WriteCloser
     Pkg["WriteSeeker"] = GijitShadow_InterfaceConvertTo2_WriteSeeker
+    Adapter["WriteSeeker"] = GijitShadow_NewAdapter_WriteSeeker
     Pkg["WriteString"] = io.WriteString
     Pkg["Writer"] = GijitShadow_InterfaceConvertTo2_Writer
+    Adapter["Writer"] = GijitShadow_NewAdapter_Writer
     Pkg["WriterAt"] = GijitShadow_InterfaceConvertTo2_WriterAt
+    Adapter["WriterAt"] = GijitShadow_NewAdapter_WriterAt
     Pkg["WriterTo"] = GijitShadow_InterfaceConvertTo2_WriterTo
+    Adapter["WriterTo"] = GijitShadow_NewAdapter_WriterTo
 
 }
 func GijitShadow_InterfaceConvertTo2_ByteReader(x interface{}) (y io.ByteReader, b bool) {
@@ -63,6 +85,20 @@ func GijitShadow_InterfaceConvertTo1_ByteReader(x interface{}) io.ByteReader {
 }
 
 
+type GijitShadow_Adapter_ByteReader struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ByteReader) ReadByte() (r0 byte, r1 error) {
+	a.call("ReadByte", []interface{}{}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ByteReader(call shadow.Caller) io.ByteReader {
+	return &GijitShadow_Adapter_ByteReader{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ByteScanner(x interface{}) (y io.ByteScanner, b bool) {
 	y, b = x.(io.ByteScanner)
 	return
@@ -73,6 +109,25 @@ func GijitShadow_InterfaceConvertTo1_ByteScanner(x interface{}) io.ByteScanner {
 }
 
 
+type GijitShadow_Adapter_ByteScanner struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ByteScanner) ReadByte() (r0 byte, r1 error) {
+	a.call("ReadByte", []interface{}{}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_ByteScanner) UnreadByte() (r0 error) {
+	a.call("UnreadByte", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_ByteScanner(call shadow.Caller) io.ByteScanner {
+	return &GijitShadow_Adapter_ByteScanner{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ByteWriter(x interface{}) (y io.ByteWriter, b bool) {
 	y, b = x.(io.ByteWriter)
 	return
@@ -83,6 +138,20 @@ func GijitShadow_InterfaceConvertTo1_ByteWriter(x interface{}) io.ByteWriter {
 }
 
 
+type GijitShadow_Adapter_ByteWriter struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ByteWriter) WriteByte(p0 byte) (r0 error) {
+	a.call("WriteByte", []interface{}{p0}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_ByteWriter(call shadow.Caller) io.ByteWriter {
+	return &GijitShadow_Adapter_ByteWriter{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Closer(x interface{}) (y io.Closer, b bool) {
 	y, b = x.(io.Closer)
 	return
@@ -93,6 +162,20 @@ func GijitShadow_InterfaceConvertTo1_Closer(x interface{}) io.Closer {
 }
 
 
+type GijitShadow_Adapter_Closer struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Closer) Close() (r0 error) {
+	a.call("Close", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_Closer(call shadow.Caller) io.Closer {
+	return &GijitShadow_Adapter_Closer{call: call}
+}
+
+
 func GijitShadow_NewStruct_LimitedReader(src *io.LimitedReader) *io.LimitedReader {
     if src == nil {
 	   return &io.LimitedReader{}
@@ -130,6 +213,25 @@ func GijitShadow_InterfaceConvertTo1_ReadCloser(x interface{}) io.ReadCloser {
 }
 
 
+type GijitShadow_Adapter_ReadCloser struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ReadCloser) Close() (r0 error) {
+	a.call("Close", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadCloser) Read(p0 []byte) (r0 int, r1 error) {
+	a.call("Read", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ReadCloser(call shadow.Caller) io.ReadCloser {
+	return &GijitShadow_Adapter_ReadCloser{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReadSeeker(x interface{}) (y io.ReadSeeker, b bool) {
 	y, b = x.(io.ReadSeeker)
 	return
@@ -140,6 +242,25 @@ func GijitShadow_InterfaceConvertTo1_ReadSeeker(x interface{}) io.ReadSeeker {
 }
 
 
+type GijitShadow_Adapter_ReadSeeker struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ReadSeeker) Read(p0 []byte) (r0 int, r1 error) {
+	a.call("Read", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadSeeker) Seek(p0 int64, p1 int) (r0 int64, r1 error) {
+	a.call("Seek", []interface{}{p0, p1}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ReadSeeker(call shadow.Caller) io.ReadSeeker {
+	return &GijitShadow_Adapter_ReadSeeker{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReadWriteCloser(x interface{}) (y io.ReadWriteCloser, b bool) {
 	y, b = x.(io.ReadWriteCloser)
 	return
@@ -150,6 +271,30 @@ func GijitShadow_InterfaceConvertTo1_ReadWriteCloser(x interface{}) io.ReadWrite
 }
 
 
+type GijitShadow_Adapter_ReadWriteCloser struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ReadWriteCloser) Close() (r0 error) {
+	a.call("Close", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriteCloser) Read(p0 []byte) (r0 int, r1 error) {
+	a.call("Read", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriteCloser) Write(p0 []byte) (r0 int, r1 error) {
+	a.call("Write", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ReadWriteCloser(call shadow.Caller) io.ReadWriteCloser {
+	return &GijitShadow_Adapter_ReadWriteCloser{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReadWriteSeeker(x interface{}) (y io.ReadWriteSeeker, b bool) {
 	y, b = x.(io.ReadWriteSeeker)
 	return
@@ -160,6 +305,30 @@ func GijitShadow_InterfaceConvertTo1_ReadWriteSeeker(x interface{}) io.ReadWrite
 }
 
 
+type GijitShadow_Adapter_ReadWriteSeeker struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ReadWriteSeeker) Read(p0 []byte) (r0 int, r1 error) {
+	a.call("Read", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriteSeeker) Seek(p0 int64, p1 int) (r0 int64, r1 error) {
+	a.call("Seek", []interface{}{p0, p1}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriteSeeker) Write(p0 []byte) (r0 int, r1 error) {
+	a.call("Write", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ReadWriteSeeker(call shadow.Caller) io.ReadWriteSeeker {
+	return &GijitShadow_Adapter_ReadWriteSeeker{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReadWriter(x interface{}) (y io.ReadWriter, b bool) {
 	y, b = x.(io.ReadWriter)
 	return
@@ -170,6 +339,25 @@ func GijitShadow_InterfaceConvertTo1_ReadWriter(x interface{}) io.ReadWriter {
 }
 
 
+type GijitShadow_Adapter_ReadWriter struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ReadWriter) Read(p0 []byte) (r0 int, r1 error) {
+	a.call("Read", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriter) Write(p0 []byte) (r0 int, r1 error) {
+	a.call("Write", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ReadWriter(call shadow.Caller) io.ReadWriter {
+	return &GijitShadow_Adapter_ReadWriter{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Reader(x interface{}) (y io.Reader, b bool) {
 	y, b = x.(io.Reader)
 	return
@@ -180,6 +368,20 @@ func GijitShadow_InterfaceConvertTo1_Reader(x interface{}) io.Reader {
 }
 
 
+type GijitShadow_Adapter_Reader struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Reader) Read(p0 []byte) (r0 int, r1 error) {
+	a.call("Read", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_Reader(call shadow.Caller) io.Reader {
+	return &GijitShadow_Adapter_Reader{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReaderAt(x interface{}) (y io.ReaderAt, b bool) {
 	y, b = x.(io.ReaderAt)
 	return
@@ -190,6 +392,20 @@ func GijitShadow_InterfaceConvertTo1_ReaderAt(x interface{}) io.ReaderAt {
 }
 
 
+type GijitShadow_Adapter_ReaderAt struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ReaderAt) ReadAt(p0 []byte, p1 int64) (r0 int, r1 error) {
+	a.call("ReadAt", []interface{}{p0, p1}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ReaderAt(call shadow.Caller) io.ReaderAt {
+	return &GijitShadow_Adapter_ReaderAt{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReaderFrom(x interface{}) (y io.ReaderFrom, b bool) {
 	y, b = x.(io.ReaderFrom)
 	return
@@ -200,6 +416,20 @@ func GijitShadow_InterfaceConvertTo1_ReaderFrom(x interface{}) io.ReaderFrom {
 }
 
 
+type GijitShadow_Adapter_ReaderFrom struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_ReaderFrom) ReadFrom(p0 io.Reader) (r0 int64, r1 error) {
+	a.call("ReadFrom", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_ReaderFrom(call shadow.Caller) io.ReaderFrom {
+	return &GijitShadow_Adapter_ReaderFrom{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_RuneReader(x interface{}) (y io.RuneReader, b bool) {
 	y, b = x.(io.RuneReader)
 	return
@@ -210,6 +440,20 @@ func GijitShadow_InterfaceConvertTo1_RuneReader(x interface{}) io.RuneReader {
 }
 
 
+type GijitShadow_Adapter_RuneReader struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_RuneReader) ReadRune() (r0 rune, r1 int, r2 error) {
+	a.call("ReadRune", []interface{}{}, []interface{}{&r0, &r1, &r2})
+	return
+}
+
+func GijitShadow_NewAdapter_RuneReader(call shadow.Caller) io.RuneReader {
+	return &GijitShadow_Adapter_RuneReader{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_RuneScanner(x interface{}) (y io.RuneScanner, b bool) {
 	y, b = x.(io.RuneScanner)
 	return
@@ -220,6 +464,25 @@ func GijitShadow_InterfaceConvertTo1_RuneScanner(x interface{}) io.RuneScanner {
 }
 
 
+type GijitShadow_Adapter_RuneScanner struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_RuneScanner) ReadRune() (r0 rune, r1 int, r2 error) {
+	a.call("ReadRune", []interface{}{}, []interface{}{&r0, &r1, &r2})
+	return
+}
+
+func (a *GijitShadow_Adapter_RuneScanner) UnreadRune() (r0 error) {
+	a.call("UnreadRune", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_RuneScanner(call shadow.Caller) io.RuneScanner {
+	return &GijitShadow_Adapter_RuneScanner{call: call}
+}
+
+
 func GijitShadow_NewStruct_SectionReader(src *io.SectionReader) *io.SectionReader {
     if src == nil {
 	   return &io.SectionReader{}
@@ -239,6 +502,20 @@ func GijitShadow_InterfaceConvertTo1_Seeker(x interface{}) io.Seeker {
 }
 
 
+type GijitShadow_Adapter_Seeker struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Seeker) Seek(p0 int64, p1 int) (r0 int64, r1 error) {
+	a.call("Seek", []interface{}{p0, p1}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_Seeker(call shadow.Caller) io.Seeker {
+	return &GijitShadow_Adapter_Seeker{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_WriteCloser(x interface{}) (y io.WriteCloser, b bool) {
 	y, b = x.(io.WriteCloser)
 	return
@@ -249,6 +526,25 @@ func GijitShadow_InterfaceConvertTo1_WriteCloser(x interface{}) io.WriteCloser {
 }
 
 
+type GijitShadow_Adapter_WriteCloser struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_WriteCloser) Close() (r0 error) {
+	a.call("Close", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_WriteCloser) Write(p0 []byte) (r0 int, r1 error) {
+	a.call("Write", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_WriteCloser(call shadow.Caller) io.WriteCloser {
+	return &GijitShadow_Adapter_WriteCloser{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_WriteSeeker(x interface{}) (y io.WriteSeeker, b bool) {
 	y, b = x.(io.WriteSeeker)
 	return
@@ -259,6 +555,25 @@ func GijitShadow_InterfaceConvertTo1_WriteSeeker(x interface{}) io.WriteSeeker {
 }
 
 
+type GijitShadow_Adapter_WriteSeeker struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_WriteSeeker) Seek(p0 int64, p1 int) (r0 int64, r1 error) {
+	a.call("Seek", []interface{}{p0, p1}, []interface{}{&r0, &r1})
+	return
+}
+
+func (a *GijitShadow_Adapter_WriteSeeker) Write(p0 []byte) (r0 int, r1 error) {
+	a.call("Write", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_WriteSeeker(call shadow.Caller) io.WriteSeeker {
+	return &GijitShadow_Adapter_WriteSeeker{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Writer(x interface{}) (y io.Writer, b bool) {
 	y, b = x.(io.Writer)
 	return
@@ -269,6 +584,20 @@ func GijitShadow_InterfaceConvertTo1_Writer(x interface{}) io.Writer {
 }
 
 
+type GijitShadow_Adapter_Writer struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Writer) Write(p0 []byte) (r0 int, r1 error) {
+	a.call("Write", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_Writer(call shadow.Caller) io.Writer {
+	return &GijitShadow_Adapter_Writer{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_WriterAt(x interface{}) (y io.WriterAt, b bool) {
 	y, b = x.(io.WriterAt)
 	return
@@ -279,6 +608,20 @@ func GijitShadow_InterfaceConvertTo1_WriterAt(x interface{}) io.WriterAt {
 }
 
 
+type GijitShadow_Adapter_WriterAt struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_WriterAt) WriteAt(p0 []byte, p1 int64) (r0 int, r1 error) {
+	a.call("WriteAt", []interface{}{p0, p1}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_WriterAt(call shadow.Caller) io.WriterAt {
+	return &GijitShadow_Adapter_WriterAt{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_WriterTo(x interface{}) (y io.WriterTo, b bool) {
 	y, b = x.(io.WriterTo)
 	return
@@ -289,6 +632,20 @@ func GijitShadow_InterfaceConvertTo1_WriterTo(x interface{}) io.WriterTo {
 }
 
 
+type GijitShadow_Adapter_WriterTo struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_WriterTo) WriteTo(p0 io.Writer) (r0 int64, r1 error) {
+	a.call("WriteTo", []interface{}{p0}, []interface{}{&r0, &r1})
+	return
+}
+
+func GijitShadow_NewAdapter_WriterTo(call shadow.Caller) io.WriterTo {
+	return &GijitShadow_Adapter_WriterTo{call: call}
+}
+
+
 
  func InitLua() string {
   return `
@@ -362,4 +719,9 @@ __type__.io.SectionReader = {
 setmetatable(__type__.io.SectionReader, __type__.io.SectionReader);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("io", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
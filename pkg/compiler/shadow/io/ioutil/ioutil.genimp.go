@@ -1,6 +1,7 @@
 package shadow_ioutil
 
 import "io/ioutil"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -21,4 +22,8 @@ func init() {
   return `
 __type__.ioutil ={};
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("io/ioutil", Pkg, Ctor, InitLua)
+}
@@ -1,6 +1,7 @@
 package shadow_math
 
 import "math"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -108,3 +109,7 @@ __type__.math ={};
 
 `
 }
+
+func init() {
+	shadow.Register("math", Pkg, Ctor, InitLua)
+}
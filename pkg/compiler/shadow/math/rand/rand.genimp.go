@@ -1,9 +1,11 @@
 package shadow_rand
 
 import "math/rand"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
 
 func init() {
     Pkg["ExpFloat64"] = rand.ExpFloat64
@@ -25,7 +27,9 @@ func init() {
     Pkg["Seed"] = rand.Seed
     Pkg["Shuffle"] = rand.Shuffle
     Pkg["Source"] = GijitShadow_InterfaceConvertTo2_Source
+    Adapter["Source"] = GijitShadow_NewAdapter_Source
     Pkg["Source64"] = GijitShadow_InterfaceConvertTo2_Source64
+    Adapter["Source64"] = GijitShadow_NewAdapter_Source64
     Pkg["Uint32"] = rand.Uint32
     Pkg["Uint64"] = rand.Uint64
     Ctor["Zipf"] = GijitShadow_NewStruct_Zipf
@@ -50,6 +54,25 @@ func GijitShadow_InterfaceConvertTo1_Source(x interface{}) rand.Source {
 }
 
 
+type GijitShadow_Adapter_Source struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Source) Int63() (r0 int64) {
+	a.call("Int63", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_Source) Seed(p0 int64) {
+	a.call("Seed", []interface{}{p0}, []interface{}{})
+	return
+}
+
+func GijitShadow_NewAdapter_Source(call shadow.Caller) rand.Source {
+	return &GijitShadow_Adapter_Source{call: call}
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Source64(x interface{}) (y rand.Source64, b bool) {
 	y, b = x.(rand.Source64)
 	return
@@ -60,6 +83,30 @@ func GijitShadow_InterfaceConvertTo1_Source64(x interface{}) rand.Source64 {
 }
 
 
+type GijitShadow_Adapter_Source64 struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Source64) Int63() (r0 int64) {
+	a.call("Int63", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_Source64) Seed(p0 int64) {
+	a.call("Seed", []interface{}{p0}, []interface{}{})
+	return
+}
+
+func (a *GijitShadow_Adapter_Source64) Uint64() (r0 uint64) {
+	a.call("Uint64", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_Source64(call shadow.Caller) rand.Source64 {
+	return &GijitShadow_Adapter_Source64{call: call}
+}
+
+
 func GijitShadow_NewStruct_Zipf(src *rand.Zipf) *rand.Zipf {
     if src == nil {
 	   return &rand.Zipf{}
@@ -108,4 +155,9 @@ __type__.rand.Zipf = {
 setmetatable(__type__.rand.Zipf, __type__.rand.Zipf);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("math/rand", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
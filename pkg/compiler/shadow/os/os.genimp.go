@@ -1,9 +1,13 @@
 package shadow_os
 
+import "io/fs"
 import "os"
+import "time"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
 
 func init() {
     Pkg["Args"] = os.Args
@@ -27,6 +31,7 @@ func init() {
     Pkg["ExpandEnv"] = os.ExpandEnv
     Ctor["File"] = GijitShadow_NewStruct_File
     Pkg["FileInfo"] = GijitShadow_InterfaceConvertTo2_FileInfo
+    Adapter["FileInfo"] = GijitShadow_NewAdapter_FileInfo
     Pkg["FindProcess"] = os.FindProcess
     Pkg["Getegid"] = os.Getegid
     Pkg["Getenv"] = os.Getenv
@@ -82,6 +87,7 @@ func init() {
     Pkg["SameFile"] = os.SameFile
     Pkg["Setenv"] = os.Setenv
     Pkg["Signal"] = GijitShadow_InterfaceConvertTo2_Signal
+    Adapter["Signal"] = GijitShadow_NewAdapter_Signal
     Pkg["StartProcess"] = os.StartProcess
     Pkg["Stat"] = os.Stat
     Pkg["Stderr"] = os.Stderr
@@ -113,6 +119,45 @@ func GijitShadow_InterfaceConvertTo1_FileInfo(x interface{}) os.FileInfo {
 }
 
 
+type GijitShadow_Adapter_FileInfo struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_FileInfo) IsDir() (r0 bool) {
+	a.call("IsDir", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_FileInfo) ModTime() (r0 time.Time) {
+	a.call("ModTime", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_FileInfo) Mode() (r0 fs.FileMode) {
+	a.call("Mode", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_FileInfo) Name() (r0 string) {
+	a.call("Name", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_FileInfo) Size() (r0 int64) {
+	a.call("Size", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_FileInfo) Sys() (r0 any) {
+	a.call("Sys", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_FileInfo(call shadow.Caller) os.FileInfo {
+	return &GijitShadow_Adapter_FileInfo{call: call}
+}
+
+
 func GijitShadow_NewStruct_LinkError(src *os.LinkError) *os.LinkError {
     if src == nil {
 	   return &os.LinkError{}
@@ -168,6 +213,25 @@ func GijitShadow_InterfaceConvertTo1_Signal(x interface{}) os.Signal {
 }
 
 
+type GijitShadow_Adapter_Signal struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Signal) Signal() {
+	a.call("Signal", []interface{}{}, []interface{}{})
+	return
+}
+
+func (a *GijitShadow_Adapter_Signal) String() (r0 string) {
+	a.call("String", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func GijitShadow_NewAdapter_Signal(call shadow.Caller) os.Signal {
+	return &GijitShadow_Adapter_Signal{call: call}
+}
+
+
 func GijitShadow_NewStruct_SyscallError(src *os.SyscallError) *os.SyscallError {
     if src == nil {
 	   return &os.SyscallError{}
@@ -301,4 +365,9 @@ __type__.os.SyscallError = {
 setmetatable(__type__.os.SyscallError, __type__.os.SyscallError);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("os", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
@@ -1,6 +1,7 @@
 package shadow_reflect
 
 import "reflect"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -237,4 +238,8 @@ __type__.reflect.ValueError = {
 setmetatable(__type__.reflect.ValueError, __type__.reflect.ValueError);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("reflect", Pkg, Ctor, InitLua)
+}
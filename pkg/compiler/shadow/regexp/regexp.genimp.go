@@ -1,6 +1,7 @@
 package shadow_regexp
 
 import "regexp"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -48,4 +49,8 @@ __type__.regexp.Regexp = {
 setmetatable(__type__.regexp.Regexp, __type__.regexp.Regexp);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("regexp", Pkg, Ctor, InitLua)
+}
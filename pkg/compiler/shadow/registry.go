@@ -0,0 +1,83 @@
+// Package shadow keeps the registry of binary (pre-compiled)
+// Go packages that can be imported at the gi REPL.
+//
+// Each package written by gen-gijit-shadow-import registers
+// itself here from an init() function. So to make another
+// binary package importable, generate its shadow package and
+// link it into a custom `gi` build with a blank import; the
+// compiler needs no changes.
+package shadow
+
+import (
+	"path"
+	"sort"
+	"sync"
+)
+
+// Package describes one shadowed binary package.
+type Package struct {
+	// Path is the import path, e.g. "math/rand".
+	Path string
+
+	// Name is the Lua global the package is bound
+	// to, e.g. "rand". Its struct constructors are
+	// bound to "__ctor__" + Name.
+	Name string
+
+	// Pkg maps exported names to functions, variables
+	// and interface converters; Ctor maps struct names
+	// to their copy constructors. Ctor may be nil.
+	Pkg  map[string]interface{}
+	Ctor map[string]interface{}
+
+	// InitLua returns the Lua that declares the
+	// package's types in __type__. It may be nil.
+	InitLua func() string
+}
+
+var (
+	mut      sync.Mutex
+	registry = make(map[string]*Package)
+)
+
+// Register makes the binary package at importPath available
+// to `import` at the REPL. A later Register of the same path
+// replaces the earlier one.
+func Register(importPath string, pkg, ctor map[string]interface{}, initLua func() string) {
+	if importPath == "" {
+		panic("shadow.Register: empty import path")
+	}
+	if pkg == nil {
+		panic("shadow.Register: nil Pkg map for '" + importPath + "'")
+	}
+	mut.Lock()
+	registry[importPath] = &Package{
+		Path:    importPath,
+		Name:    path.Base(importPath),
+		Pkg:     pkg,
+		Ctor:    ctor,
+		InitLua: initLua,
+	}
+	mut.Unlock()
+}
+
+// Lookup returns the registered package for importPath.
+func Lookup(importPath string) (*Package, bool) {
+	mut.Lock()
+	defer mut.Unlock()
+	p, ok := registry[importPath]
+	return p, ok
+}
+
+// Paths returns the sorted import paths of
+// all registered packages.
+func Paths() []string {
+	mut.Lock()
+	defer mut.Unlock()
+	paths := make([]string, 0, len(registry))
+	for k := range registry {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+	return paths
+}
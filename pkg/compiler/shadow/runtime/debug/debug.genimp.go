@@ -1,6 +1,7 @@
 package shadow_debug
 
 import "runtime/debug"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -50,4 +51,8 @@ __type__.debug.GCStats = {
 setmetatable(__type__.debug.GCStats, __type__.debug.GCStats);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("runtime/debug", Pkg, Ctor, InitLua)
+}
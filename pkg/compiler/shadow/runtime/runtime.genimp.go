@@ -1,9 +1,11 @@
 package shadow_runtime
 
 import "runtime"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
 
 func init() {
     Pkg["BlockProfile"] = runtime.BlockProfile
@@ -15,6 +17,7 @@ func init() {
     Pkg["CallersFrames"] = runtime.CallersFrames
     Pkg["Compiler"] = runtime.Compiler
     Pkg["Error"] = GijitShadow_InterfaceConvertTo2_Error
+    Adapter["Error"] = GijitShadow_NewAdapter_Error
     Ctor["Frame"] = GijitShadow_NewStruct_Frame
     Ctor["Frames"] = GijitShadow_NewStruct_Frames
     Ctor["Func"] = GijitShadow_NewStruct_Func
@@ -73,6 +76,25 @@ func GijitShadow_InterfaceConvertTo1_Error(x interface{}) runtime.Error {
 }
 
 
+type GijitShadow_Adapter_Error struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Error) Error() (r0 string) {
+	a.call("Error", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_Error) RuntimeError() {
+	a.call("RuntimeError", []interface{}{}, []interface{}{})
+	return
+}
+
+func GijitShadow_NewAdapter_Error(call shadow.Caller) runtime.Error {
+	return &GijitShadow_Adapter_Error{call: call}
+}
+
+
 func GijitShadow_NewStruct_Frame(src *runtime.Frame) *runtime.Frame {
     if src == nil {
 	   return &runtime.Frame{}
@@ -277,4 +299,9 @@ __type__.runtime.TypeAssertionError = {
 setmetatable(__type__.runtime.TypeAssertionError, __type__.runtime.TypeAssertionError);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("runtime", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
@@ -0,0 +1,77 @@
+package shadow_sort
+
+import "sort"
+import "github.com/gijit/gi/pkg/compiler/shadow"
+
+var Pkg = make(map[string]interface{})
+var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
+
+func init() {
+    Pkg["Find"] = sort.Find
+    Pkg["Float64s"] = sort.Float64s
+    Pkg["Float64sAreSorted"] = sort.Float64sAreSorted
+    Pkg["Interface"] = GijitShadow_InterfaceConvertTo2_Interface
+    Adapter["Interface"] = GijitShadow_NewAdapter_Interface
+    Pkg["Ints"] = sort.Ints
+    Pkg["IntsAreSorted"] = sort.IntsAreSorted
+    Pkg["IsSorted"] = sort.IsSorted
+    Pkg["Reverse"] = sort.Reverse
+    Pkg["Search"] = sort.Search
+    Pkg["SearchFloat64s"] = sort.SearchFloat64s
+    Pkg["SearchInts"] = sort.SearchInts
+    Pkg["SearchStrings"] = sort.SearchStrings
+    Pkg["Slice"] = sort.Slice
+    Pkg["SliceIsSorted"] = sort.SliceIsSorted
+    Pkg["SliceStable"] = sort.SliceStable
+    Pkg["Sort"] = sort.Sort
+    Pkg["Stable"] = sort.Stable
+    Pkg["Strings"] = sort.Strings
+    Pkg["StringsAreSorted"] = sort.StringsAreSorted
+
+}
+func GijitShadow_InterfaceConvertTo2_Interface(x interface{}) (y sort.Interface, b bool) {
+	y, b = x.(sort.Interface)
+	return
+}
+
+func GijitShadow_InterfaceConvertTo1_Interface(x interface{}) sort.Interface {
+	return x.(sort.Interface)
+}
+
+
+type GijitShadow_Adapter_Interface struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Interface) Len() (r0 int) {
+	a.call("Len", []interface{}{}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_Interface) Less(p0 int, p1 int) (r0 bool) {
+	a.call("Less", []interface{}{p0, p1}, []interface{}{&r0})
+	return
+}
+
+func (a *GijitShadow_Adapter_Interface) Swap(p0 int, p1 int) {
+	a.call("Swap", []interface{}{p0, p1}, []interface{}{})
+	return
+}
+
+func GijitShadow_NewAdapter_Interface(call shadow.Caller) sort.Interface {
+	return &GijitShadow_Adapter_Interface{call: call}
+}
+
+
+
+ func InitLua() string {
+  return `
+__type__.sort ={};
+
+`}
+
+func init() {
+	shadow.Register("sort", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
@@ -1,6 +1,7 @@
 package shadow_strconv
 
 import "strconv"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -74,4 +75,8 @@ __type__.strconv.NumError = {
 setmetatable(__type__.strconv.NumError, __type__.strconv.NumError);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("strconv", Pkg, Ctor, InitLua)
+}
@@ -1,6 +1,7 @@
 package shadow_strings
 
 import "strings"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -139,4 +140,8 @@ __type__.strings.Replacer = {
 setmetatable(__type__.strings.Replacer, __type__.strings.Replacer);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("strings", Pkg, Ctor, InitLua)
+}
@@ -1,6 +1,7 @@
 package shadow_atomic
 
 import "sync/atomic"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -69,4 +70,8 @@ __type__.atomic.Value = {
 setmetatable(__type__.atomic.Value, __type__.atomic.Value);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("sync/atomic", Pkg, Ctor, InitLua)
+}
@@ -1,13 +1,16 @@
 package shadow_sync
 
 import "sync"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
+var Adapter = make(map[string]interface{})
 
 func init() {
     Ctor["Cond"] = GijitShadow_NewStruct_Cond
     Pkg["Locker"] = GijitShadow_InterfaceConvertTo2_Locker
+    Adapter["Locker"] = GijitShadow_NewAdapter_Locker
     Ctor["Map"] = GijitShadow_NewStruct_Map
     Ctor["Mutex"] = GijitShadow_NewStruct_Mutex
     Pkg["NewCond"] = sync.NewCond
@@ -36,6 +39,25 @@ func GijitShadow_InterfaceConvertTo1_Locker(x interface{}) sync.Locker {
 }
 
 
+type GijitShadow_Adapter_Locker struct {
+	call shadow.Caller
+}
+
+func (a *GijitShadow_Adapter_Locker) Lock() {
+	a.call("Lock", []interface{}{}, []interface{}{})
+	return
+}
+
+func (a *GijitShadow_Adapter_Locker) Unlock() {
+	a.call("Unlock", []interface{}{}, []interface{}{})
+	return
+}
+
+func GijitShadow_NewAdapter_Locker(call shadow.Caller) sync.Locker {
+	return &GijitShadow_Adapter_Locker{call: call}
+}
+
+
 func GijitShadow_NewStruct_Map(src *sync.Map) *sync.Map {
     if src == nil {
 	   return &sync.Map{}
@@ -214,4 +236,9 @@ __type__.sync.WaitGroup = {
 setmetatable(__type__.sync.WaitGroup, __type__.sync.WaitGroup);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("sync", Pkg, Ctor, InitLua)
+	shadow.RegisterAdapters(Adapter)
+}
@@ -1,6 +1,7 @@
 package shadow_time
 
 import "time"
+import "github.com/gijit/gi/pkg/compiler/shadow"
 
 var Pkg = make(map[string]interface{})
 var Ctor = make(map[string]interface{})
@@ -182,4 +183,8 @@ __type__.time.Timer = {
 setmetatable(__type__.time.Timer, __type__.time.Timer);
 
 
-`}
\ No newline at end of file
+`}
+
+func init() {
+	shadow.Register("time", Pkg, Ctor, InitLua)
+}
@@ -737,6 +737,17 @@ func (c *funcContext) translateBranchingStmt(caseClauses []*ast.CaseClause, defa
 	c.PrintCond(!flatten, " end "+suffix, fmt.Sprintf(" elseif __s ==  %d then  --[[ statements.go:737 --]] ", endCase))
 }
 
+// printBackEdge emits the call made on each loop
+// iteration: __preempt under -preempt, else __backEdge,
+// which lets an interrupt stop even a compiled loop.
+func (c *funcContext) printBackEdge() {
+	if c.p.preempt {
+		c.Printf("__preempt();")
+		return
+	}
+	c.Printf("__backEdge();")
+}
+
 func (c *funcContext) translateLoopingStmt(cond func() string, body *ast.BlockStmt, bodyPrefix, post func(), label *types.Label, flatten bool) {
 	prevFlowData := c.flowDatas[nil]
 	data := &flowData{
@@ -767,6 +778,9 @@ func (c *funcContext) translateLoopingStmt(cond func() string, body *ast.BlockSt
 	c.Printf("while (true) do")
 	//c.PrintCond(!flatten, "while (true) do", fmt.Sprintf("case %d:", data.beginCase))
 	c.Indent(func() {
+		// a chance to yield, or to be interrupted, on
+		// every back-edge; see __preempt in prelude/chan.lua.
+		c.printBackEdge()
 		condStr := cond()
 		if condStr != "true" {
 			c.Printf("if (not (%s)) then break; end", condStr)
@@ -797,18 +811,6 @@ func (c *funcContext) translateLoopingStmt(cond func() string, body *ast.BlockSt
 	//c.PrintCond(!flatten, " end ", fmt.Sprintf("__s = %d; goto %s; case %d:", data.beginCase, data.endCase, gotoLabel))
 }
 
-func (c *funcContext) getKeyCast(key ast.Expr) string {
-	keyType := c.p.TypeOf(key)
-	switch b := keyType.(type) {
-	case *types.Basic:
-		switch b.Kind() {
-		case types.Int64, types.Int:
-			return "__atoll"
-		}
-	}
-	return ""
-}
-
 // jea: modified copy of the above translateLoopingStmt
 func (c *funcContext) translateForRangeStmt(s *ast.RangeStmt, body *ast.BlockStmt, bodyPrefix, post func(), label *types.Label, flatten bool) {
 
@@ -836,17 +838,13 @@ func (c *funcContext) translateForRangeStmt(s *ast.RangeStmt, body *ast.BlockStm
 
 	exprType := c.p.TypeOf(s.X)
 	isMap := false
-	switch exprType.(type) {
+	switch exprType.Underlying().(type) {
 	case *types.Map:
 		isMap = true
 	}
 	ipairs := false
 	target := c.translateExpr(s.X, nil)
 
-	// keycast reverses the key -> string that was done to store in the map.
-	// string -> int, use __atoll()
-	keycast := c.getKeyCast(s.Key)
-
 	// jea TODO: if the range is not a := range, then leave off
 	// declaring the two locals in the outer do scope.
 	isDefine := s.Tok == token.DEFINE // vs token.ASSIGN
@@ -870,7 +868,11 @@ func (c *funcContext) translateForRangeStmt(s *ast.RangeStmt, body *ast.BlockStm
 	privateI := c.gensym("i") // must be float64 for ipairs
 	privateV := c.gensym("v")
 	if isMap {
-		c.Printf("do %[5]s\n for %[6]s, %[7]s in pairs(%[3]s) do \n %[1]s = %[4]s(%[6]s);\n %[2]s = %[7]s;", key, value, target, keycast, addMe, privateI, privateV)
+		// __mapRange gives back the Go keys, not the
+		// strings they are stored under, after a cursor
+		// that is never nil, so that a nil key is seen.
+		privateC := c.gensym("c")
+		c.Printf("do %[4]s\n for %[7]s, %[5]s, %[6]s in __mapRange(%[3]s) do \n %[1]s = %[5]s;\n %[2]s = %[6]s;", key, value, target, addMe, privateI, privateV, privateC)
 
 	} else {
 		// slice or array
@@ -888,6 +890,7 @@ func (c *funcContext) translateForRangeStmt(s *ast.RangeStmt, body *ast.BlockStm
 		}
 		c.Printf("%s", s)
 	}
+	c.printBackEdge()
 	prevEV := c.p.escapingVars
 	c.handleEscapingVars(body)
 
@@ -987,16 +990,10 @@ func (c *funcContext) translateAssign(lhs, rhs ast.Expr, define bool) string {
 			if typesutil.IsJsObject(c.p.TypeOf(l.Index)) {
 				c.p.errList = append(c.p.errList, types.Error{Fset: c.p.fileSet, Pos: l.Index.Pos(), Msg: "cannot use js.Object as map key"})
 			}
-			// jea: map assignment in lua, need the quotes to convert map keys to strings.
-			dq := ``
-			switch bas := t.Key().(type) {
-			case *types.Basic:
-				dq = `"`
-				if bas.Kind() == types.String {
-					dq = ``
-				}
-			}
-			return fmt.Sprintf(`%[1]s[  %[2]s%[3]s%[2]s  ] = %[4]s;`, c.translateExpr(l.X, nil), dq, c.translateImplicitConversionWithCloning(l.Index, t.Key()), c.translateImplicitConversionWithCloning(rhs, t.Elem()))
+			// jea: map assignment in lua. The map's keyFor turns the
+			// key into a string, so struct, array and interface keys
+			// compare by value.
+			return fmt.Sprintf(`%s('set', %s, %s);`, c.translateExpr(l.X, nil), c.translateImplicitConversionWithCloning(l.Index, t.Key()), c.translateImplicitConversionWithCloning(rhs, t.Elem()))
 			// jea replace next 2 lines with the above
 			//keyVar := c.newVariable("_key")
 			//return fmt.Sprintf(`%s = %s; (%s || __throwRuntimeError("assignment to entry in nil map"))[%s.keyFor(%s)] = { k: %s, v: %s };`, keyVar, c.translateImplicitConversionWithCloning(l.Index, t.Key()), c.translateExpr(l.X), c.typeName(0, t.Key()), keyVar, keyVar, c.translateImplicitConversionWithCloning(rhs, t.Elem()))
@@ -0,0 +1,90 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test1900SyncForGoroutines(t *testing.T) {
+
+	cv.Convey(`sync.Mutex, RWMutex, WaitGroup, Once and Cond should park goroutines on the scheduler instead of blocking the whole vm`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		code := `
+import (
+	"sync"
+	"time"
+)
+var mu sync.Mutex
+count := 0
+wg := &sync.WaitGroup{}
+for i := 0; i < 5; i++ {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mu.Lock()
+		c := count
+		time.Sleep(time.Millisecond)
+		count = c + 1
+		mu.Unlock()
+	}()
+}
+wg.Wait()
+
+var once sync.Once
+onceN := 0
+for i := 0; i < 3; i++ {
+	once.Do(func() { onceN++ })
+}
+
+var rw sync.RWMutex
+rw.RLock()
+rw.RLock()
+writeWhileReading := rw.TryLock()
+rw.RUnlock()
+rw.RUnlock()
+writeAfter := rw.TryLock()
+rw.Unlock()
+
+type queue struct {
+	mu    sync.Mutex
+	items []int
+}
+q := &queue{}
+cond := sync.NewCond(&q.mu)
+got := 0
+done := make(chan bool)
+go func() {
+	q.mu.Lock()
+	for len(q.items) == 0 {
+		cond.Wait()
+	}
+	got = q.items[0]
+	q.mu.Unlock()
+	done <- true
+}()
+time.Sleep(time.Millisecond)
+q.mu.Lock()
+q.items = append(q.items, 42)
+cond.Signal()
+q.mu.Unlock()
+<-done
+`
+		translation, err := inc.Tr([]byte(code))
+		panicOn(err)
+		LuaRunAndReport(vm, string(translation))
+
+		// with the lock held across each Sleep, no
+		// increment is lost.
+		LuaMustInt64(vm, "count", 5)
+		LuaMustInt64(vm, "onceN", 1)
+		LuaMustBool(vm, "writeWhileReading", false)
+		LuaMustBool(vm, "writeAfter", true)
+		LuaMustInt64(vm, "got", 42)
+	})
+}
@@ -2,6 +2,8 @@ package compiler
 
 import (
 	"bytes"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	//"github.com/gijit/gi/pkg/verb"
@@ -91,14 +93,12 @@ func Test923TimeStruct(t *testing.T) {
 	})
 }
 
-/* work in progress
 func Test922TimeoutsInSelect(t *testing.T) {
 
 	cv.Convey(`channel timeouts in a select statement`, t, func() {
 
 		code := `import "time"`
 		code2 := `
-ch := make(chan int)
 toCount:= 0
 go func() {
     for {
@@ -124,10 +124,76 @@ time.Sleep(5 * time.Millisecond*100)
 
 		translation2 := inc.trMust([]byte(code2))
 		LuaRunAndReport(vm, string(translation2))
-		LuaMustInt(vm, "toCount", 3)
+		LuaMustInt64(vm, "toCount", 3)
 
 		cv.So(true, cv.ShouldBeTrue)
 
 	})
 }
-*/
+
+func Test924TimerStopAndTicker(t *testing.T) {
+
+	cv.Convey(`a stopped time.NewTimer should never fire, and time.NewTicker should tick repeatedly`, t, func() {
+
+		code := `import "time"`
+		code2 := `
+tmr := time.NewTimer(time.Millisecond*50)
+stopped := tmr.Stop()
+which := 0
+select {
+   case <-tmr.C:
+      which = 1
+   case <-time.After(time.Millisecond*100):
+      which = 2
+}
+ticks := 0
+tkr := time.NewTicker(time.Millisecond*20)
+for i := 0; i < 5; i++ {
+   <-tkr.C
+   ticks++
+}
+tkr.Stop()
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation := inc.trMust([]byte(code))
+		LuaRunAndReport(vm, string(translation))
+
+		translation2 := inc.trMust([]byte(code2))
+		LuaRunAndReport(vm, string(translation2))
+		LuaMustBool(vm, "stopped", true)
+		LuaMustInt64(vm, "which", 2)
+		LuaMustInt64(vm, "ticks", 5)
+
+		cv.So(true, cv.ShouldBeTrue)
+
+	})
+}
+
+func Test925StaticPreludeIsCurrent(t *testing.T) {
+
+	cv.Convey(`the prelude compiled into prelude_static.go should match prelude/*.lua; else regenerate it with cmd/gen_static_prelude`, t, func() {
+
+		names, err := filepath.Glob("prelude/*.lua")
+		panicOn(err)
+		cv.So(len(names), cv.ShouldBeGreaterThan, 0)
+		var stale []string
+		for _, name := range names {
+			onDisk, err := ioutil.ReadFile(name)
+			panicOn(err)
+			var static []byte
+			f, err := preludeFiles.Open(filepath.Base(name))
+			if err == nil {
+				static, err = ioutil.ReadAll(f)
+				f.Close()
+			}
+			if err != nil || !bytes.Equal(static, onDisk) {
+				stale = append(stale, name)
+			}
+		}
+		cv.So(stale, cv.ShouldBeEmpty)
+	})
+}
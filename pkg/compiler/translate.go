@@ -40,6 +40,26 @@ type IncrState struct {
 	minify   bool
 	PrintAST bool
 
+	// NoAnsPrint leaves the value of a bare expression
+	// in __gijit_ans without printing it. The embedded
+	// Interpreter fetches it from there instead.
+	NoAnsPrint bool
+
+	// retranslating is set while redef.go re-translates
+	// the dependents of a redefined declaration.
+	retranslating bool
+
+	// for linemap.go: the funcs in the latest input, and
+	// the Go line of each line of its translation.
+	goFuncs   []goFunc
+	lastLines []goLine
+
+	// txn undoes the latest input if its Lua fails,
+	// see txn.go. undo holds those of the inputs
+	// before it, for :undo.
+	txn  *incrTxn
+	undo []*incrTxn
+
 	// default to no import caching
 	//AllowImportCaching bool
 
@@ -74,6 +94,7 @@ func NewIncrState(lvm *LuaVm, cfg *GIConfig) *IncrState {
 	importContext := &ImportContext{
 		Packages: make(map[string]*types.Package),
 		Import:   ic.CompileTimeGiImportFunc,
+		Preempt:  cfg.Preempt,
 	}
 
 	key := "main"
@@ -155,6 +176,16 @@ func (tr *IncrState) Tr(src []byte) (by []byte, err error) {
 
 func (tr *IncrState) TrWithPrepend(src []byte, prependOk bool) (by []byte, err error) {
 
+	// runs after the recover below has set err.
+	txn := tr.beginTxn()
+	defer func() {
+		if err != nil {
+			tr.rollback(txn)
+		} else {
+			tr.endTxn(txn)
+		}
+	}()
+
 	defer func() {
 		r := recover()
 		if r != nil {
@@ -163,6 +194,12 @@ func (tr *IncrState) TrWithPrepend(src []byte, prependOk bool) (by []byte, err e
 		}
 	}()
 
+	if !tr.retranslating {
+		tr.goFuncs = nil
+		tr.lastLines = nil
+		tr.txn = nil
+	}
+
 	// detect the leading '=' and turn it into
 	// __gijit_ans :=
 	var didPrepend, shouldPrepend bool
@@ -212,6 +249,7 @@ func (tr *IncrState) TrWithPrepend(src []byte, prependOk bool) (by []byte, err e
 	}
 
 	files := []*ast.File{file}
+	txn.files = files
 	pp("file='%#v'", file)
 	pp("file.Name='%#v'", file.Name)
 	file.Name = &ast.Ident{
@@ -226,9 +264,13 @@ func (tr *IncrState) TrWithPrepend(src []byte, prependOk bool) (by []byte, err e
 		panic(msg)
 		return nil, fmt.Errorf(msg)
 	}
+	tr.goFuncs = append(tr.goFuncs, collectGoFuncs(file, tr.CurPkg.pack.Name)...)
+
 	depth := 0
-	tr.CurPkg.Arch, err = IncrementallyCompile(tr.CurPkg.Arch, tr.CurPkg.pack.ImportPath, files, tr.CurPkg.fileSet, tr.CurPkg.importContext, tr.minify, depth)
+	arch, err := IncrementallyCompile(tr.CurPkg.Arch, tr.CurPkg.pack.ImportPath, files, tr.CurPkg.fileSet, tr.CurPkg.importContext, tr.minify, depth)
 	panicOn(err)
+	tr.CurPkg.Arch = arch
+	txn.translated(arch)
 	//pp("archive = '%#v'", tr.CurPkg.Arch)
 	//pp("len(tr.CurPkg.Arch.Declarations)= '%v'", len(tr.CurPkg.Arch.Declarations))
 	//pp("len(tr.CurPkg.Arch.NewCode)= '%v'", len(tr.CurPkg.Arch.NewCodeText))
@@ -242,11 +284,23 @@ func (tr *IncrState) TrWithPrepend(src []byte, prependOk bool) (by []byte, err e
 	}
 	tr.CurPkg.Arch.NewCodeText = nil
 
-	return res.Bytes(), nil
+	// if src redefined anything, bring along
+	// what was translated against the old definition.
+	res.Write(tr.retranslateDependents())
+
+	if tr.retranslating {
+		// the outermost TrWithPrepend makes the line map.
+		return res.Bytes(), nil
+	}
+	lua, poses := stripLineMarkers(res.Bytes())
+	tr.lastLines = goLinesFor(tr.CurPkg.fileSet, poses, tr.goFuncs)
+	tr.txn = txn
+	return lua, nil
 }
 
 var gijitAnsPrefix = []byte("__gijit_ans := []interface{}{")
 var gijitAnsSuffix = []byte("}\n __gijit_printQuoted(__gijit_ans...);")
+var gijitAnsSuffixNoPrint = []byte("}\n")
 
 // at the beginning of src, transform a first '='[^=] into
 // "__gijit_ans := "
@@ -260,13 +314,17 @@ func (tr *IncrState) prependAns(src []byte) (ret []byte, didPrepend bool) {
 	trimmed := bytes.TrimFunc(src, unicode.IsSpace)
 	n := len(leftTrimmed)
 	leftdiff := nsrc - n
+	suffix := gijitAnsSuffix
+	if tr.NoAnsPrint {
+		suffix = gijitAnsSuffixNoPrint
+	}
 	if tr.cfg.CalculatorMode {
 		middle := removeTrailingSemicolon(trimmed[leftdiff:])
-		return append(gijitAnsPrefix, append(middle, gijitAnsSuffix...)...), true
+		return append(gijitAnsPrefix, append(middle, suffix...)...), true
 	}
 	if n > 1 && leftTrimmed[0] == '=' && leftTrimmed[1] != '=' {
 		middle := removeTrailingSemicolon(trimmed[leftdiff+1:])
-		return append(gijitAnsPrefix, append(middle, gijitAnsSuffix...)...), true
+		return append(gijitAnsPrefix, append(middle, suffix...)...), true
 	}
 	return src, false
 }
@@ -0,0 +1,243 @@
+package compiler
+
+// txn.go: each input at the prompt is atomic.
+//
+// Translating an input declares its names with the type
+// checker, and records them in the Archive, before any of
+// its Lua runs. If the translation then fails part way, or
+// the Lua fails when run, the checker would go on believing
+// in variables and types that the vm never defined, and
+// later inputs would fail mysteriously. So TrWithPrepend
+// records the old value of each name, checker entry and
+// cached source the input changes, and puts them back if
+// translation fails, and beginRun and endRun do the same
+// for a run that fails, restoring too the Lua globals the
+// input was about to define. The transactions of the inputs
+// that ran cleanly are kept for :undo, see undo.go.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// importedCode is package code an input took from the
+// NewCodeText of an imported package's archive.
+type importedCode struct {
+	arc  *Archive
+	code [][]byte
+}
+
+// incrTxn is what to put back if an input fails.
+type incrTxn struct {
+	arch      *Archive // nil before the first input.
+	files     []*ast.File
+	check     *types.CheckerSnapshot
+	src       *srcJournal
+	seq       int
+	redefined []string
+	imported  []importedCode
+
+	// once translated, the globals and the __type__
+	// entries its Lua will set.
+	vars []string
+	typs []string
+}
+
+// srcJournal keeps the old FuncSrcCache and declGraph
+// entries an input replaces, the first time each
+// changes, as the CheckerSnapshot does for the checker.
+type srcJournal struct {
+	funcSrc map[string]*string  // nil if there was none.
+	decls   map[string]*topDecl // nil if there was none.
+}
+
+func (g *declGraph) recordDecl(key string) {
+	for _, j := range g.journals {
+		if _, ok := j.decls[key]; !ok {
+			j.decls[key] = g.decls[key]
+		}
+	}
+}
+
+func (g *declGraph) recordFuncSrc(cache map[string]string, name string) {
+	for _, j := range g.journals {
+		if _, ok := j.funcSrc[name]; !ok {
+			var old *string
+			if src, ok := cache[name]; ok {
+				old = &src
+			}
+			j.funcSrc[name] = old
+		}
+	}
+}
+
+// done stops j recording.
+func (g *declGraph) done(j *srcJournal) {
+	for i, x := range g.journals {
+		if x == j {
+			g.journals = append(g.journals[:i], g.journals[i+1:]...)
+			return
+		}
+	}
+}
+
+func (tr *IncrState) beginTxn() *incrTxn {
+	a := tr.CurPkg.Arch
+	txn := &incrTxn{arch: a}
+	if a == nil {
+		return txn
+	}
+	txn.check = a.Check.Snapshot()
+	txn.src = &srcJournal{
+		funcSrc: make(map[string]*string),
+		decls:   make(map[string]*topDecl),
+	}
+	g := a.DeclGraph
+	g.journals = append(g.journals, txn.src)
+	txn.seq = g.seq
+	txn.redefined = g.redefined
+	return txn
+}
+
+// endTxn stops txn recording once its input has
+// translated; rollback can still put it back.
+func (tr *IncrState) endTxn(txn *incrTxn) {
+	if a := txn.arch; a != nil {
+		a.Check.Done(txn.check)
+		a.DeclGraph.done(txn.src)
+	}
+}
+
+// translated records what the input's Lua will define,
+// once TrWithPrepend has translated it.
+func (txn *incrTxn) translated(a *Archive) {
+	txn.imported = a.ImportedCode
+	if txn.arch == nil {
+		// everything is new, bar the prelude funcs
+		// addPreludeToNewPkg declares.
+		for _, name := range a.Pkg.Scope().Names() {
+			if obj := a.Pkg.Scope().Lookup(name); obj.Pos().IsValid() {
+				txn.declare(obj)
+			}
+		}
+		return
+	}
+	for _, obj := range a.Check.Declared(txn.check) {
+		txn.declare(obj)
+	}
+}
+
+func (txn *incrTxn) declare(obj types.Object) {
+	switch obj.(type) {
+	case *types.Var, *types.Func:
+		txn.vars = append(txn.vars, obj.Name())
+	case *types.TypeName:
+		txn.typs = append(txn.typs, obj.Name())
+	}
+}
+
+// rollback restores the type checker and archive.
+func (tr *IncrState) rollback(txn *incrTxn) {
+	for _, imp := range txn.imported {
+		imp.arc.NewCodeText = imp.code
+	}
+	if txn.arch == nil {
+		// the next input starts afresh.
+		tr.CurPkg.Arch = nil
+		return
+	}
+	a := txn.arch
+	tr.CurPkg.Arch = a
+	a.Check.Restore(txn.check)
+	forgetInfo(a.TypesInfo, txn.files)
+	g := a.DeclGraph
+	g.done(txn.src)
+	for name, src := range txn.src.funcSrc {
+		if src == nil {
+			delete(a.FuncSrcCache, name)
+		} else {
+			a.FuncSrcCache[name] = *src
+		}
+	}
+	for key, td := range txn.src.decls {
+		if td == nil {
+			delete(g.decls, key)
+		} else {
+			g.decls[key] = td
+		}
+	}
+	g.seq = txn.seq
+	g.redefined = txn.redefined
+	a.NewCodeText = nil
+	a.ImportedCode = nil
+}
+
+// forgetInfo drops what the checker recorded about files.
+func forgetInfo(info *types.Info, files []*ast.File) {
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+			switch x := n.(type) {
+			case *ast.Ident:
+				delete(info.Defs, x)
+				delete(info.Uses, x)
+			case *ast.SelectorExpr:
+				delete(info.Selections, x)
+			}
+			if e, ok := n.(ast.Expr); ok {
+				delete(info.Types, e)
+			}
+			delete(info.Implicits, n)
+			delete(info.Scopes, n)
+			return true
+		})
+	}
+}
+
+// beginRun is called before the Lua of the latest input
+// is run on lvm, to save the globals it will set. The vm
+// keeps one save for each transaction in tr.undo, plus
+// this one.
+func (tr *IncrState) beginRun(lvm *LuaVm) {
+	txn := tr.txn
+	if txn == nil {
+		return
+	}
+	err := LuaRun(lvm, fmt.Sprintf("__gijitTxSave(%s, %s, %d);", luaStringList(txn.vars), luaStringList(txn.typs), undoDepth+1), false)
+	panicOn(err)
+}
+
+// endRun is called after the Lua of the latest input has
+// run. If it failed, the input is undone; if not, it can
+// be undone later.
+func (tr *IncrState) endRun(lvm *LuaVm, failed bool) {
+	txn := tr.txn
+	tr.txn = nil
+	if txn == nil {
+		return
+	}
+	if !failed {
+		tr.undo = append(tr.undo, txn)
+		if len(tr.undo) > undoDepth {
+			tr.undo = tr.undo[1:]
+		}
+		return
+	}
+	tr.rollback(txn)
+	err := LuaRun(lvm, "__gijitTxRestore();", false)
+	panicOn(err)
+}
+
+// luaStringList renders Go identifiers as a Lua array.
+func luaStringList(names []string) string {
+	q := make([]string, len(names))
+	for i, name := range names {
+		q[i] = `"` + name + `"`
+	}
+	return "{" + strings.Join(q, ", ") + "}"
+}
@@ -0,0 +1,76 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test2300FailedInputsAreRolledBack(t *testing.T) {
+
+	cv.Convey(`an input that fails to translate, or fails when run, should leave neither the type checker nor the Lua globals believing in what it declared`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) error {
+			translation, err := inc.Tr([]byte(src))
+			if err != nil {
+				return err
+			}
+			inc.beginRun(vm)
+			err = LuaRun(vm, inc.tagChunk(string(translation)), true)
+			if err == nil {
+				err = lastEvalError(vm)
+			}
+			inc.endRun(vm, err != nil)
+			return err
+		}
+
+		err = run(`
+d := 5
+type P struct {
+	X int
+}
+`)
+		cv.So(err, cv.ShouldBeNil)
+
+		// fails to type-check, after declaring a.
+		err = run(`
+a := 1
+b := notDeclaredAnywhere
+`)
+		cv.So(err, cv.ShouldNotBeNil)
+		_, err = inc.Tr([]byte(`c := a`))
+		cv.So(err, cv.ShouldNotBeNil)
+
+		// type-checks, redeclaring d and P and declaring f,
+		// then panics when run.
+		err = run(`
+d := "five"
+type P struct {
+	Y string
+}
+f := 7
+panic("boom")
+`)
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "boom")
+
+		err = run(`
+e := d + 1
+p := P{X: 2}
+px := p.X
+`)
+		cv.So(err, cv.ShouldBeNil)
+		LuaMustInt64(vm, "d", 5)
+		LuaMustInt64(vm, "e", 6)
+		LuaMustInt64(vm, "px", 2)
+
+		_, err = inc.Tr([]byte(`g := f`))
+		cv.So(err, cv.ShouldNotBeNil)
+		LuaMustNotBeInGlobalEnv(vm, "f")
+	})
+}
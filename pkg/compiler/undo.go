@@ -0,0 +1,82 @@
+package compiler
+
+// undo.go: :undo and :forget take definitions back out of
+// the session, so that a mistaken top-level var, func or
+// type can be declared afresh, even with a different type.
+//
+// :undo reverts the latest input that ran cleanly by
+// rolling back its transaction (see txn.go). :forget
+// removes one package-level name from the type checker,
+// the archive and the Lua globals, and reports what used
+// it, using the dependency tracking in redef.go.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gijit/gi/pkg/types"
+)
+
+// undoDepth is how many inputs :undo can go back.
+const undoDepth = 20
+
+// Undo reverts the latest input that ran cleanly,
+// as if it had failed.
+func (tr *IncrState) Undo(lvm *LuaVm) error {
+	n := len(tr.undo)
+	if n == 0 {
+		return fmt.Errorf("nothing to undo")
+	}
+	txn := tr.undo[n-1]
+	tr.undo = tr.undo[:n-1]
+	tr.rollback(txn)
+	return LuaRun(lvm, "__gijitTxRestore();", false)
+}
+
+// Forget removes the package-level name declared at the
+// prompt. It returns the declarations that used it, which
+// will fail now if they are run or re-translated. A forget
+// can't be undone, and :undo can't reach back past it.
+func (tr *IncrState) Forget(lvm *LuaVm, name string) (users []string, err error) {
+	a := tr.CurPkg.Arch
+	var obj types.Object
+	if a != nil {
+		obj = a.Pkg.Scope().Lookup(name)
+	}
+	if obj == nil || !obj.Pos().IsValid() {
+		return nil, fmt.Errorf("'%s' was not declared at the prompt", name)
+	}
+	qual := a.Pkg.Path() + "." + name
+
+	for _, td := range a.DeclGraph.usersOf([]string{qual}, -1) {
+		what := shortDeclName(td.key)
+		if td.kind == "method" {
+			what = strings.Replace(td.key, a.Pkg.Path()+".", "", -1)
+		}
+		users = append(users, what)
+	}
+
+	// from the checker, leaving no dependency on obj in
+	// the declarations that used it; else checking the
+	// next input would trip over them.
+	a.Check.Forget(obj)
+	delete(a.FuncSrcCache, name)
+	delete(a.DeclGraph.decls, qual)
+	keep := a.Declarations[:0]
+	for _, d := range a.Declarations {
+		if d.FullName != qual && d.DceObjectFilter != name {
+			keep = append(keep, d)
+		}
+	}
+	a.Declarations = keep
+
+	var lua string
+	switch obj.(type) {
+	case *types.Var, *types.Func:
+		lua = fmt.Sprintf("rawset(_G, %q, nil); ", name)
+	case *types.TypeName:
+		lua = fmt.Sprintf("__type__[%q] = nil; ", name)
+	}
+	tr.undo = nil
+	return users, LuaRun(lvm, lua+"__gijitTxClear();", false)
+}
@@ -0,0 +1,71 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test2400UndoAndForget(t *testing.T) {
+
+	cv.Convey(`Undo should revert the latest clean input, and Forget should remove a name from the checker and the vm, reporting what used it`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) error {
+			translation, err := inc.Tr([]byte(src))
+			if err != nil {
+				return err
+			}
+			inc.beginRun(vm)
+			err = LuaRun(vm, inc.tagChunk(string(translation)), true)
+			if err == nil {
+				err = lastEvalError(vm)
+			}
+			inc.endRun(vm, err != nil)
+			return err
+		}
+
+		cv.So(run(`x := 1`), cv.ShouldBeNil)
+		cv.So(run(`x := "two"`), cv.ShouldBeNil)
+		cv.So(run(`w := 9`), cv.ShouldBeNil)
+
+		// w goes, then x is an int again.
+		cv.So(inc.Undo(vm), cv.ShouldBeNil)
+		LuaMustNotBeInGlobalEnv(vm, "w")
+		_, err = inc.Tr([]byte(`w2 := w`))
+		cv.So(err, cv.ShouldNotBeNil)
+
+		cv.So(inc.Undo(vm), cv.ShouldBeNil)
+		cv.So(run(`y := x + 1`), cv.ShouldBeNil)
+		LuaMustInt64(vm, "y", 2)
+
+		cv.So(run(`
+func f() int { return 3 }
+func h() int { return f() + 1 }
+`), cv.ShouldBeNil)
+
+		users, err := inc.Forget(vm, "f")
+		cv.So(err, cv.ShouldBeNil)
+		cv.So(users, cv.ShouldResemble, []string{"h"})
+		LuaMustNotBeInGlobalEnv(vm, "f")
+		_, err = inc.Tr([]byte(`z := f()`))
+		cv.So(err, cv.ShouldNotBeNil)
+
+		// f can be declared afresh.
+		cv.So(run(`func f() string { return "s" }`), cv.ShouldBeNil)
+		cv.So(run(`fs := f()`), cv.ShouldBeNil)
+		LuaMustString(vm, "fs", "s")
+
+		_, err = inc.Forget(vm, "notDeclared")
+		cv.So(err, cv.ShouldNotBeNil)
+
+		// :undo can't reach back past a forget.
+		cv.So(inc.Undo(vm), cv.ShouldBeNil)
+		cv.So(inc.Undo(vm), cv.ShouldBeNil)
+		cv.So(inc.Undo(vm), cv.ShouldNotBeNil)
+	})
+}
@@ -50,6 +50,13 @@ func (c *funcContext) SetPos(pos token.Pos) {
 }
 
 func (c *funcContext) writePos() {
+	if c.p.linePos {
+		if c.posAvailable {
+			c.posAvailable = false
+			c.output = append(c.output, c.posMarker(c.pos)...)
+		}
+		return
+	}
 	// jea debug: turn off writePos() for now
 	return
 	if c.posAvailable {
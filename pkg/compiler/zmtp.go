@@ -0,0 +1,345 @@
+package compiler
+
+// zmtp.go: a minimal ZMTP 3.0 implementation, just enough
+// of ZeroMQ's wire protocol for a Jupyter front end to
+// talk to the gijit kernel (see repl_kernel.go) without
+// needing libzmq linked in.
+//
+// Only the NULL security mechanism is supported. Each
+// accepted connection is its own route: a reply is written
+// back on the connection its request arrived on, which is
+// all a ROUTER socket does for Jupyter's shell, control
+// and stdin channels. A PUB socket writes every message
+// to every peer and ignores subscriptions, since Jupyter
+// front ends subscribe to everything on iopub anyway.
+//
+// Reference: https://rfc.zeromq.org/spec:23/ZMTP/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+const (
+	zmtpFlagMore    = 0x01
+	zmtpFlagLong    = 0x02
+	zmtpFlagCommand = 0x04
+
+	zmtpGreetingLen = 64
+)
+
+// zmtpConn is one handshaken ZMTP connection.
+type zmtpConn struct {
+	nc       net.Conn
+	rd       *bufio.Reader
+	wmut     sync.Mutex
+	peerType string
+}
+
+func zmtpGreeting(asServer bool) []byte {
+	g := make([]byte, zmtpGreetingLen)
+	g[0] = 0xff
+	g[9] = 0x7f
+	g[10] = 3 // version major
+	g[11] = 0 // version minor
+	copy(g[12:32], "NULL")
+	if asServer {
+		g[32] = 1
+	}
+	return g
+}
+
+// zmtpHandshake exchanges greetings and READY
+// commands with the peer on nc. sockType is our
+// ZeroMQ socket type, e.g. "ROUTER" or "PUB".
+func zmtpHandshake(nc net.Conn, sockType string, asServer bool) (*zmtpConn, error) {
+	c := &zmtpConn{nc: nc, rd: bufio.NewReader(nc)}
+
+	_, err := nc.Write(zmtpGreeting(asServer))
+	if err != nil {
+		return nil, err
+	}
+	peer := make([]byte, zmtpGreetingLen)
+	_, err = io.ReadFull(c.rd, peer)
+	if err != nil {
+		return nil, err
+	}
+	if peer[0] != 0xff || peer[9]&1 != 1 {
+		return nil, fmt.Errorf("zmtp: bad greeting signature from %v", nc.RemoteAddr())
+	}
+	if peer[10] < 3 {
+		return nil, fmt.Errorf("zmtp: peer %v speaks ZMTP %v.%v; need 3.0 or later", nc.RemoteAddr(), peer[10], peer[11])
+	}
+	mech := string(bytes.TrimRight(peer[12:32], "\x00"))
+	if mech != "NULL" {
+		return nil, fmt.Errorf("zmtp: unsupported security mechanism '%s'", mech)
+	}
+
+	// NULL mechanism: each side sends READY.
+	err = c.writeFrame(zmtpReady(sockType), zmtpFlagCommand)
+	if err != nil {
+		return nil, err
+	}
+	flags, body, err := c.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	if flags&zmtpFlagCommand == 0 {
+		return nil, fmt.Errorf("zmtp: expected READY command, got a message frame")
+	}
+	name, props, err := zmtpParseCommand(body)
+	if err != nil {
+		return nil, err
+	}
+	if name != "READY" {
+		return nil, fmt.Errorf("zmtp: expected READY command, got '%s'", name)
+	}
+	c.peerType = props["Socket-Type"]
+	return c, nil
+}
+
+func zmtpReady(sockType string) []byte {
+	var b bytes.Buffer
+	b.WriteByte(5)
+	b.WriteString("READY")
+	prop := func(name, val string) {
+		b.WriteByte(byte(len(name)))
+		b.WriteString(name)
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(val)))
+		b.Write(n[:])
+		b.WriteString(val)
+	}
+	prop("Socket-Type", sockType)
+	switch sockType {
+	case "REQ", "DEALER", "ROUTER":
+		prop("Identity", "")
+	}
+	return b.Bytes()
+}
+
+func zmtpParseCommand(body []byte) (name string, props map[string]string, err error) {
+	props = make(map[string]string)
+	if len(body) < 1 || len(body) < 1+int(body[0]) {
+		return "", nil, fmt.Errorf("zmtp: short command frame")
+	}
+	name = string(body[1 : 1+body[0]])
+	rest := body[1+body[0]:]
+	if name != "READY" {
+		// other commands carry no properties we need.
+		return name, props, nil
+	}
+	for len(rest) > 0 {
+		nlen := int(rest[0])
+		if len(rest) < 1+nlen+4 {
+			return "", nil, fmt.Errorf("zmtp: short READY property")
+		}
+		pname := string(rest[1 : 1+nlen])
+		vlen := int(binary.BigEndian.Uint32(rest[1+nlen:]))
+		rest = rest[1+nlen+4:]
+		if len(rest) < vlen {
+			return "", nil, fmt.Errorf("zmtp: short READY property value")
+		}
+		props[pname] = string(rest[:vlen])
+		rest = rest[vlen:]
+	}
+	return name, props, nil
+}
+
+func (c *zmtpConn) writeFrame(body []byte, flags byte) error {
+	var hdr [9]byte
+	n := 2
+	if len(body) > 255 {
+		flags |= zmtpFlagLong
+		binary.BigEndian.PutUint64(hdr[1:], uint64(len(body)))
+		n = 9
+	} else {
+		hdr[1] = byte(len(body))
+	}
+	hdr[0] = flags
+	_, err := c.nc.Write(hdr[:n])
+	if err != nil {
+		return err
+	}
+	_, err = c.nc.Write(body)
+	return err
+}
+
+func (c *zmtpConn) readFrame() (flags byte, body []byte, err error) {
+	flags, err = c.rd.ReadByte()
+	if err != nil {
+		return
+	}
+	var size uint64
+	if flags&zmtpFlagLong != 0 {
+		var n [8]byte
+		_, err = io.ReadFull(c.rd, n[:])
+		if err != nil {
+			return
+		}
+		size = binary.BigEndian.Uint64(n[:])
+	} else {
+		var b byte
+		b, err = c.rd.ReadByte()
+		if err != nil {
+			return
+		}
+		size = uint64(b)
+	}
+	body = make([]byte, size)
+	_, err = io.ReadFull(c.rd, body)
+	return
+}
+
+// ReadMsg returns the frames of the next multipart
+// message, skipping any commands (e.g. the SUBSCRIBE
+// and PING of ZMTP 3.1 peers).
+func (c *zmtpConn) ReadMsg() ([][]byte, error) {
+	var frames [][]byte
+	for {
+		flags, body, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&zmtpFlagCommand != 0 {
+			continue
+		}
+		frames = append(frames, body)
+		if flags&zmtpFlagMore == 0 {
+			return frames, nil
+		}
+	}
+}
+
+// WriteMsg sends frames as one multipart message.
+// It is safe to call from multiple goroutines.
+func (c *zmtpConn) WriteMsg(frames [][]byte) error {
+	c.wmut.Lock()
+	defer c.wmut.Unlock()
+	for i, f := range frames {
+		var flags byte
+		if i < len(frames)-1 {
+			flags = zmtpFlagMore
+		}
+		err := c.writeFrame(f, flags)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *zmtpConn) Close() error {
+	return c.nc.Close()
+}
+
+// zmtpDial connects to a zmtpSocket; used by tests
+// to play the part of the Jupyter front end.
+func zmtpDial(addr, sockType string) (*zmtpConn, error) {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, err := zmtpHandshake(nc, sockType, false)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// zmtpSocket accepts ZMTP connections on one port.
+// If recv is nil, messages from peers are discarded,
+// as a PUB socket does.
+type zmtpSocket struct {
+	typ  string
+	ln   net.Listener
+	recv func(c *zmtpConn, frames [][]byte)
+
+	mut   sync.Mutex
+	peers map[*zmtpConn]bool
+}
+
+func zmtpListen(addr, sockType string, recv func(c *zmtpConn, frames [][]byte)) (*zmtpSocket, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &zmtpSocket{
+		typ:   sockType,
+		ln:    ln,
+		recv:  recv,
+		peers: make(map[*zmtpConn]bool),
+	}
+	go s.serve()
+	return s, nil
+}
+
+func (s *zmtpSocket) serve() {
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(nc)
+	}
+}
+
+func (s *zmtpSocket) handle(nc net.Conn) {
+	c, err := zmtpHandshake(nc, s.typ, true)
+	if err != nil {
+		p("zmtp %s handshake failed: '%v'", s.typ, err)
+		nc.Close()
+		return
+	}
+	s.mut.Lock()
+	s.peers[c] = true
+	s.mut.Unlock()
+
+	defer func() {
+		s.mut.Lock()
+		delete(s.peers, c)
+		s.mut.Unlock()
+		c.Close()
+	}()
+	for {
+		frames, err := c.ReadMsg()
+		if err != nil {
+			return
+		}
+		if s.recv != nil {
+			s.recv(c, frames)
+		}
+	}
+}
+
+// Publish writes frames to every connected peer.
+func (s *zmtpSocket) Publish(frames [][]byte) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for c := range s.peers {
+		err := c.WriteMsg(frames)
+		if err != nil {
+			c.Close()
+			delete(s.peers, c)
+		}
+	}
+}
+
+func (s *zmtpSocket) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+func (s *zmtpSocket) Close() {
+	s.ln.Close()
+	s.mut.Lock()
+	for c := range s.peers {
+		c.Close()
+	}
+	s.mut.Unlock()
+}
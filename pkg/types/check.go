@@ -7,6 +7,8 @@
 package types
 
 import (
+	"sort"
+
 	"github.com/gijit/gi/pkg/ast"
 	"github.com/gijit/gi/pkg/constant"
 	"github.com/gijit/gi/pkg/token"
@@ -98,6 +100,9 @@ type Checker struct {
 
 	// debugging
 	indent int // indentation for tracing
+
+	// jea add: the snapshots still recording; see Snapshot.
+	journals []*CheckerSnapshot
 }
 
 // addUnusedImport adds the position of a dot-imported package
@@ -472,3 +477,179 @@ func (check *Checker) recordScope(node ast.Node, scope *Scope) {
 	}
 	m[node] = scope
 }
+
+// jea add: a CheckerSnapshot holds the package-level state
+// that checking one input at the REPL can change, so that
+// an input that fails, to type-check or later to run, can
+// be undone with Restore. Rather than copy the package, it
+// keeps the old value of each name, ObjMap entry and method
+// list the first time it changes, until Done.
+type CheckerSnapshot struct {
+	scope    *Scope
+	children int
+	imports  int
+	context  context
+
+	elems   map[string]Object    // nil if the name was free.
+	objMap  map[Object]*DeclInfo // nil if obj had no entry.
+	methods map[*Named][]*Func
+}
+
+// Snapshot starts recording the state Restore puts back.
+// Snapshots nest: each records the changes made until its
+// Done or Restore.
+func (check *Checker) Snapshot() *CheckerSnapshot {
+	s := &CheckerSnapshot{
+		scope:    check.scope,
+		children: len(check.pkg.scope.children),
+		imports:  len(check.pkg.imports),
+		context:  check.context,
+		elems:    make(map[string]Object),
+		objMap:   make(map[Object]*DeclInfo),
+		methods:  make(map[*Named][]*Func),
+	}
+	check.journals = append(check.journals, s)
+	check.pkg.scope.record = check.recordElem
+	return s
+}
+
+// Done stops s recording, leaving what it has
+// recorded for a later Restore.
+func (check *Checker) Done(s *CheckerSnapshot) {
+	for i, j := range check.journals {
+		if j == s {
+			check.journals = append(check.journals[:i], check.journals[i+1:]...)
+			break
+		}
+	}
+	if len(check.journals) == 0 {
+		check.pkg.scope.record = nil
+	}
+}
+
+func (check *Checker) recordElem(name string, old Object) {
+	for _, s := range check.journals {
+		if _, ok := s.elems[name]; !ok {
+			s.elems[name] = old
+		}
+	}
+}
+
+func (check *Checker) recordObjMap(obj Object) {
+	for _, s := range check.journals {
+		if _, ok := s.objMap[obj]; !ok {
+			s.objMap[obj] = check.ObjMap[obj]
+		}
+	}
+}
+
+// recordMethods is called before the methods of named change.
+func (check *Checker) recordMethods(named *Named) {
+	for _, s := range check.journals {
+		if _, ok := s.methods[named]; !ok {
+			s.methods[named] = append([]*Func(nil), named.methods...)
+		}
+	}
+}
+
+// setObjMap and deleteObjMap change ObjMap, for Restore.
+func (check *Checker) setObjMap(obj Object, d *DeclInfo) {
+	check.recordObjMap(obj)
+	check.ObjMap[obj] = d
+}
+
+func (check *Checker) deleteObjMap(obj Object) {
+	check.recordObjMap(obj)
+	delete(check.ObjMap, obj)
+}
+
+// Restore returns check to the state recorded in s,
+// and stops s recording.
+func (check *Checker) Restore(s *CheckerSnapshot) {
+	check.Done(s)
+	pkgScope := check.pkg.scope
+	for name, obj := range s.elems {
+		if obj == nil {
+			delete(pkgScope.elems, name)
+		} else {
+			pkgScope.elems[name] = obj
+		}
+	}
+	if s.children < len(pkgScope.children) {
+		pkgScope.children = pkgScope.children[:s.children]
+	}
+	if s.imports < len(check.pkg.imports) {
+		check.pkg.imports = check.pkg.imports[:s.imports]
+	}
+	for obj, d := range s.objMap {
+		if d == nil {
+			delete(check.ObjMap, obj)
+		} else {
+			check.ObjMap[obj] = d
+		}
+	}
+	for named, methods := range s.methods {
+		named.methods = methods
+	}
+	// a bailout can leave us in a nested scope.
+	check.scope = s.scope
+	check.context = s.context
+}
+
+// Declared returns, sorted by name, the objects in the
+// package scope declared or redeclared since s was taken.
+func (check *Checker) Declared(s *CheckerSnapshot) []Object {
+	var names []string
+	for name, old := range s.elems {
+		if obj := check.pkg.scope.elems[name]; obj != nil && obj != old {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	objs := make([]Object, len(names))
+	for i, name := range names {
+		objs[i] = check.pkg.scope.elems[name]
+	}
+	return objs
+}
+
+// Forget removes obj, a package-level object, from check:
+// from the package scope, from ObjMap, and from the
+// dependencies of the declarations that used it, so that
+// the name can be declared afresh. For a type name, its
+// methods go too.
+func (check *Checker) Forget(obj Object) {
+	gone := objSet{obj: true}
+	if tn, ok := obj.(*TypeName); ok {
+		if named, ok := tn.typ.(*Named); ok {
+			for _, m := range named.methods {
+				gone[m] = true
+			}
+		}
+		delete(check.Methods, tn.name)
+	}
+	if check.pkg.scope.Lookup(obj.Name()) == obj {
+		check.pkg.scope.DeleteByName(obj.Name())
+	}
+	for o := range gone {
+		check.deleteObjMap(o)
+	}
+	for _, d := range check.ObjMap {
+		for o := range gone {
+			delete(d.deps, o)
+		}
+	}
+	if check.Info == nil {
+		return
+	}
+	for id, o := range check.Defs {
+		if gone[o] {
+			delete(check.Defs, id)
+		}
+	}
+	for id, o := range check.Uses {
+		if gone[o] {
+			delete(check.Uses, id)
+		}
+	}
+}
@@ -457,7 +457,7 @@ func (check *Checker) addMethodDecls(obj *TypeName) {
 						pp("doing mset.replace with m = '%s', and alt= '%s'", m, alt)
 						prior := mset.replace(m)
 
-						delete(check.ObjMap, prior)
+						check.deleteObjMap(prior)
 
 						// jea: do we need to delete prior in check.Defs as well?
 						// Hmm... we have an Object, not an *ast.Ident.
@@ -472,6 +472,7 @@ func (check *Checker) addMethodDecls(obj *TypeName) {
 						}
 
 						// need to delete the method from the type too.
+						check.recordMethods(base)
 						for i, curm := range base.methods {
 							if curm == prior {
 								base.methods = append(base.methods[:i], base.methods[i+1:]...)
@@ -497,6 +498,7 @@ func (check *Checker) addMethodDecls(obj *TypeName) {
 
 		// methods with blank _ names cannot be found - don't keep them
 		if base != nil && m.name != "_" {
+			check.recordMethods(base)
 			base.methods = append(base.methods, m)
 		}
 	}
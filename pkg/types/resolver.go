@@ -120,7 +120,7 @@ func (check *Checker) declarePkgObj(ident *ast.Ident, obj Object, d *DeclInfo) {
 
 	check.declare(check.pkg.scope, ident, obj, token.NoPos)
 	pp("REDECLARE jea debug. check.ObjMap[obj] being assigned d. obj.Id()='%s', d='%#v'", obj.Id(), d)
-	check.ObjMap[obj] = d
+	check.setObjMap(obj, d)
 	obj.setOrder(uint32(len(check.ObjMap)))
 }
 
@@ -492,7 +492,7 @@ func (check *Checker) collectObjects(depth int) {
 				}
 				info := &DeclInfo{File: fileScope, Fdecl: d}
 				pp("REDECLARE jea debug: check.ObjMap[obj.Id()='%s'] being set to info with Fdecl:d='%#v'", obj.Id(), d)
-				check.ObjMap[obj] = info
+				check.setObjMap(obj, info)
 				obj.setOrder(uint32(len(check.ObjMap)))
 
 				// jea: allow assignments at the top level!
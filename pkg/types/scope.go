@@ -32,6 +32,9 @@ type Scope struct {
 	isFunc     bool              // set if this is a function scope (internal use only)
 	methodName string            // function name; or method name with struct type-name prefix
 
+	// jea add: if set, record gets each name, and the object
+	// it had, before elems changes; see Checker.Snapshot.
+	record func(name string, old Object)
 }
 
 // jea: avoid the error:
@@ -59,7 +62,7 @@ func NewScope(parent *Scope, pos, end token.Pos, comment, methodName string) (sc
 		}
 	}
 
-	s := &Scope{parent, nil, nil, pos, end, comment, false, methodName}
+	s := &Scope{parent, nil, nil, pos, end, comment, false, methodName, nil}
 	// don't add children to Universe scope!
 	if parent != nil && parent != Universe {
 		parent.children = append(parent.children, s)
@@ -120,6 +123,9 @@ func (s *Scope) Lookup(name string) Object {
 // jea add
 func (s *Scope) DeleteByName(name string) Object {
 	obj := s.elems[name]
+	if s.record != nil {
+		s.record(name, obj)
+	}
 	delete(s.elems, name)
 	return obj
 }
@@ -159,6 +165,9 @@ func (s *Scope) Insert(obj Object) Object {
 	if s.elems == nil {
 		s.elems = make(map[string]Object)
 	}
+	if s.record != nil {
+		s.record(name, nil)
+	}
 	s.elems[name] = obj
 	if obj.Parent() == nil {
 		obj.setParent(s) // obj.parent = s
@@ -178,6 +187,9 @@ func (s *Scope) Replace(obj Object) Object {
 		s.elems = make(map[string]Object)
 	}
 	alt := s.elems[name]
+	if s.record != nil {
+		s.record(name, alt)
+	}
 	s.elems[name] = obj
 	if obj.Parent() == nil {
 		obj.setParent(s)